@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
@@ -17,7 +18,9 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/repository"
 	"github.com/wicaker/user/internal/transport"
+	"github.com/wicaker/user/internal/worker"
 )
 
 func init() {
@@ -34,9 +37,8 @@ func main() {
 		sqlxConf   = config.NewSqlx()
 		dbConn     *sqlx.DB
 		rabbitConn = config.NewRabbitmq()
-		errChan    = make(chan error)
+		errChan    = make(chan error, 2)
 	)
-	defer close(errChan)
 	defer func() {
 		err := rabbitConn.Close()
 		if err != nil {
@@ -62,55 +64,88 @@ func main() {
 		}).Fatal(err)
 	}
 
-	go func() {
-		for {
-			errServerClosed := make(chan error)
-			eServer := transport.Echo(dbConn, rabbitConn.Queue)
-			srv := &http.Server{
-				Addr:         ":" + os.Getenv("SERVER_ECHO_PORT"),
-				WriteTimeout: 15 * time.Second,
-				ReadTimeout:  15 * time.Second,
-				Handler:      eServer,
-			}
+	// sqlite is a local development database, its schema is migrated on start
+	if sqlxConf.Driver() == config.DriverSqlite {
+		err = sqlxConf.MigrateUp(sqlxConf.Migrations("."))
+		if err != nil && err != migrate.ErrNoChange {
 			logrus.WithFields(logrus.Fields{
 				"at": time.Now().Format("2006-01-02 15:04:05"),
-			}).Printf("Starting echo server on port :%s...", os.Getenv("SERVER_ECHO_PORT"))
-
-			// start http server
-			go func() {
-				err := srv.ListenAndServe()
-				if err != nil {
-					if err == http.ErrServerClosed {
-						errServerClosed <- err
-					} else {
-						errChan <- err
-					}
-				}
-			}()
-
-			// reconnect when rabbitmq server terminated accidentally
-			err = <-rabbitConn.ErrorChannel
-			if !rabbitConn.IsClose {
-				rabbitConn.Reconnect(err)
-			} else {
-				errChan <- err
-			}
-
-			// shutdown http server gracefully
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
-			if err := srv.Shutdown(ctx); err != nil {
-				errChan <- err
-			}
+			}).Fatal(err)
+		}
+	}
 
+	// route reads to replicas which are healthy and do not lag behind
+	var replicas *repository.ReplicaSet
+	replicaConns, err := sqlxConf.OpenReplicas()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Fatal(err)
+	}
+	defer func() {
+		err := sqlxConf.CloseReplicas(replicaConns)
+		if err != nil {
 			logrus.WithFields(logrus.Fields{
 				"at": time.Now().Format("2006-01-02 15:04:05"),
-			}).Println(<-errServerClosed)
+			}).Errorln(err)
+		}
+	}()
+	if len(replicaConns) > 0 {
+		replicaCtx, stopChecks := context.WithCancel(context.Background())
+		defer stopChecks()
+		replicas = repository.NewReplicaSet(replicaConns, sqlxConf.ReplicaMaxLag)
+		go replicas.Run(replicaCtx, sqlxConf.ReplicaCheckInterval)
+	}
+
+	// re-encrypt profiles under the current master key in background
+	encryptionConf := config.NewEncryption()
+	keyring, err := encryptionConf.Keyring()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Fatal(err)
+	}
+	if keyring != nil {
+		workerCtx, stopWorker := context.WithCancel(context.Background())
+		defer stopWorker()
+		profileKeyRepo := repository.NewProfileKeySqlxRepository(dbConn, keyring)
+		if sqlxConf.Driver() == config.DriverSqlite {
+			profileKeyRepo = repository.NewProfileKeySqliteRepository(dbConn, keyring)
+		}
+		reencryption := worker.NewReencryption(profileKeyRepo, encryptionConf.ReencryptInterval, encryptionConf.ReencryptBatchSize)
+		go reencryption.Run(workerCtx)
+	}
+
+	// relay outbox messages, the rabbitmq broker reconnects by itself and its queues stay usable
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go transport.NewOutboxRelay(dbConn, rabbitConn.Queue).Run(relayCtx)
+
+	// fail data exports left pending by builds stopped with a previous process
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go worker.NewExportSweeper(repository.NewDataExportSqlxRepository(dbConn), config.NewExport().SweepInterval).Run(sweepCtx)
+
+	srv := &http.Server{
+		Addr:         ":" + os.Getenv("SERVER_ECHO_PORT"),
+		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  15 * time.Second,
+		Handler:      transport.Echo(dbConn, replicas, rabbitConn.Queue),
+	}
+	logrus.WithFields(logrus.Fields{
+		"at": time.Now().Format("2006-01-02 15:04:05"),
+	}).Printf("Starting echo server on port :%s...", os.Getenv("SERVER_ECHO_PORT"))
+
+	// start http server
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			errChan <- err
 		}
 	}()
 
 	go func() {
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt)
 		errChan <- fmt.Errorf("%s", <-c)
 	}()
@@ -118,4 +153,13 @@ func main() {
 	logrus.WithFields(logrus.Fields{
 		"at": time.Now().Format("2006-01-02 15:04:05"),
 	}).Errorln("terminated ", <-errChan)
+
+	// shutdown http server gracefully
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Errorln(err)
+	}
 }
@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+// CacheConfig collects all of necessary field for caching user and profile lookups, 0 TTL disable the cache
+type CacheConfig struct {
+	Size int
+	TTL  time.Duration
+}
+
+// NewCache will create new an CacheConfig, at most CACHE_SIZE users and profiles are cached for CACHE_TTL
+func NewCache() *CacheConfig {
+	config := new(CacheConfig)
+	config.Size = int(positiveEnv("CACHE_SIZE", 10000))
+	config.TTL = durationEnv("CACHE_TTL", 0)
+
+	return config
+}
+
+// Enabled reports whether lookups are cached
+func (c *CacheConfig) Enabled() bool {
+	return c.TTL > 0
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -9,3 +12,18 @@ func logError(message string, err error) {
 		logrus.Printf("%s: %s", message, err)
 	}
 }
+
+func errInvalidMapping(value string) error {
+	return fmt.Errorf("%q is not a valid mapping", value)
+}
+
+// splitList split a separated environment value, empty items are omitted
+func splitList(value string, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/pkg/envelope"
+)
+
+// EncryptionConfig collects all of necessary field for encryption of personal data at rest
+type EncryptionConfig struct {
+	MasterKeys    map[int][]byte
+	KeyVersion    int
+	BlindIndexKey []byte
+	// ReencryptInterval is pause of background re-encryption once every profile uses KeyVersion
+	ReencryptInterval time.Duration
+	// ReencryptBatchSize is number of profiles re-encrypted per transaction
+	ReencryptBatchSize uint
+	err                error
+}
+
+// NewEncryption will create new an EncryptionConfig represent configuration of field-level encryption.
+// Keys are base64 encoded, given directly in env or in a file named by the _FILE variable
+func NewEncryption() *EncryptionConfig {
+	config := new(EncryptionConfig)
+	config.MasterKeys = make(map[int][]byte)
+	config.ReencryptInterval = durationEnv("ENCRYPTION_REENCRYPT_INTERVAL", time.Minute*10)
+	config.ReencryptBatchSize = 100
+	if v := os.Getenv("ENCRYPTION_REENCRYPT_BATCH_SIZE"); v != "" {
+		size, err := strconv.ParseUint(v, 10, 32)
+		if err == nil && size == 0 {
+			err = errors.New("must be greater than zero")
+		}
+		if err != nil {
+			logError("Invalid ENCRYPTION_REENCRYPT_BATCH_SIZE", err)
+		} else {
+			config.ReencryptBatchSize = uint(size)
+		}
+	}
+
+	// ENCRYPTION_MASTER_KEYS format: <version>:<base64 key>;<version>:<base64 key>, in a file one key per line is accepted as well
+	masterKeys, err := secretEnv("ENCRYPTION_MASTER_KEYS")
+	if err != nil {
+		config.err = err
+		return config
+	}
+	for _, mapping := range splitList(strings.Replace(masterKeys, "\n", ";", -1), ";") {
+		i := strings.Index(mapping, ":")
+		if i <= 0 {
+			config.err = errors.New("invalid master key, expected <version>:<base64 key>")
+			return config
+		}
+
+		version, err := strconv.Atoi(strings.TrimSpace(mapping[:i]))
+		if err != nil {
+			config.err = errors.Wrap(err, "invalid master key version")
+			return config
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(mapping[i+1:]))
+		if err != nil {
+			config.err = errors.Wrapf(err, "invalid master key version %d", version)
+			return config
+		}
+
+		config.MasterKeys[version] = key
+		if version > config.KeyVersion {
+			config.KeyVersion = version
+		}
+	}
+
+	// the newest master key is used unless ENCRYPTION_KEY_VERSION pins another one
+	if v := os.Getenv("ENCRYPTION_KEY_VERSION"); v != "" {
+		config.KeyVersion, err = strconv.Atoi(v)
+		if err != nil {
+			config.err = errors.Wrap(err, "invalid ENCRYPTION_KEY_VERSION")
+			return config
+		}
+	}
+
+	indexKey, err := secretEnv("ENCRYPTION_BLIND_INDEX_KEY")
+	if err != nil {
+		config.err = err
+		return config
+	}
+	config.BlindIndexKey, err = base64.StdEncoding.DecodeString(strings.TrimSpace(indexKey))
+	if err != nil {
+		config.err = errors.Wrap(err, "invalid ENCRYPTION_BLIND_INDEX_KEY")
+	}
+
+	return config
+}
+
+// Enabled report whether encryption keys have been configured
+func (c *EncryptionConfig) Enabled() bool {
+	return len(c.MasterKeys) > 0 || len(c.BlindIndexKey) > 0 || c.err != nil
+}
+
+// Keyring returns keyring of the configured keys, it is nil when encryption is not configured
+func (c *EncryptionConfig) Keyring() (*envelope.Keyring, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return envelope.NewKeyring(c.MasterKeys, c.KeyVersion, c.BlindIndexKey)
+}
+
+// secretEnv returns value of env key, or content of the file named by env key + "_FILE"
+func secretEnv(key string) (string, error) {
+	if value := os.Getenv(key); value != "" {
+		return value, nil
+	}
+
+	file := os.Getenv(key + "_FILE")
+	if file == "" {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "read %s", key+"_FILE")
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExportConfig collects all of necessary field for building and downloading data exports
+type ExportConfig struct {
+	Dir       string
+	BaseURL   string
+	LinkTTL   time.Duration
+	Retention time.Duration
+	// SweepInterval is interval of failing exports left pending by a stopped build
+	SweepInterval time.Duration
+}
+
+// NewExport will create new an ExportConfig represent configuration of data export
+func NewExport() *ExportConfig {
+	config := new(ExportConfig)
+
+	config.Dir = os.Getenv("EXPORT_DIR")
+	if config.Dir == "" {
+		config.Dir = filepath.Join(os.TempDir(), "user-exports")
+	}
+
+	config.BaseURL = strings.TrimSuffix(os.Getenv("EXPORT_BASE_URL"), "/")
+	config.LinkTTL = durationEnv("EXPORT_LINK_TTL", time.Minute*15)
+	config.Retention = durationEnv("EXPORT_RETENTION", time.Hour*24*7)
+	config.SweepInterval = durationEnv("EXPORT_SWEEP_INTERVAL", time.Minute)
+
+	return config
+}
+
+// durationEnv parse duration of environment variable, the default is used when it is empty or invalid
+func durationEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logError("Invalid "+key, err)
+		return def
+	}
+
+	return d
+}
@@ -0,0 +1,21 @@
+package config
+
+import "os"
+
+// GeoIPConfig collects all of necessary field for locating ip address of login
+type GeoIPConfig struct {
+	DatabaseFile string
+}
+
+// NewGeoIP will create new an GeoIPConfig represent configuration of the local GeoIP database
+func NewGeoIP() *GeoIPConfig {
+	config := new(GeoIPConfig)
+	config.DatabaseFile = os.Getenv("GEOIP_DATABASE_FILE")
+
+	return config
+}
+
+// Enabled report whether GeoIP database has been configured
+func (c *GeoIPConfig) Enabled() bool {
+	return c.DatabaseFile != ""
+}
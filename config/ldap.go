@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// LdapGroupRole maps a directory group to a role of domain.User
+type LdapGroupRole struct {
+	GroupDN string
+	Role    string
+}
+
+// LdapConfig collects all of necessary field for authentication against ldap / active directory
+type LdapConfig struct {
+	URL          string
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string
+	GroupAttr    string
+	GroupRoles   []LdapGroupRole
+	EmailDomains []string
+}
+
+// NewLdap will create new a LdapConfig represent configuration of ldap authentication backend
+func NewLdap() *LdapConfig {
+	config := new(LdapConfig)
+
+	config.URL = os.Getenv("LDAP_URL")
+	config.StartTLS = os.Getenv("LDAP_START_TLS") == "true"
+	config.BindDN = os.Getenv("LDAP_BIND_DN")
+	config.BindPassword = os.Getenv("LDAP_BIND_PASSWORD")
+	config.BaseDN = os.Getenv("LDAP_BASE_DN")
+
+	config.UserFilter = os.Getenv("LDAP_USER_FILTER")
+	if config.UserFilter == "" {
+		config.UserFilter = "(mail=%s)"
+	}
+
+	config.GroupAttr = os.Getenv("LDAP_GROUP_ATTRIBUTE")
+	if config.GroupAttr == "" {
+		config.GroupAttr = "memberOf"
+	}
+
+	// LDAP_GROUP_ROLES format: <group dn>=<role>;<group dn>=<role>
+	for _, mapping := range splitList(os.Getenv("LDAP_GROUP_ROLES"), ";") {
+		i := strings.LastIndex(mapping, "=")
+		if i <= 0 {
+			logError("Invalid LDAP_GROUP_ROLES mapping", errInvalidMapping(mapping))
+			continue
+		}
+		config.GroupRoles = append(config.GroupRoles, LdapGroupRole{
+			GroupDN: strings.TrimSpace(mapping[:i]),
+			Role:    strings.TrimSpace(mapping[i+1:]),
+		})
+	}
+
+	for _, d := range splitList(os.Getenv("LDAP_EMAIL_DOMAINS"), ",") {
+		config.EmailDomains = append(config.EmailDomains, strings.ToLower(d))
+	}
+
+	return config
+}
+
+// Enabled report whether ldap authentication has been configured
+func (c *LdapConfig) Enabled() bool {
+	return c.URL != "" && len(c.EmailDomains) > 0
+}
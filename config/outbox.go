@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// OutboxConfig collects all of necessary field for relaying outbox messages to rabbitmq
+type OutboxConfig struct {
+	RelayInterval time.Duration
+	BatchSize     uint
+	Lease         time.Duration
+	Retention     time.Duration
+	EventSource   string
+}
+
+// NewOutbox will create new an OutboxConfig, pending messages are relayed every OUTBOX_RELAY_INTERVAL
+// in batches of OUTBOX_BATCH_SIZE claimed for OUTBOX_LEASE, and sent messages are kept for OUTBOX_RETENTION.
+// The lease should outlast publishing of a whole batch, which waits up to RABBITMQ_CONFIRM_TIMEOUT per message.
+// EVENT_SOURCE is the CloudEvents source of events published to queues of a CloudEvents format
+func NewOutbox() *OutboxConfig {
+	config := new(OutboxConfig)
+	config.RelayInterval = durationEnv("OUTBOX_RELAY_INTERVAL", time.Second)
+	config.BatchSize = positiveEnv("OUTBOX_BATCH_SIZE", 100)
+	config.Lease = durationEnv("OUTBOX_LEASE", time.Minute*10)
+	config.Retention = durationEnv("OUTBOX_RETENTION", time.Hour*24*7)
+	config.EventSource = os.Getenv("EVENT_SOURCE")
+	if config.EventSource == "" {
+		config.EventSource = "/user"
+	}
+
+	return config
+}
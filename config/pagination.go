@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// PaginationConfig collects all of necessary field for paginating list endpoints
+type PaginationConfig struct {
+	CursorKey    []byte
+	DefaultLimit uint
+	MaxLimit     uint
+}
+
+// NewPagination will create new an PaginationConfig, cursors are signed by PAGINATION_CURSOR_KEY or JWT_SECRET when it is not set
+func NewPagination() *PaginationConfig {
+	config := new(PaginationConfig)
+	config.DefaultLimit = positiveEnv("PAGINATION_DEFAULT_LIMIT", 20)
+	config.MaxLimit = positiveEnv("PAGINATION_MAX_LIMIT", 100)
+	if config.DefaultLimit > config.MaxLimit {
+		config.DefaultLimit = config.MaxLimit
+	}
+
+	key, err := secretEnv("PAGINATION_CURSOR_KEY")
+	logError("Invalid PAGINATION_CURSOR_KEY", err)
+	if key == "" {
+		key = os.Getenv("JWT_SECRET")
+	}
+	config.CursorKey = []byte(key)
+
+	return config
+}
+
+// positiveEnv parse positive number of environment variable, the default is used when it is empty or invalid
+func positiveEnv(key string, def uint) uint {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err == nil && n == 0 {
+		err = errors.New("must be greater than zero")
+	}
+	if err != nil {
+		logError("Invalid "+key, err)
+		return def
+	}
+
+	return uint(n)
+}
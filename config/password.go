@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// PasswordConfig collects all of necessary field for password history and expiry, 0 disable the policy
+type PasswordConfig struct {
+	HistorySize int
+	MaxAge      time.Duration
+}
+
+// NewPassword will create new an PasswordConfig, tenants may override it with their own policy
+func NewPassword() *PasswordConfig {
+	config := new(PasswordConfig)
+	config.MaxAge = durationEnv("PASSWORD_MAX_AGE", 0)
+
+	if v := os.Getenv("PASSWORD_HISTORY_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err == nil && size < 0 {
+			err = fmt.Errorf("%d is negative", size)
+		}
+		if err != nil {
+			logError("Invalid PASSWORD_HISTORY_SIZE", err)
+		} else {
+			config.HistorySize = size
+		}
+	}
+
+	return config
+}
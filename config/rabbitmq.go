@@ -3,29 +3,32 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/streadway/amqp"
-
+	"github.com/wicaker/user/internal/domain"
 	"github.com/wicaker/user/internal/pkg/rmq"
 )
 
 // MqConfig collects all of necessary field for connection configuration to rabbitmq
 type MqConfig struct {
-	IsClose        bool
-	AmqpConnection *amqp.Connection
-	ErrorChannel   chan *amqp.Error
-	rmqConn        *rmq.Connection
+	Broker         *rmq.Broker
 	Queue          []rmq.Queue
+	format         rmq.Format
+	queueFormats   map[string]rmq.Format
+	confirmTimeout time.Duration
 }
 
-// NewRabbitmq to start a new configuration to rabbitmq
+// NewRabbitmq to start a new configuration to rabbitmq. Events are published in RABBITMQ_MESSAGE_FORMAT,
+// RABBITMQ_QUEUE_FORMATS overrides it by queue so consumers can migrate to CloudEvents one queue at a time.
+// Publishing fails unless the broker confirms a message in RABBITMQ_CONFIRM_TIMEOUT. The broker reconnects by itself,
+// at most RABBITMQ_BUFFER_SIZE messages of buffered queues are kept while it is disconnected
 func NewRabbitmq() *MqConfig {
 	config := new(MqConfig)
+	config.parseFormats()
+	config.confirmTimeout = durationEnv("RABBITMQ_CONFIRM_TIMEOUT", time.Second*5)
 
-	config.rmqConn = rmq.NewConnection(os.Getenv("RABBITMQ_SERVER"))
-	config.AmqpConnection = config.rmqConn.Dial()
-	config.ErrorChannel = config.rmqConn.ErrorChannel(config.AmqpConnection)
-	config.rmqConn.NotifyClose(config.AmqpConnection, config.ErrorChannel)
+	config.Broker = rmq.NewBroker(os.Getenv("RABBITMQ_SERVER"), int(positiveEnv("RABBITMQ_BUFFER_SIZE", 1000)))
 	config.registerQueue()
 
 	return config
@@ -34,17 +37,7 @@ func NewRabbitmq() *MqConfig {
 // Close to close amqp connection
 func (c *MqConfig) Close() error {
 	log.Println("closing rabbitmq connection...")
-	c.IsClose = true
-	return c.AmqpConnection.Close()
-}
-
-// Reconnect to reconnecting connection
-func (c *MqConfig) Reconnect(err error) {
-	log.Printf("%s: %s", "Reconnecting after connection closed", err)
-	c.AmqpConnection = c.rmqConn.Dial()
-	c.ErrorChannel = c.rmqConn.ErrorChannel(c.AmqpConnection)
-	c.rmqConn.NotifyClose(c.AmqpConnection, c.ErrorChannel)
-	c.registerQueue()
+	return c.Broker.Close()
 }
 
 func (c *MqConfig) registerQueue() {
@@ -53,12 +46,57 @@ func (c *MqConfig) registerQueue() {
 		ExcType: rmq.TOPIC,
 	}
 
-	registerChannel := rmq.NewQueue("publish-user-register", c.AmqpConnection, exchange, []string{}, false, true)
+	// messages of the publish queues are relayed from the outbox, they stay in the outbox while disconnected
+	registerChannel := rmq.NewQueue("publish-user-register", c.Broker, exchange, []string{}, false, true, false, c.queueFormat("publish-user-register"), c.confirmTimeout)
 	c.Queue = append(c.Queue, registerChannel)
 
-	changePassworChannel := rmq.NewQueue("publish-user-change-password", c.AmqpConnection, exchange, []string{}, false, true)
+	changePassworChannel := rmq.NewQueue("publish-user-change-password", c.Broker, exchange, []string{}, false, true, false, c.queueFormat("publish-user-change-password"), c.confirmTimeout)
 	c.Queue = append(c.Queue, changePassworChannel)
 
-	forgotPassworChannel := rmq.NewQueue("publish-user-forgot-password", c.AmqpConnection, exchange, []string{}, false, true)
+	forgotPassworChannel := rmq.NewQueue("publish-user-forgot-password", c.Broker, exchange, []string{}, false, true, false, c.queueFormat("publish-user-forgot-password"), c.confirmTimeout)
 	c.Queue = append(c.Queue, forgotPassworChannel)
+
+	inviteChannel := rmq.NewQueue("publish-user-invite", c.Broker, exchange, []string{}, false, true, false, c.queueFormat("publish-user-invite"), c.confirmTimeout)
+	c.Queue = append(c.Queue, inviteChannel)
+
+	exportChannel := rmq.NewQueue("publish-user-export", c.Broker, exchange, []string{}, false, true, false, c.queueFormat("publish-user-export"), c.confirmTimeout)
+	c.Queue = append(c.Queue, exportChannel)
+
+	newLoginChannel := rmq.NewQueue("publish-user-new-login", c.Broker, exchange, []string{}, false, true, false, c.queueFormat("publish-user-new-login"), c.confirmTimeout)
+	c.Queue = append(c.Queue, newLoginChannel)
+
+	cacheInvalidationChannel := rmq.NewBroadcastQueue("user-cache-invalidation", c.Broker, exchange, []string{domain.CacheInvalidationRoutingKey}, c.confirmTimeout)
+	c.Queue = append(c.Queue, cacheInvalidationChannel)
+}
+
+func (c *MqConfig) parseFormats() {
+	format, err := rmq.ParseFormat(os.Getenv("RABBITMQ_MESSAGE_FORMAT"))
+	logError("Invalid RABBITMQ_MESSAGE_FORMAT", err)
+	if err != nil {
+		format = rmq.FormatPlain
+	}
+	c.format = format
+
+	c.queueFormats = make(map[string]rmq.Format)
+	for _, mapping := range splitList(os.Getenv("RABBITMQ_QUEUE_FORMATS"), ",") {
+		i := strings.LastIndex(mapping, "=")
+		if i <= 0 {
+			logError("Invalid RABBITMQ_QUEUE_FORMATS mapping", errInvalidMapping(mapping))
+			continue
+		}
+		format, err := rmq.ParseFormat(mapping[i+1:])
+		if err != nil {
+			logError("Invalid RABBITMQ_QUEUE_FORMATS mapping", err)
+			continue
+		}
+		c.queueFormats[strings.TrimSpace(mapping[:i])] = format
+	}
+}
+
+// queueFormat returns format of messages published to the queue name
+func (c *MqConfig) queueFormat(name string) rmq.Format {
+	if format, ok := c.queueFormats[name]; ok {
+		return format
+	}
+	return c.format
 }
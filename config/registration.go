@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RegistrationConfig collects all of necessary field for deciding who may register
+type RegistrationConfig struct {
+	Mode                  string
+	AllowedDomains        []string
+	DisposableDomainsFile string
+}
+
+// NewRegistration will create new a RegistrationConfig represent configuration of registration mode
+func NewRegistration() *RegistrationConfig {
+	config := new(RegistrationConfig)
+
+	// REGISTRATION_MODE is one of open, invite or domain
+	config.Mode = strings.ToLower(strings.TrimSpace(os.Getenv("REGISTRATION_MODE")))
+	if config.Mode == "" {
+		config.Mode = "open"
+	}
+
+	for _, d := range splitList(os.Getenv("REGISTRATION_ALLOWED_DOMAINS"), ",") {
+		config.AllowedDomains = append(config.AllowedDomains, strings.ToLower(d))
+	}
+
+	config.DisposableDomainsFile = os.Getenv("REGISTRATION_DISPOSABLE_DOMAINS_FILE")
+
+	return config
+}
+
+// DisposableDomains loads denylist of disposable email domains, one domain per line and # starts a comment
+func (c *RegistrationConfig) DisposableDomains() ([]string, error) {
+	if c.DisposableDomainsFile == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(c.DisposableDomainsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "open disposable domains file")
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			domains = append(domains, strings.ToLower(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read disposable domains file")
+	}
+
+	return domains, nil
+}
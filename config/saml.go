@@ -0,0 +1,52 @@
+package config
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"strings"
+)
+
+// SamlConfig collects all of necessary field for saml service provider
+type SamlConfig struct {
+	BaseURL  string
+	KeyFile  string
+	CertFile string
+}
+
+// NewSaml will create new a SamlConfig represent configuration of saml service provider
+func NewSaml() *SamlConfig {
+	config := new(SamlConfig)
+
+	config.BaseURL = strings.TrimSuffix(os.Getenv("SAML_SP_BASE_URL"), "/")
+	config.KeyFile = os.Getenv("SAML_SP_KEY_FILE")
+	config.CertFile = os.Getenv("SAML_SP_CERT_FILE")
+
+	return config
+}
+
+// KeyPair loads the service provider key and certificate, both are nil when they are not configured
+func (c *SamlConfig) KeyPair() (*rsa.PrivateKey, *x509.Certificate, error) {
+	if c.KeyFile == "" || c.CertFile == "" {
+		return nil, nil, nil
+	}
+
+	pair, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("saml service provider key must be an RSA key")
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, cert, nil
+}
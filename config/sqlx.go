@@ -1,22 +1,59 @@
 package config
 
 import (
+	"database/sql"
+	"fmt"
 	"log"
 	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/jmoiron/sqlx"
 
 	// import source driver
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
+const (
+	// DriverPostgres is sql driver name of postgres
+	DriverPostgres = "postgres"
+	// DriverSqlite is sql driver name of sqlite, its DATABASE_URL is a file name of go-sqlite3 DSN.
+	// go-sqlite3 is built with cgo, a binary built with CGO_ENABLED=0 can not open it
+	DriverSqlite = "sqlite3"
+)
+
+// dbDrivers are sql drivers accepted by DB_DRIVER
+var dbDrivers = map[string]string{
+	"postgres": DriverPostgres,
+	"sqlite":   DriverSqlite,
+	"sqlite3":  DriverSqlite,
+}
+
 // SqlxConfig sqlx configuration
 type SqlxConfig struct {
-	url string
-	mi  *migrate.Migrate
+	driver      string
+	url         string
+	replicaURLs []string
+	isolation   sql.IsolationLevel
+	mi          *migrate.Migrate
+
+	// ReplicaMaxLag is the replay lag above which a replica is not read
+	ReplicaMaxLag time.Duration
+	// ReplicaCheckInterval is interval of replica health checks
+	ReplicaCheckInterval time.Duration
+}
+
+// txIsolations are isolation levels accepted by DB_TX_ISOLATION
+var txIsolations = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
 }
 
 // NewSqlx will create new a SqlxConfig represent configuration database using sqlx library
@@ -24,13 +61,85 @@ func NewSqlx() *SqlxConfig {
 	config := new(SqlxConfig)
 
 	config.url = os.Getenv("DATABASE_URL")
+
+	config.driver = DriverPostgres
+	if value := os.Getenv("DB_DRIVER"); value != "" {
+		driver, ok := dbDrivers[strings.ToLower(value)]
+		if ok {
+			config.driver = driver
+		} else {
+			logError("Invalid DB_DRIVER", fmt.Errorf("unknown database driver %q", value))
+		}
+	}
+
+	config.replicaURLs = splitList(os.Getenv("DATABASE_REPLICA_URLS"), ",")
+	if len(config.replicaURLs) > 0 && config.driver != DriverPostgres {
+		logError("Invalid DATABASE_REPLICA_URLS", fmt.Errorf("read replicas are not supported by %s", config.driver))
+		config.replicaURLs = nil
+	}
+	config.ReplicaMaxLag = durationEnv("DB_REPLICA_MAX_LAG", time.Second)
+	config.ReplicaCheckInterval = durationEnv("DB_REPLICA_CHECK_INTERVAL", 5*time.Second)
+
+	config.isolation = sql.LevelSerializable
+	if value := os.Getenv("DB_TX_ISOLATION"); value != "" {
+		isolation, ok := txIsolations[strings.ToLower(value)]
+		if ok {
+			config.isolation = isolation
+		} else {
+			logError("Invalid DB_TX_ISOLATION", fmt.Errorf("unknown isolation level %q", value))
+		}
+	}
 	return config
 }
 
+// Driver returns sql driver name of the database, postgres unless DB_DRIVER is set
+func (s *SqlxConfig) Driver() string {
+	return s.driver
+}
+
+// Migrations returns source url of migrations of the database driver, relative to dir
+func (s *SqlxConfig) Migrations(dir string) string {
+	if s.driver == DriverSqlite {
+		return "file://" + path.Join(dir, "migrations/sqlite")
+	}
+	return "file://" + path.Join(dir, "migrations")
+}
+
+// TxIsolation returns isolation level of unit of work transactions, serializable unless DB_TX_ISOLATION is set
+func (s *SqlxConfig) TxIsolation() sql.IsolationLevel {
+	return s.isolation
+}
+
 // Open will open database connection using sqlx library
 func (s *SqlxConfig) Open() (*sqlx.DB, error) {
 	log.Println("open database connection...")
-	return sqlx.Open(`postgres`, s.url)
+	return sqlx.Open(s.driver, s.url)
+}
+
+// OpenReplicas will open connections of read replicas of DATABASE_REPLICA_URLS, there are none when it is not set
+func (s *SqlxConfig) OpenReplicas() ([]*sqlx.DB, error) {
+	var replicas []*sqlx.DB
+	for _, url := range s.replicaURLs {
+		log.Println("open replica database connection...")
+		conn, err := sqlx.Open(s.driver, url)
+		if err != nil {
+			s.CloseReplicas(replicas)
+			return nil, err
+		}
+		replicas = append(replicas, conn)
+	}
+	return replicas, nil
+}
+
+// CloseReplicas close connections of read replicas
+func (s *SqlxConfig) CloseReplicas(replicas []*sqlx.DB) error {
+	var err error
+	for _, conn := range replicas {
+		if closeErr := conn.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 // Close database connection
@@ -41,9 +150,14 @@ func (s *SqlxConfig) Close(conn *sqlx.DB) error {
 
 // MigrateUp running migration up
 func (s *SqlxConfig) MigrateUp(filePath string) error {
+	url := s.url
+	if s.driver == DriverSqlite {
+		url = "sqlite3://" + url
+	}
+
 	mi, err := migrate.New(
 		filePath,
-		s.url)
+		url)
 
 	if err != nil {
 		return err
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// StepUpConfig collects all of necessary field for step-up authentication of sensitive operations
+type StepUpConfig struct {
+	MaxAge time.Duration
+}
+
+// NewStepUp will create new an StepUpConfig, sensitive operations require credentials checked within STEP_UP_MAX_AGE
+func NewStepUp() *StepUpConfig {
+	config := new(StepUpConfig)
+	config.MaxAge = durationEnv("STEP_UP_MAX_AGE", time.Minute*5)
+
+	return config
+}
@@ -0,0 +1,42 @@
+package authenticator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type domainAuthenticator struct {
+	fallback domain.Authenticator
+	routes   map[string]domain.Authenticator
+}
+
+// NewDomainAuthenticator will create new an domainAuthenticator object representation of domain.Authenticator interface.
+// Credentials are verified by the authenticator registered for the email domain, or by fallback when there is none
+func NewDomainAuthenticator(fallback domain.Authenticator, routes map[string]domain.Authenticator) domain.Authenticator {
+	r := make(map[string]domain.Authenticator, len(routes))
+	for d, a := range routes {
+		r[strings.ToLower(d)] = a
+	}
+
+	return &domainAuthenticator{
+		fallback: fallback,
+		routes:   r,
+	}
+}
+
+func (a *domainAuthenticator) Authenticate(ctx context.Context, email string, password string) (*domain.User, error) {
+	if authn, ok := a.routes[emailDomain(email)]; ok {
+		return authn.Authenticate(ctx, email, password)
+	}
+	return a.fallback.Authenticate(ctx, email, password)
+}
+
+func emailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.ToLower(email[i+1:])
+}
@@ -0,0 +1,205 @@
+package authenticator
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/domain"
+)
+
+type ldapAuthenticator struct {
+	conf     *config.LdapConfig
+	userRepo domain.UserRepository
+}
+
+// NewLdapAuthenticator will create new an ldapAuthenticator object representation of domain.Authenticator interface.
+// The user is searched with the service account, then verified by binding with their own dn and password.
+// A user that does not exist yet is provisioned on the first successful login.
+func NewLdapAuthenticator(conf *config.LdapConfig, userRepo domain.UserRepository) domain.Authenticator {
+	return &ldapAuthenticator{
+		conf:     conf,
+		userRepo: userRepo,
+	}
+}
+
+/**
+ * Used to authenticate against directory. Pseudocode:
+ * - reject empty password, directory treats it as an unauthenticated bind
+ * - connect and bind with service account
+ * - search user entry by email
+ * - bind with user entry dn and password
+ * - map directory groups to role
+ * - provision or sync local user
+ */
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, email string, password string) (*domain.User, error) {
+	if password == "" {
+		return nil, domain.ErrWrongPassword
+	}
+
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := a.searchUser(conn, email)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, domain.ErrWrongPassword
+		}
+		return nil, errors.Wrap(err, "ldap user bind")
+	}
+
+	return a.provision(ctx, email, a.mapRole(entry.GetAttributeValues(a.conf.GroupAttr)))
+}
+
+func (a *ldapAuthenticator) dial(ctx context.Context) (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.conf.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "ldap dial")
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetTimeout(time.Until(deadline))
+	}
+
+	if a.conf.StartTLS {
+		u, err := url.Parse(a.conf.URL)
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "parse ldap url")
+		}
+
+		err = conn.StartTLS(&tls.Config{ServerName: u.Hostname()})
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "ldap start tls")
+		}
+	}
+
+	if a.conf.BindDN != "" {
+		err = conn.Bind(a.conf.BindDN, a.conf.BindPassword)
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "ldap service account bind")
+		}
+	}
+
+	return conn, nil
+}
+
+func (a *ldapAuthenticator) searchUser(conn *ldap.Conn, email string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		a.conf.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,     // size limit
+		0,     // time limit
+		false, // types only
+		fmt.Sprintf(a.conf.UserFilter, ldap.EscapeFilter(email)),
+		[]string{"dn", a.conf.GroupAttr},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "ldap search user")
+	}
+
+	// an ambiguous email must never pick one of the entries
+	if len(res.Entries) != 1 {
+		return nil, nil
+	}
+
+	return res.Entries[0], nil
+}
+
+// mapRole returns the role of the first configured group the user is member of
+func (a *ldapAuthenticator) mapRole(groups []string) string {
+	for _, gr := range a.conf.GroupRoles {
+		for _, g := range groups {
+			if strings.EqualFold(g, gr.GroupDN) {
+				return gr.Role
+			}
+		}
+	}
+	return domain.RoleUser
+}
+
+/**
+ * Used to provision directory user just in time. Pseudocode:
+ * - check user in database
+ * - if not exist, store a new user with an unusable random password
+ * - activate user and sync role from directory
+ */
+func (a *ldapAuthenticator) provision(ctx context.Context, email string, role string) (*domain.User, error) {
+	checkUser, err := a.userRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("email", email),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if checkUser == nil {
+		password, err := unusablePassword()
+		if err != nil {
+			return nil, err
+		}
+
+		checkUser, err = a.userRepo.Store(ctx, &domain.User{
+			Email:    email,
+			Password: password,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Store user data")
+		}
+	}
+
+	if checkUser.IsActive && checkUser.Role == role {
+		return checkUser, nil
+	}
+
+	checkUser.IsActive = true
+	checkUser.Role = role
+
+	checkUser, err = a.userRepo.Update(ctx, checkUser)
+	if err != nil {
+		return nil, errors.Wrap(err, "Update user data")
+	}
+
+	return checkUser, nil
+}
+
+// unusablePassword returns bcrypt hash of random secret, nobody knows the plain password
+func unusablePassword() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.Wrap(err, "generate random password")
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(secret)), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.Wrap(err, "Password Encryption failed")
+	}
+
+	return string(password), nil
+}
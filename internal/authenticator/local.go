@@ -0,0 +1,39 @@
+package authenticator
+
+import (
+	"context"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type localAuthenticator struct {
+	userRepo domain.UserRepository
+}
+
+// NewLocalAuthenticator will create new an localAuthenticator object representation of domain.Authenticator interface.
+// It compares the given password with the bcrypt hash stored in users.password
+func NewLocalAuthenticator(userRepo domain.UserRepository) domain.Authenticator {
+	return &localAuthenticator{userRepo}
+}
+
+func (a *localAuthenticator) Authenticate(ctx context.Context, email string, password string) (*domain.User, error) {
+	checkUser, err := a.userRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("email", email),
+		domain.Eq("is_active", true),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if checkUser == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(checkUser.Password), []byte(password))
+	if err != nil {
+		return nil, domain.ErrWrongPassword
+	}
+
+	return checkUser, nil
+}
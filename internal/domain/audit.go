@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// AuditRegister is action of registering a user
+	AuditRegister = "user.register"
+	// AuditLogin is action of logging in
+	AuditLogin = "user.login"
+	// AuditReauthenticate is action of confirming credentials again before a sensitive operation
+	AuditReauthenticate = "user.reauthenticate"
+	// AuditChangeEmail is action of changing email address
+	AuditChangeEmail = "user.change_email"
+	// AuditChangePassword is action of requesting a new password
+	AuditChangePassword = "user.change_password"
+	// AuditActivation is action of activating a registered user
+	AuditActivation = "user.activation"
+	// AuditPasswordConfirm is action of confirming a new password
+	AuditPasswordConfirm = "user.password_confirm"
+	// AuditForgotPasswordRequest is action of requesting a password reset
+	AuditForgotPasswordRequest = "user.forgot_password_request"
+	// AuditForgotPasswordConfirm is action of resetting a forgotten password
+	AuditForgotPasswordConfirm = "user.forgot_password_confirm"
+	// AuditLogoutEverywhere is action of revoking every token issued to user
+	AuditLogoutEverywhere = "user.logout_everywhere"
+
+	// AuditSuccess is outcome of action which has been done
+	AuditSuccess = "success"
+	// AuditFailure is outcome of action which has been rejected or failed
+	AuditFailure = "failure"
+
+	// AuditRedacted replaces sensitive values in metadata of audit event
+	AuditRedacted = "[REDACTED]"
+)
+
+// AuditEvent models, an append-only record of security-relevant action.
+// Hash covers PrevHash and every field of the event, so a modified or removed event breaks the chain
+type AuditEvent struct {
+	UUID       string          `json:"uuid" db:"uuid"`
+	Seq        int64           `json:"seq" db:"seq"`
+	ActorUUID  *string         `json:"actor_uuid" db:"actor_uuid"`
+	TargetUUID *string         `json:"target_uuid" db:"target_uuid"`
+	Action     string          `json:"action" db:"action"`
+	Outcome    string          `json:"outcome" db:"outcome"`
+	Reason     *string         `json:"reason" db:"reason"`
+	IPAddress  string          `json:"ip_address" db:"ip_address"`
+	UserAgent  string          `json:"user_agent" db:"user_agent"`
+	Metadata   json.RawMessage `json:"metadata" db:"metadata"`
+	PrevHash   string          `json:"prev_hash" db:"prev_hash"`
+	Hash       string          `json:"hash" db:"hash"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditGenesisHash is PrevHash of the first audit event
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+// ChainHash compute hash of event chained to PrevHash
+func (e *AuditEvent) ChainHash() string {
+	value := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+
+	h := sha256.New()
+	for _, field := range []string{
+		e.PrevHash,
+		e.UUID,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		value(e.ActorUUID),
+		value(e.TargetUUID),
+		e.Action,
+		e.Outcome,
+		value(e.Reason),
+		e.IPAddress,
+		e.UserAgent,
+		string(e.Metadata),
+	} {
+		// length prefix keeps field boundaries unambiguous
+		fmt.Fprintf(h, "%d:%s", len(field), field)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditEventFilter represent query of audit events, zero value fields are not filtered
+type AuditEventFilter struct {
+	From       *time.Time
+	To         *time.Time
+	Actions    []string
+	ActorUUID  string
+	TargetUUID string
+	// UserUUID matches events of which the user is either actor or target
+	UserUUID string
+}
+
+// AuditVerification is result of checking the hash chain of audit events
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+}
+
+// AuditEventRepository represent the audit event's repository contract, events can not be updated or deleted
+type AuditEventRepository interface {
+	Store(ctx context.Context, event *AuditEvent) (*AuditEvent, error)
+	FindBy(ctx context.Context, filter AuditEventFilter) ([]*AuditEvent, error)
+	FindPage(ctx context.Context, filter AuditEventFilter, page PageRequest) ([]*AuditEvent, *Page, error)
+	FindAfter(ctx context.Context, seq int64, limit uint) ([]*AuditEvent, error)
+}
+
+// AuditUsecase represent the audit event's usecase contract
+type AuditUsecase interface {
+	Fetch(ctx context.Context, filter AuditEventFilter, page PageRequest, parsedToken JWToken) ([]*AuditEvent, *Page, error)
+	Verify(ctx context.Context, parsedToken JWToken) (*AuditVerification, error)
+}
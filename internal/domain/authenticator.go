@@ -0,0 +1,8 @@
+package domain
+
+import "context"
+
+// Authenticator represent the contract of credential verification used on login
+type Authenticator interface {
+	Authenticate(ctx context.Context, email string, password string) (*User, error)
+}
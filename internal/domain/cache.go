@@ -0,0 +1,24 @@
+package domain
+
+// CacheInvalidationRoutingKey is routing key of cache invalidation events
+const CacheInvalidationRoutingKey = "user.cache_invalidation"
+
+// Cache represent the cache contract of repository decorators. Entries are tagged by the records they hold,
+// invalidating a tag removes every entry of the tag
+type Cache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, tags ...string)
+	// SetLoaded stores value loaded after Generation returned generation, unless one of tags has been invalidated
+	// since, so a value loaded before a change is not cached after the change invalidated it
+	SetLoaded(key string, value interface{}, generation uint64, tags ...string)
+	Invalidate(tag string)
+	// Generation returns the current generation, it grows on every invalidation
+	Generation() uint64
+}
+
+// CacheInvalidation is event published when records are changed, every replica drops entries of Tags.
+// Origin is the replica which changed the records, it has already dropped them
+type CacheInvalidation struct {
+	Origin string   `json:"origin"`
+	Tags   []string `json:"tags"`
+}
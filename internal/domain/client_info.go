@@ -0,0 +1,22 @@
+package domain
+
+import "context"
+
+type clientInfoKey struct{}
+
+// ClientInfo represent the client who sends the request
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// WithClientInfo returns copy of ctx carrying the given client info
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns client info carried by ctx, it is empty when there is none
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
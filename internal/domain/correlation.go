@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns copy of ctx carrying the given correlation id, events written in ctx carry it
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns correlation id carried by ctx, it is empty when there is none
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
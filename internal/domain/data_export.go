@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// ExportPending is status of export which is being built
+	ExportPending = "pending"
+	// ExportReady is status of export which can be downloaded
+	ExportReady = "ready"
+	// ExportFailed is status of export which could not be built
+	ExportFailed = "failed"
+)
+
+const (
+	// ExportTimeout bounds building of a single data export, an export pending for longer was left by a stopped build
+	ExportTimeout = time.Minute * 5
+	// ExportTimedOut is error of an export left pending by a stopped build
+	ExportTimedOut = "export timed out"
+)
+
+// DataExport models, an archive of every personal data tied to a user
+type DataExport struct {
+	UUID        string     `json:"uuid" db:"uuid"`
+	UserUUID    string     `json:"user_uuid" db:"user_uuid"`
+	Status      string     `json:"status" db:"status"`
+	FilePath    *string    `json:"-" db:"file_path"`
+	Error       *string    `json:"-" db:"error"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"`
+	DownloadURL string     `json:"download_url,omitempty" db:"-"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// DataExportRepository represent the data export's repository contract
+type DataExportRepository interface {
+	Find(ctx context.Context, uuid string) (*DataExport, error)
+	FindOneBy(ctx context.Context, query *Query) (*DataExport, error)
+	Store(ctx context.Context, export *DataExport) (*DataExport, error)
+	Update(ctx context.Context, export *DataExport) (*DataExport, error)
+	// FailStale marks failed with reason the exports pending since before the given time, it returns number of them
+	FailStale(ctx context.Context, before time.Time, reason string) (int, error)
+}
+
+// DataExportUsecase represent the data export's usecase contract
+type DataExportUsecase interface {
+	Request(ctx context.Context, parsedToken JWToken) (*DataExport, error)
+	Get(ctx context.Context, uuid string, parsedToken JWToken) (*DataExport, error)
+	Download(ctx context.Context, uuid string, expires string, signature string) (*DataExport, error)
+}
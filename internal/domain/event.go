@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of the events, the type is routing key of the published event
+const (
+	EventUserRegistered          = "user.register"
+	EventPasswordChangeRequested = "user.change_password"
+	EventPasswordResetRequested  = "user.forgot_password"
+	EventUserInvited             = "user.invite"
+	EventDataExportReady         = "user.export_ready"
+	EventNewLoginDetected        = "user.new_login"
+)
+
+// Event represent the contract of domain events published through the outbox. Version is version of the schema of its type,
+// fields may be added within a version while removing, renaming or retyping a field requires a new version
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// EventEnvelope is the published message of an event, Data is the JSON encoded event.
+// ID is the same for every delivery of an event, so consumers can drop duplicates
+type EventEnvelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// UserRegistered asks to send the activation token to a registered user
+type UserRegistered struct {
+	UserUUID         string `json:"user_uuid"`
+	EmailDestination string `json:"email_destination"`
+	Token            string `json:"token"`
+}
+
+// EventType returns type of the event
+func (UserRegistered) EventType() string { return EventUserRegistered }
+
+// EventVersion returns version of the event schema
+func (UserRegistered) EventVersion() int { return 1 }
+
+// PasswordChangeRequested asks to send the token confirming a new password to the user
+type PasswordChangeRequested struct {
+	UserUUID         string `json:"user_uuid"`
+	EmailDestination string `json:"email_destination"`
+	Token            string `json:"token"`
+}
+
+// EventType returns type of the event
+func (PasswordChangeRequested) EventType() string { return EventPasswordChangeRequested }
+
+// EventVersion returns version of the event schema
+func (PasswordChangeRequested) EventVersion() int { return 1 }
+
+// PasswordResetRequested asks to send the token resetting a forgotten password to the user
+type PasswordResetRequested struct {
+	UserUUID         string `json:"user_uuid"`
+	EmailDestination string `json:"email_destination"`
+	Token            string `json:"token"`
+}
+
+// EventType returns type of the event
+func (PasswordResetRequested) EventType() string { return EventPasswordResetRequested }
+
+// EventVersion returns version of the event schema
+func (PasswordResetRequested) EventVersion() int { return 1 }
+
+// UserInvited asks to send the invite token to an invited email address
+type UserInvited struct {
+	InvitationUUID   string  `json:"invitation_uuid"`
+	InvitedBy        *string `json:"invited_by"`
+	EmailDestination string  `json:"email_destination"`
+	Token            string  `json:"token"`
+}
+
+// EventType returns type of the event
+func (UserInvited) EventType() string { return EventUserInvited }
+
+// EventVersion returns version of the event schema
+func (UserInvited) EventVersion() int { return 1 }
+
+// DataExportReady asks to send the download link of a built data export to the user
+type DataExportReady struct {
+	UserUUID         string     `json:"user_uuid"`
+	ExportUUID       string     `json:"export_uuid"`
+	EmailDestination string     `json:"email_destination"`
+	DownloadURL      string     `json:"download_url"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+}
+
+// EventType returns type of the event
+func (DataExportReady) EventType() string { return EventDataExportReady }
+
+// EventVersion returns version of the event schema
+func (DataExportReady) EventVersion() int { return 1 }
+
+// NewLoginDetected tells a user about a login from a device or country they have not logged in from before
+type NewLoginDetected struct {
+	UserUUID         string    `json:"user_uuid"`
+	EmailDestination string    `json:"email_destination"`
+	Reasons          []string  `json:"reasons"`
+	IPAddress        string    `json:"ip_address"`
+	Browser          string    `json:"browser"`
+	OS               string    `json:"os"`
+	Device           string    `json:"device"`
+	Country          *string   `json:"country"`
+	City             *string   `json:"city"`
+	LoggedInAt       time.Time `json:"logged_in_at"`
+}
+
+// EventType returns type of the event
+func (NewLoginDetected) EventType() string { return EventNewLoginDetected }
+
+// EventVersion returns version of the event schema
+func (NewLoginDetected) EventVersion() int { return 1 }
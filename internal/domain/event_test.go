@@ -0,0 +1,144 @@
+package domain_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+var (
+	sampleUUID = "7b1c4a52-5c1e-4a53-9a8e-0c52d3f0a111"
+	sampleTime = time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	sampleText = "sample"
+)
+
+// eventSamples are events of every type with every field set. Their JSON is checked against the published schemas
+// in testdata/events, a schema is frozen once published and changing it requires a new version
+var eventSamples = []domain.Event{
+	domain.UserRegistered{UserUUID: sampleUUID, EmailDestination: `quoted"user@mail.com`, Token: "token"},
+	domain.PasswordChangeRequested{UserUUID: sampleUUID, EmailDestination: "user@mail.com", Token: "token"},
+	domain.PasswordResetRequested{UserUUID: sampleUUID, EmailDestination: "user@mail.com", Token: "token"},
+	domain.UserInvited{InvitationUUID: sampleUUID, InvitedBy: &sampleUUID, EmailDestination: "invited@mail.com", Token: "token"},
+	domain.DataExportReady{UserUUID: sampleUUID, ExportUUID: sampleUUID, EmailDestination: "user@mail.com", DownloadURL: "http://localhost", ExpiresAt: &sampleTime},
+	domain.NewLoginDetected{UserUUID: sampleUUID, EmailDestination: "user@mail.com", Reasons: []string{"new_device"}, IPAddress: "203.0.113.7",
+		Browser: "Safari", OS: "iOS", Device: domain.DeviceMobile, Country: &sampleText, City: &sampleText, LoggedInAt: sampleTime},
+}
+
+func TestEventSchemaCompatibility(t *testing.T) {
+	for _, event := range eventSamples {
+		event := event
+		name := fmt.Sprintf("%s.v%d", event.EventType(), event.EventVersion())
+
+		t.Run(name, func(t *testing.T) {
+			schema := readSchema(t, name+".json")
+
+			data, err := json.Marshal(event)
+			require.NoError(t, err)
+			var current interface{}
+			require.NoError(t, json.Unmarshal(data, &current))
+
+			// consumers of the published schema can read the current event
+			assertCompatible(t, "", schemaOf(t, schema), current)
+
+			// the current event type reads published events, none of their fields is dropped
+			decoder := json.NewDecoder(bytes.NewReader(schema))
+			decoder.DisallowUnknownFields()
+			decoded := reflect.New(reflect.TypeOf(event))
+			assert.NoError(t, decoder.Decode(decoded.Interface()))
+		})
+	}
+}
+
+func TestEventSchemasHaveEvents(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "events", "*.v*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	published := make(map[string]bool)
+	for _, event := range eventSamples {
+		published[fmt.Sprintf("%s.v%d.json", event.EventType(), event.EventVersion())] = true
+	}
+
+	// a published schema is only replaced by a later version, its version is still produced meanwhile
+	for _, file := range files {
+		assert.True(t, published[filepath.Base(file)], "no event produces schema %s", filepath.Base(file))
+	}
+}
+
+func TestEventEnvelope(t *testing.T) {
+	correlationID := "correlation-1"
+	message := domain.OutboxMessage{
+		UUID:          sampleUUID,
+		RoutingKey:    domain.EventUserRegistered,
+		Version:       1,
+		CorrelationID: &correlationID,
+		Payload:       `{"email_destination":"quoted\"user@mail.com"}`,
+		CreatedAt:     sampleTime,
+	}
+
+	data, err := json.Marshal(message.Envelope())
+	require.NoError(t, err)
+	var current interface{}
+	require.NoError(t, json.Unmarshal(data, &current))
+	assertCompatible(t, "", schemaOf(t, readSchema(t, "envelope.json")), current)
+
+	var envelope domain.EventEnvelope
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, sampleUUID, envelope.ID)
+	assert.Equal(t, domain.EventUserRegistered, envelope.Type)
+	assert.Equal(t, 1, envelope.Version)
+	assert.True(t, sampleTime.Equal(envelope.OccurredAt))
+	assert.Equal(t, correlationID, envelope.CorrelationID)
+	assert.JSONEq(t, message.Payload, string(envelope.Data))
+}
+
+func readSchema(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "events", name))
+	require.NoError(t, err)
+	return data
+}
+
+func schemaOf(t *testing.T, data []byte) interface{} {
+	var schema interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+	return schema
+}
+
+// assertCompatible checks every field of schema is in current with the same JSON type, fields may be added
+func assertCompatible(t *testing.T, path string, schema interface{}, current interface{}) {
+	switch s := schema.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !assert.True(t, ok, "%s is not an object", path) {
+			return
+		}
+		for key, value := range s {
+			field := strings.TrimPrefix(path+"."+key, ".")
+			if assert.Contains(t, c, key, "%s is missing", field) {
+				assertCompatible(t, field, value, c[key])
+			}
+		}
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !assert.True(t, ok, "%s is not an array", path) {
+			return
+		}
+		if len(s) > 0 && len(c) > 0 {
+			assertCompatible(t, path+"[]", s[0], c[0])
+		}
+	case nil:
+	default:
+		assert.Equal(t, reflect.TypeOf(schema), reflect.TypeOf(current), "%s changed type", path)
+	}
+}
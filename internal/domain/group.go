@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Group models
+type Group struct {
+	UUID        string    `json:"uuid" db:"uuid"`
+	TenantUUID  string    `json:"tenant_uuid" db:"tenant_uuid"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	ExternalID  *string   `json:"external_id" db:"external_id"`
+	Members     []string  `json:"members" db:"-"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// GroupRepository represent the group's repository contract
+type GroupRepository interface {
+	Find(ctx context.Context, uuid string) (*Group, error)
+	FindOneBy(ctx context.Context, query *Query) (*Group, error)
+	FindBy(ctx context.Context, query *Query) ([]*Group, error)
+	FindPage(ctx context.Context, query *Query, page PageRequest) ([]*Group, *Page, error)
+	Count(ctx context.Context, query *Query) (int, error)
+	Store(ctx context.Context, group *Group) (*Group, error)
+	Update(ctx context.Context, group *Group) (*Group, error)
+	Delete(ctx context.Context, uuid string) error
+	DeleteMember(ctx context.Context, userUUID string) error
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Invitation models, an admin's invitation for an email address to register
+type Invitation struct {
+	UUID       string     `json:"uuid" db:"uuid"`
+	Email      string     `json:"email" db:"email" validate:"required,email"`
+	Token      string     `json:"-" db:"token"`
+	InvitedBy  *string    `json:"invited_by" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at" db:"accepted_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// InvitationRepository represent the invitation's repository contract
+type InvitationRepository interface {
+	FindOneBy(ctx context.Context, query *Query) (*Invitation, error)
+	Store(ctx context.Context, invitation *Invitation) (*Invitation, error)
+	Update(ctx context.Context, invitation *Invitation) (*Invitation, error)
+}
+
+// InvitationUsecase represent the invitation's usecase contract
+type InvitationUsecase interface {
+	Invite(ctx context.Context, invitation *Invitation, parsedToken JWToken) (token string, err error)
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// LoginPassword is method of login by email and password, local or directory
+	LoginPassword = "password"
+	// LoginSaml is method of login through saml identity provider
+	LoginSaml = "saml"
+
+	// DeviceDesktop is device class of desktop browser
+	DeviceDesktop = "desktop"
+	// DeviceMobile is device class of mobile browser
+	DeviceMobile = "mobile"
+	// DeviceBot is device class of crawler or script
+	DeviceBot = "bot"
+)
+
+// LoginAttempt models, a record of successful or failed login.
+// UserUUID is nil when the email does not belong to any user
+type LoginAttempt struct {
+	UUID              string    `json:"uuid" db:"uuid"`
+	UserUUID          *string   `json:"-" db:"user_uuid"`
+	Email             string    `json:"email" db:"email"`
+	Method            string    `json:"method" db:"method"`
+	Success           bool      `json:"success" db:"success"`
+	FailureReason     *string   `json:"failure_reason" db:"failure_reason"`
+	IPAddress         string    `json:"ip_address" db:"ip_address"`
+	UserAgent         string    `json:"user_agent" db:"user_agent"`
+	Browser           string    `json:"browser" db:"browser"`
+	BrowserVersion    string    `json:"browser_version" db:"browser_version"`
+	OS                string    `json:"os" db:"os"`
+	Device            string    `json:"device" db:"device"`
+	DeviceFingerprint string    `json:"-" db:"device_fingerprint"`
+	CountryCode       *string   `json:"country_code" db:"country_code"`
+	Country           *string   `json:"country" db:"country"`
+	City              *string   `json:"city" db:"city"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+// GeoLocation is coarse location of an IP address
+type GeoLocation struct {
+	CountryCode string
+	Country     string
+	City        string
+}
+
+// GeoLocator represent lookup of IP address location, nil location is returned when it is unknown
+type GeoLocator interface {
+	Locate(ip string) (*GeoLocation, error)
+}
+
+// LoginAttemptRepository represent the login attempt's repository contract
+type LoginAttemptRepository interface {
+	FindBy(ctx context.Context, query *Query) ([]*LoginAttempt, error)
+	FindPage(ctx context.Context, query *Query, page PageRequest) ([]*LoginAttempt, *Page, error)
+	Count(ctx context.Context, query *Query) (int, error)
+	Store(ctx context.Context, attempt *LoginAttempt) (*LoginAttempt, error)
+}
+
+// LoginHistoryUsecase represent the login history's usecase contract
+type LoginHistoryUsecase interface {
+	Record(ctx context.Context, method string, email string, user *User, loginErr error)
+	Fetch(ctx context.Context, parsedToken JWToken, page PageRequest) ([]*LoginAttempt, *Page, error)
+}
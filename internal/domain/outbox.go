@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// MessageIDHeader is header of a message published from the outbox carrying its UUID, it is also the message id
+// of the publishing. A message may be delivered more than once so consumers drop the ids they have already handled
+const MessageIDHeader = "message_id"
+
+// OutboxMessage is an event written in the transaction of the change it describes, a relay publishes it afterwards.
+// Payload is the JSON encoded event of type RoutingKey and schema Version
+type OutboxMessage struct {
+	UUID          string     `db:"uuid"`
+	RoutingKey    string     `db:"routing_key"`
+	Version       int        `db:"version"`
+	CorrelationID *string    `db:"correlation_id"`
+	Payload       string     `db:"payload"`
+	Attempts      int        `db:"attempts"`
+	LastError     *string    `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LockedUntil   *time.Time `db:"locked_until"`
+	SentAt        *time.Time `db:"sent_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+// Envelope returns the published message of the event, UUID of the message is the event id
+// and the event occurred when the message was written
+func (m *OutboxMessage) Envelope() EventEnvelope {
+	envelope := EventEnvelope{
+		ID:         m.UUID,
+		Type:       m.RoutingKey,
+		Version:    m.Version,
+		OccurredAt: m.CreatedAt,
+		Data:       json.RawMessage(m.Payload),
+	}
+	if m.CorrelationID != nil {
+		envelope.CorrelationID = *m.CorrelationID
+	}
+	return envelope
+}
+
+// OutboxRepository represent the outbox message's repository contract
+type OutboxRepository interface {
+	Store(ctx context.Context, message *OutboxMessage) (*OutboxMessage, error)
+	// Claim returns at most limit unsent messages whose next attempt is due, oldest first. They are claimed
+	// until lease is over, so another relay skips them without a transaction kept open while they are published
+	Claim(ctx context.Context, limit uint, lease time.Duration) ([]*OutboxMessage, error)
+	// Update stores the attempt of message and releases its claim
+	Update(ctx context.Context, message *OutboxMessage) (*OutboxMessage, error)
+	// DeleteSent deletes messages sent before the given time, it returns number of deleted messages
+	DeleteSent(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// DefaultPageLimit is number of records of a page when no limit is requested
+const DefaultPageLimit = 20
+
+// Cursor is position of a record in (created_at, uuid) order, it is handed to clients signed and opaque
+type Cursor struct {
+	Time time.Time `json:"t"`
+	UUID string    `json:"u"`
+}
+
+// PageRequest ask for a page of records in (created_at, uuid) order, newest first when Desc.
+// After continues with records following the cursor, Before goes back to records preceding it
+type PageRequest struct {
+	Limit     uint
+	After     *Cursor
+	Before    *Cursor
+	Desc      bool
+	WithTotal bool
+}
+
+// Page describes a page of records returned by a list method, Next and Prev are nil when there is no such page.
+// Total is only counted when requested
+type Page struct {
+	Next  *Cursor
+	Prev  *Cursor
+	Total *int
+}
+
+// Size returns limit of the request, DefaultPageLimit when it is not set
+func (p PageRequest) Size() uint {
+	if p.Limit == 0 {
+		return DefaultPageLimit
+	}
+	return p.Limit
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// PasswordPolicy represent rules of local passwords, zero value disables a rule
+type PasswordPolicy struct {
+	// HistorySize is number of latest passwords, the current one included, which can not be reused
+	HistorySize int
+	// MaxAge is lifetime of password, login with an older password requires changing it
+	MaxAge time.Duration
+}
+
+// PasswordHistory models, a bcrypt hash of password which has been replaced
+type PasswordHistory struct {
+	UUID      string    `json:"uuid" db:"uuid"`
+	UserUUID  string    `json:"user_uuid" db:"user_uuid"`
+	Password  string    `json:"-" db:"password"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// PasswordHistoryRepository represent the password history's repository contract
+type PasswordHistoryRepository interface {
+	FindRecent(ctx context.Context, userUUID string, limit uint) ([]*PasswordHistory, error)
+	Store(ctx context.Context, history *PasswordHistory) (*PasswordHistory, error)
+	Prune(ctx context.Context, userUUID string, keep uint) error
+}
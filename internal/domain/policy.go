@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// PolicyTermsOfService is kind of terms of service document
+	PolicyTermsOfService = "terms_of_service"
+	// PolicyPrivacy is kind of privacy policy document
+	PolicyPrivacy = "privacy_policy"
+)
+
+// PolicyDocument models, a version of legal document users have to accept.
+// The current version of a kind is the latest one already published
+type PolicyDocument struct {
+	UUID        string    `json:"uuid" db:"uuid"`
+	Kind        string    `json:"kind" db:"kind" validate:"required,oneof=terms_of_service privacy_policy"`
+	Version     string    `json:"version" db:"version" validate:"required,max=50"`
+	URL         string    `json:"url" db:"url" validate:"required,url"`
+	PublishedAt time.Time `json:"published_at" db:"published_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Consent models, proof that user accepted a version of policy document
+type Consent struct {
+	UUID         string    `json:"uuid" db:"uuid"`
+	UserUUID     string    `json:"-" db:"user_uuid"`
+	DocumentUUID string    `json:"document_uuid" db:"document_uuid"`
+	Kind         string    `json:"kind" db:"kind" validate:"required"`
+	Version      string    `json:"version" db:"version" validate:"required"`
+	IPAddress    *string   `json:"ip_address" db:"ip_address"`
+	UserAgent    *string   `json:"user_agent" db:"user_agent"`
+	AcceptedAt   time.Time `json:"accepted_at" db:"accepted_at"`
+}
+
+// PolicyDocumentRepository represent the policy document's repository contract
+type PolicyDocumentRepository interface {
+	FindCurrent(ctx context.Context) ([]*PolicyDocument, error)
+	FindOneBy(ctx context.Context, query *Query) (*PolicyDocument, error)
+	Store(ctx context.Context, document *PolicyDocument) (*PolicyDocument, error)
+}
+
+// ConsentRepository represent the consent's repository contract
+type ConsentRepository interface {
+	FindByUser(ctx context.Context, userUUID string) ([]*Consent, error)
+	FindPageByUser(ctx context.Context, userUUID string, page PageRequest) ([]*Consent, *Page, error)
+	Store(ctx context.Context, consent *Consent) (*Consent, error)
+}
+
+// ConsentUsecase represent the consent's usecase contract
+type ConsentUsecase interface {
+	Current(ctx context.Context) ([]*PolicyDocument, error)
+	Publish(ctx context.Context, document *PolicyDocument, parsedToken JWToken) error
+	Pending(ctx context.Context, parsedToken JWToken) ([]*PolicyDocument, error)
+	Accept(ctx context.Context, consents []Consent, parsedToken JWToken) error
+	History(ctx context.Context, parsedToken JWToken, page PageRequest) ([]*Consent, *Page, error)
+}
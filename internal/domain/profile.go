@@ -5,7 +5,7 @@ import (
 	"time"
 )
 
-// Profile models
+// Profile models, Version is incremented by every update so concurrent updates can be detected
 type Profile struct {
 	UUID      string     `json:"uuid" db:"uuid"`
 	User      User       `json:"user" db:"user"`
@@ -16,6 +16,7 @@ type Profile struct {
 	Address   *string    `json:"address" db:"address"`
 	Gender    *string    `json:"gender" db:"gender"`
 	Dob       *time.Time `json:"dob" db:"dob"`
+	Version   int        `json:"version" db:"version"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 }
@@ -23,17 +24,25 @@ type Profile struct {
 // ProfileRepository represent profile's repository contract
 type ProfileRepository interface {
 	Find(ctx context.Context, uuid string) (*Profile, error)
-	FindOneBy(ctx context.Context, criteria map[string]interface{}, orderBy *map[string]string) (*Profile, error)
+	FindOneBy(ctx context.Context, query *Query) (*Profile, error)
+	// FindAll loads every record at once, lists should be read with FindPage
 	FindAll(context.Context) ([]*Profile, error)
-	FindBy(ctx context.Context, criteria map[string]interface{}, orderBy *map[string]string, limit *uint, offest *uint) ([]*Profile, error)
+	FindBy(ctx context.Context, query *Query) ([]*Profile, error)
+	FindPage(ctx context.Context, query *Query, page PageRequest) ([]*Profile, *Page, error)
+	Count(ctx context.Context, query *Query) (int, error)
 	Store(ctx context.Context, profile *Profile) (*Profile, error)
+	// Update only succeeds when Version is the stored version, ErrConflict is returned otherwise
 	Update(ctx context.Context, profile *Profile) error
 }
 
 // ProfileUsecase represent profile's usecase contract
 type ProfileUsecase interface {
-	Store(ctx context.Context, profile *Profile) (*Profile, error)
-	GetByUUID(ctx context.Context, uuid string) (*Profile, error)
-	Fetch(context.Context) ([]*Profile, error)
-	Update(ctx context.Context, profile *Profile) (*Profile, error)
+	Get(ctx context.Context, parsedToken JWToken) (*Profile, error)
+	// Update replaces profile of token user, Version is the expected version of profile or zero to update any version
+	Update(ctx context.Context, profile *Profile, parsedToken JWToken) (*Profile, error)
+}
+
+// ProfileKeyRepository represent re-encryption of profile's personal data under the current key
+type ProfileKeyRepository interface {
+	ReencryptBatch(ctx context.Context, limit uint) (int, error)
 }
@@ -0,0 +1,18 @@
+package domain
+
+import "context"
+
+// ProvisioningUsecase represent the contract of users and groups provisioning by tenant's identity provider
+type ProvisioningUsecase interface {
+	Authenticate(ctx context.Context, token string) (*Tenant, error)
+	FetchUsers(ctx context.Context, tenant *Tenant, query *Query, limit uint, offset uint) ([]*User, []*Profile, int, error)
+	GetUser(ctx context.Context, tenant *Tenant, uuid string) (*User, *Profile, error)
+	StoreUser(ctx context.Context, tenant *Tenant, user *User, profile *Profile) error
+	UpdateUser(ctx context.Context, tenant *Tenant, user *User, profile *Profile) error
+	DeleteUser(ctx context.Context, tenant *Tenant, uuid string) error
+	FetchGroups(ctx context.Context, tenant *Tenant, query *Query, limit uint, offset uint) ([]*Group, int, error)
+	GetGroup(ctx context.Context, tenant *Tenant, uuid string) (*Group, error)
+	StoreGroup(ctx context.Context, tenant *Tenant, group *Group) error
+	UpdateGroup(ctx context.Context, tenant *Tenant, group *Group) error
+	DeleteGroup(ctx context.Context, tenant *Tenant, uuid string) error
+}
@@ -0,0 +1,6 @@
+package domain
+
+// Publisher represent the message broker contract used by usecase which publishes outside of a request
+type Publisher interface {
+	Publish(message string, routingKey string, header map[string]interface{}) error
+}
@@ -0,0 +1,165 @@
+package domain
+
+import "strings"
+
+// Operator is comparison applied by a query condition
+type Operator string
+
+// Operators supported by query condition
+const (
+	OpEqual        Operator = "="
+	OpNotEqual     Operator = "!="
+	OpIn           Operator = "IN"
+	OpLike         Operator = "LIKE"
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+	OpBetween      Operator = "BETWEEN"
+	OpIsNull       Operator = "IS NULL"
+	OpIsNotNull    Operator = "IS NOT NULL"
+	OpAnd          Operator = "AND"
+	OpOr           Operator = "OR"
+)
+
+// Condition is comparison of a column with value, or group of conditions when operator is OpAnd or OpOr
+type Condition struct {
+	Column     string
+	Operator   Operator
+	Value      interface{}
+	Conditions []Condition
+}
+
+// Order is sort of a column
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Query is a typed specification of records to find.
+// Conditions are joined by AND and rendered in the given order, so the same query always produces the same SQL
+type Query struct {
+	Conditions []Condition
+	Orders     []Order
+	Limit      *uint
+	Offset     *uint
+}
+
+// Eq will match column equal to value
+func Eq(column string, value interface{}) Condition {
+	return Condition{Column: column, Operator: OpEqual, Value: value}
+}
+
+// NotEq will match column not equal to value
+func NotEq(column string, value interface{}) Condition {
+	return Condition{Column: column, Operator: OpNotEqual, Value: value}
+}
+
+// In will match column equal to any of values, it matches nothing when values is empty
+func In(column string, values ...interface{}) Condition {
+	return Condition{Column: column, Operator: OpIn, Value: values}
+}
+
+// Like will match column with case insensitive LIKE pattern, use EscapeLike for literal part of the pattern
+func Like(column string, pattern string) Condition {
+	return Condition{Column: column, Operator: OpLike, Value: pattern}
+}
+
+// Gt will match column greater than value
+func Gt(column string, value interface{}) Condition {
+	return Condition{Column: column, Operator: OpGreater, Value: value}
+}
+
+// Gte will match column greater than or equal to value
+func Gte(column string, value interface{}) Condition {
+	return Condition{Column: column, Operator: OpGreaterEqual, Value: value}
+}
+
+// Lt will match column less than value
+func Lt(column string, value interface{}) Condition {
+	return Condition{Column: column, Operator: OpLess, Value: value}
+}
+
+// Lte will match column less than or equal to value
+func Lte(column string, value interface{}) Condition {
+	return Condition{Column: column, Operator: OpLessEqual, Value: value}
+}
+
+// Between will match column within from and to, both inclusive
+func Between(column string, from interface{}, to interface{}) Condition {
+	return Condition{Column: column, Operator: OpBetween, Value: []interface{}{from, to}}
+}
+
+// IsNull will match column which is NULL
+func IsNull(column string) Condition {
+	return Condition{Column: column, Operator: OpIsNull}
+}
+
+// IsNotNull will match column which is not NULL
+func IsNotNull(column string) Condition {
+	return Condition{Column: column, Operator: OpIsNotNull}
+}
+
+// And will match when all of conditions match
+func And(conditions ...Condition) Condition {
+	return Condition{Operator: OpAnd, Conditions: conditions}
+}
+
+// Or will match when any of conditions match, it matches nothing when conditions is empty
+func Or(conditions ...Condition) Condition {
+	return Condition{Operator: OpOr, Conditions: conditions}
+}
+
+// Asc will sort column in ascending order
+func Asc(column string) Order {
+	return Order{Column: column}
+}
+
+// Desc will sort column in descending order
+func Desc(column string) Order {
+	return Order{Column: column, Desc: true}
+}
+
+// Where will create new a Query matching all of conditions
+func Where(conditions ...Condition) *Query {
+	return &Query{Conditions: conditions}
+}
+
+// And returns copy of query which also has to match conditions, q may be nil
+func (q *Query) And(conditions ...Condition) *Query {
+	c := q.clone()
+	c.Conditions = append(c.Conditions, conditions...)
+	return c
+}
+
+// OrderBy returns copy of query sorted by orders, sorts are applied in the given order
+func (q *Query) OrderBy(orders ...Order) *Query {
+	c := q.clone()
+	c.Orders = append(c.Orders, orders...)
+	return c
+}
+
+// Paginate returns copy of query returning at most limit records after skipping offset
+func (q *Query) Paginate(limit uint, offset uint) *Query {
+	c := q.clone()
+	c.Limit = &limit
+	c.Offset = &offset
+	return c
+}
+
+func (q *Query) clone() *Query {
+	c := new(Query)
+	if q == nil {
+		return c
+	}
+
+	*c = *q
+	c.Conditions = append([]Condition(nil), q.Conditions...)
+	c.Orders = append([]Order(nil), q.Orders...)
+	return c
+}
+
+// EscapeLike escapes the LIKE wildcard characters of the given value, so it is matched literally
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites returns copy of ctx whose reads see every committed write, they are not routed to read replicas
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadYourWritesFromContext reports whether reads of ctx must see every committed write
+func ReadYourWritesFromContext(ctx context.Context) bool {
+	required, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return required
+}
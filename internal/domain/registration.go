@@ -0,0 +1,18 @@
+package domain
+
+const (
+	// RegistrationOpen let anyone register
+	RegistrationOpen = "open"
+	// RegistrationInvite let only invited email addresses register
+	RegistrationInvite = "invite"
+	// RegistrationDomain let only email addresses of allowed domains register
+	RegistrationDomain = "domain"
+)
+
+// RegistrationPolicy represent who may register a new user.
+// An invitation is honored in every mode, disposable email domains are always rejected
+type RegistrationPolicy struct {
+	Mode              string
+	AllowedDomains    []string
+	DisposableDomains []string
+}
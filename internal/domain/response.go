@@ -1,9 +1,9 @@
 package domain
 
 import (
-	"errors"
 	"net/http"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -24,6 +24,53 @@ var (
 	ErrUserNotFound = errors.New("User not found! ")
 	// ErrUserAlreadyExist /
 	ErrUserAlreadyExist = errors.New("User already exist! ")
+	// ErrProfileNotFound /
+	ErrProfileNotFound = errors.New("Profile not found! ")
+	// ErrConflict will throw if the record has been modified by another request since it was read
+	ErrConflict = errors.New("Record has been modified by another request! ")
+	// ErrGroupNotFound /
+	ErrGroupNotFound = errors.New("Group not found! ")
+	// ErrGroupAlreadyExist /
+	ErrGroupAlreadyExist = errors.New("Group already exist! ")
+	// ErrSamlProviderNotFound /
+	ErrSamlProviderNotFound = errors.New("SAML identity provider not found! ")
+	// ErrInvalidSamlResponse will throw if the given saml response or its assertion is not valid
+	ErrInvalidSamlResponse = errors.New("Invalid SAML response! ")
+	// ErrForbidden will throw if the user of the given token is not allowed to do the request
+	ErrForbidden = errors.New("Forbidden")
+	// ErrInvitationRequired will throw if registration is invitation only and no invitation is given
+	ErrInvitationRequired = errors.New("Registration requires an invitation! ")
+	// ErrInvalidInvitation will throw if the given invitation is unknown, expired, already accepted or for another email
+	ErrInvalidInvitation = errors.New("Invitation is invalid or expired! ")
+	// ErrEmailDomainNotAllowed will throw if registration is restricted and email domain is not allowed,
+	// unlike ErrInvitationRequired registering is allowed with another email address
+	ErrEmailDomainNotAllowed = errors.New("Email domain is not allowed to register! ")
+	// ErrDisposableEmail will throw if email address belongs to a disposable email provider
+	ErrDisposableEmail = errors.New("Disposable email address is not allowed! ")
+	// ErrPolicyNotAccepted will throw if the given consents do not cover every current policy document
+	ErrPolicyNotAccepted = errors.New("Current terms of service and privacy policy must be accepted! ")
+	// ErrConsentRequired will throw if a new policy version is published and user has not accepted it yet
+	ErrConsentRequired = errors.New("New policy version must be accepted! ")
+	// ErrPolicyAlreadyExist /
+	ErrPolicyAlreadyExist = errors.New("Policy version already exist! ")
+	// ErrExportNotFound /
+	ErrExportNotFound = errors.New("Data export not found! ")
+	// ErrExportNotReady will throw if the requested data export is still being built or has failed
+	ErrExportNotReady = errors.New("Data export is not ready! ")
+	// ErrExportExpired will throw if the download link or the data export itself has expired
+	ErrExportExpired = errors.New("Data export link has expired! ")
+	// ErrReauthenticationRequired will throw if the token is valid but credentials were checked too long ago for a sensitive operation
+	ErrReauthenticationRequired = errors.New("Recent authentication is required! ")
+	// ErrPasswordReused will throw if the new password is one of the latest passwords of user
+	ErrPasswordReused = errors.New("Password has been used recently! ")
+	// ErrPasswordChangeRequired will throw if password of user has expired, login returns a restricted token to change it
+	ErrPasswordChangeRequired = errors.New("Password has expired, password change required! ")
+	// ErrPreconditionFailed will throw if the given If-Match header does not match the current version of resource
+	ErrPreconditionFailed = errors.New("Precondition failed! ")
+	// ErrInvalidQuery will throw if query references an unknown column or uses an operator with invalid value
+	ErrInvalidQuery = errors.New("Invalid query! ")
+	// ErrInvalidCursor will throw if pagination cursor is malformed or has been altered
+	ErrInvalidCursor = errors.New("Invalid cursor! ")
 )
 
 // Response represent response structure of request
@@ -45,7 +92,7 @@ type Validation struct {
 	Param     string `json:"param"`
 }
 
-// GetStatusCode will return status code based on type of error
+// GetStatusCode will return status code based on type of error, error wrapped by errors.Wrap is mapped by its cause
 func GetStatusCode(err error) int {
 	if err == nil {
 		return http.StatusOK
@@ -53,7 +100,7 @@ func GetStatusCode(err error) int {
 
 	logrus.Error(err)
 
-	switch err {
+	switch errors.Cause(err) {
 	case ErrEmailAlreadyExist:
 		return http.StatusConflict
 	case ErrUserAlreadyExist:
@@ -62,12 +109,58 @@ func GetStatusCode(err error) int {
 		return http.StatusNotFound
 	case ErrUserNotFound:
 		return http.StatusNotFound
+	case ErrProfileNotFound:
+		return http.StatusNotFound
+	case ErrConflict:
+		return http.StatusConflict
+	case ErrGroupAlreadyExist:
+		return http.StatusConflict
+	case ErrGroupNotFound:
+		return http.StatusNotFound
+	case ErrSamlProviderNotFound:
+		return http.StatusNotFound
+	case ErrInvalidSamlResponse:
+		return http.StatusUnauthorized
+	case ErrForbidden:
+		return http.StatusForbidden
+	case ErrInvitationRequired:
+		return http.StatusForbidden
+	case ErrInvalidInvitation:
+		return http.StatusGone
+	case ErrEmailDomainNotAllowed:
+		return http.StatusUnprocessableEntity
+	case ErrDisposableEmail:
+		return http.StatusUnprocessableEntity
+	case ErrPolicyNotAccepted:
+		return http.StatusUnprocessableEntity
+	case ErrConsentRequired:
+		return http.StatusForbidden
+	case ErrPolicyAlreadyExist:
+		return http.StatusConflict
+	case ErrExportNotFound:
+		return http.StatusNotFound
+	case ErrExportNotReady:
+		return http.StatusConflict
+	case ErrExportExpired:
+		return http.StatusGone
+	case ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrInvalidQuery:
+		return http.StatusBadRequest
+	case ErrInvalidCursor:
+		return http.StatusBadRequest
 	case ErrWrongPassword:
 		return http.StatusForbidden
 	case ErrInternalServerError:
 		return http.StatusInternalServerError
 	case ErrUnauthorized:
 		return http.StatusUnauthorized
+	case ErrReauthenticationRequired:
+		return http.StatusUnauthorized
+	case ErrPasswordReused:
+		return http.StatusUnprocessableEntity
+	case ErrPasswordChangeRequired:
+		return http.StatusForbidden
 	case ErrStatusUnprocessableEntity:
 		return http.StatusUnprocessableEntity
 	default:
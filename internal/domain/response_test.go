@@ -0,0 +1,28 @@
+package domain_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+func TestGetStatusCode(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{nil, http.StatusOK},
+		{domain.ErrInvitationRequired, http.StatusForbidden},
+		{domain.ErrEmailDomainNotAllowed, http.StatusUnprocessableEntity},
+		{errors.Wrap(domain.ErrEmailDomainNotAllowed, "Register"), http.StatusUnprocessableEntity},
+		{errors.New("unknown"), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.status, domain.GetStatusCode(test.err), "%v", test.err)
+	}
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SamlProvider models, an identity provider configured for a tenant or an email domain
+type SamlProvider struct {
+	UUID               string    `json:"uuid" db:"uuid"`
+	TenantUUID         *string   `json:"tenant_uuid" db:"tenant_uuid"`
+	EmailDomain        *string   `json:"email_domain" db:"email_domain"`
+	IdpMetadata        string    `json:"idp_metadata" db:"idp_metadata"`
+	EmailAttribute     string    `json:"email_attribute" db:"email_attribute"`
+	FirstNameAttribute string    `json:"first_name_attribute" db:"first_name_attribute"`
+	LastNameAttribute  string    `json:"last_name_attribute" db:"last_name_attribute"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+}
+
+// SamlProviderRepository represent the saml provider's repository contract
+type SamlProviderRepository interface {
+	Find(ctx context.Context, uuid string) (*SamlProvider, error)
+	FindOneBy(ctx context.Context, query *Query) (*SamlProvider, error)
+}
+
+// SamlAssertionRepository represent the repository contract of consumed assertions, so an assertion is accepted once
+type SamlAssertionRepository interface {
+	// Store records assertion of provider until expiresAt, it is false when the assertion is already recorded
+	Store(ctx context.Context, providerUUID string, assertionID string, expiresAt time.Time) (bool, error)
+}
+
+// SamlUsecase represent the saml single sign-on usecase contract
+type SamlUsecase interface {
+	Metadata(ctx context.Context, providerUUID string) ([]byte, error)
+	AuthnRequest(ctx context.Context, email string, tenantUUID string) (redirectURL string, err error)
+	Login(ctx context.Context, providerUUID string, samlResponse string, relayState string) (token string, err error)
+}
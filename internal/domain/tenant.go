@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Tenant models
+type Tenant struct {
+	UUID                string    `json:"uuid" db:"uuid"`
+	Name                string    `json:"name" db:"name"`
+	ScimToken           *string   `json:"-" db:"scim_token"`
+	PasswordHistorySize *int      `json:"password_history_size" db:"password_history_size"`
+	PasswordMaxAgeDays  *int      `json:"password_max_age_days" db:"password_max_age_days"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+}
+
+// TenantRepository represent the tenant's repository contract
+type TenantRepository interface {
+	Find(ctx context.Context, uuid string) (*Tenant, error)
+	FindOneBy(ctx context.Context, query *Query) (*Tenant, error)
+	Store(ctx context.Context, tenant *Tenant) (*Tenant, error)
+}
@@ -4,10 +4,34 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-//JWToken struct declaration
+// ScopePasswordChange is scope of restricted token issued on login with an expired password,
+// it is only accepted to change password
+const ScopePasswordChange = "password_change"
+
+// Types of token, a token is only accepted by the operation it is issued for
+const (
+	// TokenLogin is type of token issued on login, it is the only type accepted as access token
+	TokenLogin = "login"
+	// TokenActivation is type of token sent on register to activate user
+	TokenActivation = "activation"
+	// TokenPasswordConfirm is type of token sent on change password to confirm the new password
+	TokenPasswordConfirm = "password_confirm"
+	// TokenForgotPassword is type of token sent on forgot password to set a new password
+	TokenForgotPassword = "forgot_password"
+)
+
+// JWToken struct declaration
 type JWToken struct {
 	UUID  string
 	Email string
-	Salt  string
+	// SecurityStamp is security stamp of user when token is issued, token is rejected once the stamp is rotated
+	SecurityStamp string `json:"security_stamp"`
+	Role          string
+	// AuthTime is unix time when user last proved their credentials
+	AuthTime int64 `json:"auth_time,omitempty"`
+	// Scope restricts token to a single operation, token without scope is a full token
+	Scope string `json:"scope,omitempty"`
+	// Type is the operation token is issued for, one of the token types above
+	Type string `json:"typ"`
 	*jwt.StandardClaims
 }
@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// Transactor represent the unit of work contract. Repositories called with the context given to fn share its transaction,
+// which is committed when fn returns nil and rolled back otherwise. Calling WithinTransaction inside fn joins the running transaction
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
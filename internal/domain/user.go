@@ -5,25 +5,43 @@ import (
 	"time"
 )
 
+const (
+	// RoleUser is the default role of every registered user
+	RoleUser = "user"
+	// RoleAdmin is the role of user who can manage another users
+	RoleAdmin = "admin"
+)
+
 // User models
 type User struct {
-	UUID        string    `json:"uuid" db:"uuid"`
-	Email       string    `json:"email" db:"email" validate:"required,email"`
-	Password    string    `json:"password" db:"password" validate:"required"`
-	NewPassword *string   `json:"new_password" db:"new_password"`
-	IsActive    bool      `json:"is_active" db:"is_active"`
-	Salt        string    `json:"salt" db:"salt"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UUID              string    `json:"uuid" db:"uuid"`
+	Email             string    `json:"email" db:"email" validate:"required,email"`
+	Password          string    `json:"password" db:"password" validate:"required"`
+	NewPassword       *string   `json:"new_password" db:"new_password"`
+	IsActive          bool      `json:"is_active" db:"is_active"`
+	Role              string    `json:"role" db:"role"`
+	TenantUUID        *string   `json:"-" db:"tenant_uuid"`
+	ExternalID        *string   `json:"-" db:"external_id"`
+	SecurityStamp     string    `json:"-" db:"security_stamp"`
+	InviteToken       string    `json:"invite_token,omitempty" db:"-"`
+	Consents          []Consent `json:"consents,omitempty" db:"-"`
+	PasswordChangedAt time.Time `json:"password_changed_at" db:"password_changed_at"`
+	Version           int       `json:"version" db:"version"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
 }
 
 // UserRepository represent the users's repository contract
 type UserRepository interface {
 	Find(ctx context.Context, uuid string) (*User, error)
-	FindOneBy(ctx context.Context, criteria map[string]interface{}, orderBy *map[string]string) (*User, error)
+	FindOneBy(ctx context.Context, query *Query) (*User, error)
+	// FindAll loads every record at once, lists should be read with FindPage
 	FindAll(context.Context) ([]*User, error)
-	FindBy(ctx context.Context, criteria map[string]interface{}, orderBy *map[string]string, limit *uint, offest *uint) ([]*User, error)
+	FindBy(ctx context.Context, query *Query) ([]*User, error)
+	FindPage(ctx context.Context, query *Query, page PageRequest) ([]*User, *Page, error)
+	Count(ctx context.Context, query *Query) (int, error)
 	Store(ctx context.Context, user *User) (*User, error)
+	// Update only succeeds when Version is the stored version, ErrConflict is returned otherwise
 	Update(ctx context.Context, user *User) (*User, error)
 }
 
@@ -31,10 +49,12 @@ type UserRepository interface {
 type UserUsecase interface {
 	Register(ctx context.Context, user *User) (token string, err error)
 	Login(ctx context.Context, user *User) (token string, err error)
+	Reauthenticate(ctx context.Context, password string, parsedToken JWToken) (token string, err error)
 	ChangeEmail(ctx context.Context, user *User, parsedToken JWToken) error
 	Activation(ctx context.Context, parsedToken JWToken) error
 	ChangePassword(ctx context.Context, user *User, parsedToken JWToken) (tokenConfirmation string, err error)
 	PasswordConfirm(ctx context.Context, parsedToken JWToken) error
 	ForgotPasswordRequest(ctx context.Context, email string) (token string, err error)
 	ForgotPasswordConfirm(ctx context.Context, user *User, parsedToken JWToken) error
+	LogoutEverywhere(ctx context.Context, parsedToken JWToken) error
 }
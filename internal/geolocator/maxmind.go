@@ -0,0 +1,73 @@
+package geolocator
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type maxmindLocator struct {
+	db *geoip2.Reader
+}
+
+// NewMaxmindLocator will create new an maxmindLocator object representation of domain.GeoLocator interface.
+// Path is a local GeoLite2/GeoIP2 City or Country database file, it is loaded once
+func NewMaxmindLocator(path string) (domain.GeoLocator, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open geoip database")
+	}
+
+	return &maxmindLocator{db}, nil
+}
+
+// Locate returns country and city of ip, private and unknown addresses have no location
+func (m *maxmindLocator) Locate(ip string) (*domain.GeoLocation, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, nil
+	}
+
+	switch m.db.Metadata().DatabaseType {
+	case "GeoIP2-Country", "GeoLite2-Country":
+		record, err := m.db.Country(addr)
+		if err != nil {
+			return nil, err
+		}
+		if record.Country.IsoCode == "" {
+			return nil, nil
+		}
+		return &domain.GeoLocation{
+			CountryCode: record.Country.IsoCode,
+			Country:     record.Country.Names["en"],
+		}, nil
+	}
+
+	record, err := m.db.City(addr)
+	if err != nil {
+		return nil, err
+	}
+	if record.Country.IsoCode == "" {
+		return nil, nil
+	}
+
+	return &domain.GeoLocation{
+		CountryCode: record.Country.IsoCode,
+		Country:     record.Country.Names["en"],
+		City:        record.City.Names["en"],
+	}, nil
+}
+
+type noopLocator struct{}
+
+// NewNoopLocator will create new a domain.GeoLocator which never knows location, it is used when no database is configured
+func NewNoopLocator() domain.GeoLocator {
+	return noopLocator{}
+}
+
+func (noopLocator) Locate(ip string) (*domain.GeoLocation, error) {
+	return nil, nil
+}
@@ -9,8 +9,49 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-// JwtVerify will validate and parsing an incoming jwt token
+// JwtVerify will validate and parsing an incoming login token, a token restricted to a scope is rejected
 func JwtVerify(token string) (*domain.JWToken, error) {
+	parsedToken, err := JwtVerifyType(token, domain.TokenLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedToken.Scope != "" {
+		return nil, domain.ErrUnauthorized
+	}
+
+	return parsedToken, nil
+}
+
+// JwtVerifyScope will validate and parsing an incoming login token, it accepts full token and token restricted to scope
+func JwtVerifyScope(token string, scope string) (*domain.JWToken, error) {
+	parsedToken, err := JwtVerifyType(token, domain.TokenLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedToken.Scope != "" && parsedToken.Scope != scope {
+		return nil, domain.ErrUnauthorized
+	}
+
+	return parsedToken, nil
+}
+
+// JwtVerifyType will validate and parsing an incoming jwt token, a token issued for another operation than typ is rejected
+func JwtVerifyType(token string, typ string) (*domain.JWToken, error) {
+	parsedToken, err := parseJwt(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedToken.Type != typ {
+		return nil, domain.ErrUnauthorized
+	}
+
+	return parsedToken, nil
+}
+
+func parseJwt(token string) (*domain.JWToken, error) {
 	token = strings.TrimSpace(token)
 	if token == "" {
 		//Token is missing, returns with error code 403 Unauthorized
@@ -3,10 +3,16 @@ package middleware
 import (
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/internal/domain"
 )
 
+// CorrelationIDHeader is header of the correlation id of a request
+const CorrelationIDHeader = "X-Correlation-ID"
+
 // EchoMiddleware represent the data-struct for middleware
 type EchoMiddleware struct {
 	// another stuff , may be needed by middleware
@@ -25,6 +31,35 @@ func (m *EchoMiddleware) CORS(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// ClientInfo will put ip address and user agent of the client into request context
+func (m *EchoMiddleware) ClientInfo(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx := domain.WithClientInfo(req.Context(), domain.ClientInfo{
+			IPAddress: c.RealIP(),
+			UserAgent: req.UserAgent(),
+		})
+		c.SetRequest(req.WithContext(ctx))
+		return next(c)
+	}
+}
+
+// CorrelationID will put correlation id of X-Correlation-ID header into request context, a new one is created when
+// the request has none. It is returned in the response header and carried by the events of the request
+func (m *EchoMiddleware) CorrelationID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		id := req.Header.Get(CorrelationIDHeader)
+		if id == "" {
+			id = uuid.New().String()
+		}
+
+		c.Response().Header().Set(CorrelationIDHeader, id)
+		c.SetRequest(req.WithContext(domain.WithCorrelationID(req.Context(), id)))
+		return next(c)
+	}
+}
+
 // MiddlewareLogging for logging
 func (m *EchoMiddleware) MiddlewareLogging(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// ReauthenticatePath is the endpoint which issues a token with fresh auth_time
+const ReauthenticatePath = "/user/reauthenticate"
+
+// RequireRecentAuth will reject token whose auth_time is older than maxAge, or which has no auth_time.
+// The response tells client to re-authenticate, WWW-Authenticate header follows the OAuth step-up challenge.
+// An invalid or missing token is answered by the handler itself
+func RequireRecentAuth(maxAge time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			parsedToken, err := parseJwt(c.Request().Header.Get("x-access-token"))
+			if err != nil {
+				return next(c)
+			}
+
+			if parsedToken.AuthTime > 0 && time.Since(time.Unix(parsedToken.AuthTime, 0)) <= maxAge {
+				return next(c)
+			}
+
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate,
+				fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, int64(maxAge.Seconds())))
+
+			respData := map[string]interface{}{
+				"error":           "reauthentication_required",
+				"reauthenticate":  ReauthenticatePath,
+				"max_age_seconds": int64(maxAge.Seconds()),
+				"auth_time":       parsedToken.AuthTime,
+			}
+			return c.JSON(domain.GetStatusCode(domain.ErrReauthenticationRequired), domain.Response{Message: domain.ErrReauthenticationRequired.Error(), Data: respData})
+		}
+	}
+}
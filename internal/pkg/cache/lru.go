@@ -0,0 +1,165 @@
+// Package cache implements an in-process cache of bounded size whose entries expire after a TTL.
+// Entries are tagged, so every entry holding a record can be dropped when the record changes.
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// tagStripes is number of generations kept for tags, tags sharing a stripe share its generation
+const tagStripes = 256
+
+// LRU is a least recently used cache safe for concurrent use, entries older than its TTL are never returned
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+	tags    map[string]map[string]struct{}
+	now     func() time.Time
+
+	// generation grows on every invalidation, tagGenerations holds the generation of the last invalidation of tags
+	generation     uint64
+	tagGenerations [tagStripes]uint64
+}
+
+type entry struct {
+	key       string
+	value     interface{}
+	tags      []string
+	expiresAt time.Time
+}
+
+// NewLRU will create new a LRU which holds at most size entries for ttl
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		tags:    make(map[string]map[string]struct{}),
+		now:     time.Now,
+	}
+}
+
+// Get returns value of key, an expired entry is removed
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expiresAt) {
+		c.remove(el)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set stores value of key tagged by tags, the least recently used entry is evicted when the cache is full
+func (c *LRU) Set(key string, value interface{}, tags ...string) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, tags)
+}
+
+// SetLoaded stores value of key as Set does, unless a tag of tags has been invalidated after generation.
+// A tag may be taken for invalidated when it shares its stripe with an invalidated tag, value is not stored then
+func (c *LRU) SetLoaded(key string, value interface{}, generation uint64, tags ...string) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		if c.tagGenerations[stripe(tag)] > generation {
+			return
+		}
+	}
+	c.set(key, value, tags)
+}
+
+// Generation returns generation of the last invalidation
+func (c *LRU) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+func (c *LRU) set(key string, value interface{}, tags []string) {
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	el := c.order.PushFront(&entry{key: key, value: value, tags: tags, expiresAt: c.now().Add(c.ttl)})
+	c.entries[key] = el
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// Invalidate removes every entry tagged by tag
+func (c *LRU) Invalidate(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.tagGenerations[stripe(tag)] = c.generation
+	for key := range c.tags[tag] {
+		c.remove(c.entries[key])
+	}
+}
+
+// Len returns number of entries, expired entries which have not been removed yet are counted
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	c.order.Remove(el)
+	delete(c.entries, e.key)
+	for _, tag := range e.tags {
+		keys := c.tags[tag]
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// stripe returns index of the generation of tag
+func stripe(tag string) int {
+	h := fnv.New32a()
+	h.Write([]byte(tag))
+	return int(h.Sum32() % tagStripes)
+}
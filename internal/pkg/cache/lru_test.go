@@ -0,0 +1,66 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/pkg/cache"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("success evicts least recently used", func(t *testing.T) {
+		lru := cache.NewLRU(2, time.Minute)
+		lru.Set("a", 1)
+		lru.Set("b", 2)
+		lru.Get("a")
+		lru.Set("c", 3)
+
+		_, ok := lru.Get("b")
+		assert.False(t, ok)
+		value, ok := lru.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+		assert.Equal(t, 2, lru.Len())
+	})
+
+	t.Run("success expires entries", func(t *testing.T) {
+		lru := cache.NewLRU(2, time.Millisecond*10)
+		lru.Set("a", 1)
+		time.Sleep(time.Millisecond * 20)
+
+		_, ok := lru.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, lru.Len())
+	})
+
+	t.Run("success invalidates tag", func(t *testing.T) {
+		lru := cache.NewLRU(10, time.Minute)
+		lru.Set("a", 1, "x")
+		lru.Set("b", 2, "x", "y")
+		lru.Set("c", 3, "y")
+		lru.Invalidate("x")
+
+		_, ok := lru.Get("a")
+		assert.False(t, ok)
+		_, ok = lru.Get("b")
+		assert.False(t, ok)
+		_, ok = lru.Get("c")
+		assert.True(t, ok)
+	})
+
+	t.Run("success value loaded before invalidation is not stored", func(t *testing.T) {
+		lru := cache.NewLRU(10, time.Minute)
+		generation := lru.Generation()
+		lru.Invalidate("x")
+
+		lru.SetLoaded("a", 1, generation, "x")
+		_, ok := lru.Get("a")
+		assert.False(t, ok)
+
+		lru.SetLoaded("a", 1, lru.Generation(), "x")
+		_, ok = lru.Get("a")
+		assert.True(t, ok)
+	})
+}
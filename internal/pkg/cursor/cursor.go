@@ -0,0 +1,68 @@
+// Package cursor implements opaque pagination cursors: the position is JSON encoded and signed with HMAC-SHA256,
+// so clients can hand it back but can not forge or alter it.
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalid will throw if cursor is malformed or its signature does not match
+var ErrInvalid = errors.New("cursor: invalid cursor")
+
+// Codec encode and decode signed cursors
+type Codec struct {
+	key []byte
+}
+
+// NewCodec will create new a Codec signing cursors with key
+func NewCodec(key []byte) *Codec {
+	return &Codec{key: key}
+}
+
+// Encode returns signed cursor of position
+func (c *Codec) Encode(position interface{}) (string, error) {
+	payload, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies signature of cursor and decodes its position
+func (c *Codec) Decode(cursor string, position interface{}) error {
+	i := strings.IndexByte(cursor, '.')
+	if i < 0 {
+		return ErrInvalid
+	}
+
+	encoding := base64.RawURLEncoding
+	payload, err := encoding.DecodeString(cursor[:i])
+	if err != nil {
+		return ErrInvalid
+	}
+	signature, err := encoding.DecodeString(cursor[i+1:])
+	if err != nil {
+		return ErrInvalid
+	}
+	if !hmac.Equal(signature, c.sign(payload)) {
+		return ErrInvalid
+	}
+
+	if err := json.Unmarshal(payload, position); err != nil {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
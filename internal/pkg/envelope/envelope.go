@@ -0,0 +1,138 @@
+// Package envelope implements envelope encryption: every record is encrypted with its own
+// AES-256-GCM data key, and the data key is stored wrapped by a versioned master key.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeySize is the size of master keys and data keys, AES-256
+const KeySize = 32
+
+var (
+	// ErrUnknownKeyVersion will throw if data key is wrapped by a master key which is not in the keyring
+	ErrUnknownKeyVersion = errors.New("envelope: unknown master key version")
+	// ErrInvalidCiphertext will throw if ciphertext is too short or has been tampered
+	ErrInvalidCiphertext = errors.New("envelope: invalid ciphertext")
+)
+
+// Keyring holds versioned master keys and the key of blind indexes
+type Keyring struct {
+	masterKeys map[int][]byte
+	current    int
+	indexKey   []byte
+}
+
+// NewKeyring will create new a Keyring, new data keys are wrapped by master key of current version.
+// Index key must stay the same across master key rotations, otherwise blind indexes have to be rebuilt
+func NewKeyring(masterKeys map[int][]byte, current int, indexKey []byte) (*Keyring, error) {
+	for version, key := range masterKeys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("envelope: master key version %d must be %d bytes", version, KeySize)
+		}
+	}
+	if _, ok := masterKeys[current]; !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+	if len(indexKey) < KeySize {
+		return nil, fmt.Errorf("envelope: index key must be at least %d bytes", KeySize)
+	}
+
+	return &Keyring{
+		masterKeys: masterKeys,
+		current:    current,
+		indexKey:   indexKey,
+	}, nil
+}
+
+// CurrentVersion returns version of master key wrapping new data keys
+func (k *Keyring) CurrentVersion() int {
+	return k.current
+}
+
+// NewDataKey returns a random data key, and the same key wrapped by current master key
+func (k *Keyring) NewDataKey() (key []byte, wrapped []byte, err error) {
+	key = make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+
+	wrapped, err = Seal(k.masterKeys[k.current], key, wrapAdditionalData(k.current))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, wrapped, nil
+}
+
+// UnwrapDataKey returns data key wrapped by master key of the given version
+func (k *Keyring) UnwrapDataKey(wrapped []byte, version int) ([]byte, error) {
+	masterKey, ok := k.masterKeys[version]
+	if !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	return Open(masterKey, wrapped, wrapAdditionalData(version))
+}
+
+// BlindIndex returns keyed hash of value, equal values have equal index without revealing the value
+func (k *Keyring) BlindIndex(value []byte) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Seal encrypts plaintext with AES-GCM, random nonce is prepended to ciphertext.
+// Additional data is authenticated but not encrypted, the same one must be given to Open
+func Seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts ciphertext produced by Seal
+func Open(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func wrapAdditionalData(version int) []byte {
+	return []byte(fmt.Sprintf("data-key:v%d", version))
+}
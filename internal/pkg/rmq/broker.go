@@ -0,0 +1,314 @@
+package rmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+)
+
+// State is state of the connection of a Broker
+type State string
+
+const (
+	// StateConnecting is state of a broker which is dialing or restoring its queues, messages are buffered meanwhile
+	StateConnecting State = "connecting"
+	// StateConnected is state of a broker whose queues are usable
+	StateConnected State = "connected"
+	// StateClosed is state of a closed broker
+	StateClosed State = "closed"
+)
+
+const (
+	// minRedialDelay is delay of the first redial after the connection is lost
+	minRedialDelay = time.Second
+	// maxRedialDelay caps delay of the redials, the broker redials until it is closed
+	maxRedialDelay = time.Second * 30
+	// channelRecoveryDelay is delay of reopening a channel closed by the broker while the connection is up
+	channelRecoveryDelay = time.Second
+	// maxFlushAttempts bounds publishing of a buffered message which is nacked, returned or not confirmed
+	maxFlushAttempts = 3
+	// flushRetryDelay is delay of publishing again a buffered message which failed
+	flushRetryDelay = time.Second
+)
+
+var (
+	// ErrDisconnected is returned when publishing to a queue which is not buffered while the connection is down
+	ErrDisconnected = errors.New("connection to rabbitmq is down")
+	// ErrBufferFull is returned when publishing while the connection is down and the buffer is full
+	ErrBufferFull = errors.New("publish buffer is full")
+)
+
+// bufferedMessage is a message published while the connection was down
+type bufferedMessage struct {
+	queue      *queue
+	routingKey string
+	publishing amqp.Publishing
+	attempts   int
+}
+
+// Broker owns the connection to rabbitmq. It dials in background and redials when the connection is lost,
+// channels of its queues are reopened with their topology and consumers, so queues stay usable across reconnects.
+// Messages published to buffered queues while the connection is down wait in a bounded in-memory buffer
+// and are published once it is recovered. Buffering is lossy: the publisher is told a buffered message is sent,
+// it is lost when the process stops meanwhile or when the broker refuses it maxFlushAttempts times.
+// Messages which must not be lost are published to a queue which is not buffered from a durable store, as the outbox
+type Broker struct {
+	url        string
+	bufferSize int
+
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	state    State
+	queues   []*queue
+	buffer   []bufferedMessage
+	flushing bool
+	done     chan struct{}
+}
+
+// NewBroker will create new a Broker connecting to url, at most bufferSize messages are buffered while disconnected
+func NewBroker(url string, bufferSize int) *Broker {
+	b := &Broker{
+		url:        url,
+		bufferSize: bufferSize,
+		state:      StateConnecting,
+		done:       make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// State returns state of the connection
+func (b *Broker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Close closes the connection, buffered messages are dropped
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	if b.state == StateClosed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.setState(StateClosed)
+	close(b.done)
+
+	conn := b.conn
+	dropped := len(b.buffer)
+	b.conn = nil
+	b.buffer = nil
+	b.mu.Unlock()
+
+	if dropped > 0 {
+		logrus.Warnf("rabbitmq broker closed, %d buffered messages are dropped", dropped)
+	}
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+/**
+ * run keeps the connection up until the broker is closed. Pseudocode:
+ * - dial, retrying with a delay doubling up to maxRedialDelay
+ * - reopen channels of the queues and publish the buffered messages
+ * - wait for the connection to be lost and dial again
+ */
+func (b *Broker) run() {
+	delay := minRedialDelay
+	for {
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		logrus.Printf("Connecting to rabbitmq on %s", b.url)
+		conn, err := amqp.Dial(b.url)
+		if err != nil {
+			logError(fmt.Sprintf("Connection to rabbitmq failed. Retrying in %s", delay), err)
+			select {
+			case <-b.done:
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxRedialDelay {
+				delay = maxRedialDelay
+			}
+			continue
+		}
+		delay = minRedialDelay
+
+		closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		if !b.connected(conn) {
+			conn.Close()
+			return
+		}
+
+		select {
+		case <-b.done:
+			return
+		case err := <-closed:
+			b.disconnected(conn, err)
+		}
+	}
+}
+
+// connected restores the queues on conn and flushes the buffer, it is false when the broker has been closed
+func (b *Broker) connected(conn *amqp.Connection) bool {
+	b.mu.Lock()
+	if b.state == StateClosed {
+		b.mu.Unlock()
+		return false
+	}
+	b.conn = conn
+	queues := append([]*queue(nil), b.queues...)
+	b.mu.Unlock()
+
+	for _, q := range queues {
+		q.recover(conn)
+	}
+	b.flush()
+	return true
+}
+
+func (b *Broker) disconnected(conn *amqp.Connection, err *amqp.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn != conn {
+		return
+	}
+	logError("Connection to rabbitmq closed", err)
+	b.conn = nil
+	if b.state != StateClosed {
+		b.setState(StateConnecting)
+	}
+}
+
+// register adds q to the queues restored on reconnect, it is restored at once when the broker is connected
+func (b *Broker) register(q *queue) {
+	b.mu.Lock()
+	b.queues = append(b.queues, q)
+	conn := b.conn
+	b.mu.Unlock()
+
+	if conn != nil {
+		q.recover(conn)
+	}
+}
+
+// connection returns the current connection, it is nil while disconnected
+func (b *Broker) connection() *amqp.Connection {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.conn
+}
+
+// enqueue buffers a message published while the connection is down, it is published at most maxFlushAttempts times
+// once the connection is recovered and dropped afterward, see flush
+func (b *Broker) enqueue(q *queue, routingKey string, publishing amqp.Publishing) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.state == StateClosed:
+		return ErrDisconnected
+	case len(b.buffer) >= b.bufferSize:
+		return ErrBufferFull
+	}
+	b.buffer = append(b.buffer, bufferedMessage{queue: q, routingKey: routingKey, publishing: publishing})
+	return nil
+}
+
+/**
+ * flush publishes the buffered messages in order. Pseudocode:
+ * - stop when the connection is down or another flush is running
+ * - publish the oldest message, a message whose channel is closed is put back until the channel is reopened
+ * - a message which is nacked, returned or not confirmed is put back and published again after flushRetryDelay
+ * - it is dropped and logged with its body after maxFlushAttempts, its publisher has already been told it is sent
+ * - the broker is connected once the buffer is empty, messages published meanwhile are buffered to keep the order
+ */
+func (b *Broker) flush() {
+	b.mu.Lock()
+	if b.flushing || b.conn == nil {
+		b.mu.Unlock()
+		return
+	}
+	b.flushing = true
+	b.mu.Unlock()
+
+	for {
+		b.mu.Lock()
+		if b.conn == nil || len(b.buffer) == 0 {
+			if b.conn != nil {
+				b.setState(StateConnected)
+			}
+			b.flushing = false
+			b.mu.Unlock()
+			return
+		}
+		message := b.buffer[0]
+		b.buffer = b.buffer[1:]
+		b.mu.Unlock()
+
+		err := message.queue.publish(message.routingKey, message.publishing)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, ErrChannelClosed):
+			b.putBack(message)
+			b.mu.Lock()
+			b.flushing = false
+			b.mu.Unlock()
+			return
+		}
+
+		message.attempts++
+		if message.attempts >= maxFlushAttempts {
+			logrus.WithFields(logrus.Fields{
+				"at":          time.Now().Format("2006-01-02 15:04:05"),
+				"routing_key": message.routingKey,
+				"message_id":  message.publishing.MessageId,
+				"body":        string(message.publishing.Body),
+			}).Errorf("Publishing buffered message failed %d times, it is dropped: %s", message.attempts, err)
+			continue
+		}
+
+		logError("Publishing buffered message failed, it is published again", err)
+		b.putBack(message)
+		select {
+		case <-b.done:
+			return
+		case <-time.After(flushRetryDelay):
+		}
+	}
+}
+
+// putBack puts message back at the head of the buffer, unless the broker has been closed
+func (b *Broker) putBack(message bufferedMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateClosed {
+		b.buffer = append([]bufferedMessage{message}, b.buffer...)
+	}
+}
+
+// setState changes state of the broker, b.mu must be held
+func (b *Broker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	logrus.WithFields(logrus.Fields{
+		"at": time.Now().Format("2006-01-02 15:04:05"),
+	}).Printf("rabbitmq connection is %s", state)
+}
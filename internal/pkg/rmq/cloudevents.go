@@ -0,0 +1,373 @@
+package rmq
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Format is how messages published to a queue are encoded
+type Format string
+
+const (
+	// FormatPlain publishes message as the body, headers as they are given
+	FormatPlain Format = "plain"
+	// FormatStructured publishes CloudEvents structured content mode messages, the event with its attributes is the JSON body
+	FormatStructured Format = "cloudevents-structured"
+	// FormatBinary publishes CloudEvents binary content mode messages, attributes are ce- headers and data is the body
+	FormatBinary Format = "cloudevents-binary"
+)
+
+const (
+	// CloudEventsSpecVersion is version of the CloudEvents specification of published events
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is content type of structured content mode messages
+	CloudEventsContentType = "application/cloudevents+json"
+	// CloudEventsHeaderPrefix prefixes headers of CloudEvents attributes, publish headers with the prefix are attributes of the event
+	CloudEventsHeaderPrefix = "ce-"
+
+	defaultDataContentType = "application/json"
+)
+
+var (
+	// ErrNotCloudEvent is returned when decoding a message which is not a CloudEvent
+	ErrNotCloudEvent = errors.New("message is not a cloudevent")
+
+	// cloudEventAttributes are the attributes of the specification, any other attribute is an extension
+	cloudEventAttributes = map[string]bool{
+		"specversion": true, "id": true, "source": true, "type": true,
+		"datacontenttype": true, "dataschema": true, "subject": true, "time": true,
+		"data": true, "data_base64": true,
+	}
+)
+
+// ParseFormat returns format of value, an empty value is FormatPlain
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return FormatPlain, nil
+	case FormatPlain, FormatStructured, FormatBinary:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown message format %q", value)
+	}
+}
+
+// CloudEvent is an event of the CloudEvents 1.0 specification. Extensions are kept in their canonical string form,
+// Data is the encoded data of DataContentType
+type CloudEvent struct {
+	SpecVersion     string
+	ID              string
+	Source          string
+	Type            string
+	DataContentType string
+	DataSchema      string
+	Subject         string
+	Time            time.Time
+	Extensions      map[string]string
+	Data            []byte
+}
+
+// Validate checks the event has the required attributes and valid extension names
+func (e CloudEvent) Validate() error {
+	if e.SpecVersion != CloudEventsSpecVersion {
+		return fmt.Errorf("unsupported cloudevents specversion %q", e.SpecVersion)
+	}
+	switch {
+	case e.ID == "":
+		return errors.New("cloudevent attribute id is required")
+	case e.Source == "":
+		return errors.New("cloudevent attribute source is required")
+	case e.Type == "":
+		return errors.New("cloudevent attribute type is required")
+	}
+	for name := range e.Extensions {
+		if !validAttributeName(name) || cloudEventAttributes[name] {
+			return fmt.Errorf("invalid cloudevent extension name %q", name)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the event in the JSON event format. JSON data is embedded, any other data is base64 encoded
+func (e CloudEvent) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{}, len(e.Extensions)+8)
+	for name, value := range e.Extensions {
+		object[name] = value
+	}
+	object["specversion"] = e.SpecVersion
+	object["id"] = e.ID
+	object["source"] = e.Source
+	object["type"] = e.Type
+	setOptional(object, "datacontenttype", e.DataContentType)
+	setOptional(object, "dataschema", e.DataSchema)
+	setOptional(object, "subject", e.Subject)
+	if !e.Time.IsZero() {
+		object["time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	if e.Data != nil {
+		if isJSONContentType(e.DataContentType) {
+			if !json.Valid(e.Data) {
+				return nil, fmt.Errorf("data of cloudevent %s is not valid JSON", e.ID)
+			}
+			object["data"] = json.RawMessage(e.Data)
+		} else {
+			object["data_base64"] = base64.StdEncoding.EncodeToString(e.Data)
+		}
+	}
+
+	return json.Marshal(object)
+}
+
+// UnmarshalJSON decodes an event of the JSON event format
+func (e *CloudEvent) UnmarshalJSON(b []byte) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(b, &object); err != nil {
+		return err
+	}
+
+	event := CloudEvent{}
+	var data json.RawMessage
+	for name, raw := range object {
+		switch name {
+		case "data":
+			data = raw
+			continue
+		case "data_base64":
+			var encoded string
+			if err := json.Unmarshal(raw, &encoded); err != nil {
+				return fmt.Errorf("cloudevent attribute data_base64: %w", err)
+			}
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return fmt.Errorf("cloudevent attribute data_base64: %w", err)
+			}
+			event.Data = decoded
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if err := event.setAttribute(name, canonicalString(value)); err != nil {
+			return err
+		}
+	}
+
+	// data of a non JSON content type is a JSON string
+	if data != nil {
+		event.Data = []byte(data)
+		if !isJSONContentType(event.DataContentType) {
+			var text string
+			if err := json.Unmarshal(data, &text); err != nil {
+				return fmt.Errorf("cloudevent attribute data: %w", err)
+			}
+			event.Data = []byte(text)
+		}
+	}
+
+	*e = event
+	return nil
+}
+
+func (e *CloudEvent) setAttribute(name string, value string) error {
+	switch name {
+	case "specversion":
+		e.SpecVersion = value
+	case "id":
+		e.ID = value
+	case "source":
+		e.Source = value
+	case "type":
+		e.Type = value
+	case "datacontenttype":
+		e.DataContentType = value
+	case "dataschema":
+		e.DataSchema = value
+	case "subject":
+		e.Subject = value
+	case "time":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("cloudevent attribute time: %w", err)
+		}
+		e.Time = t
+	default:
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]string)
+		}
+		e.Extensions[name] = value
+	}
+	return nil
+}
+
+// EncodeCloudEvent binds event to an AMQP message of format, headers which are not attributes are kept
+func EncodeCloudEvent(event CloudEvent, format Format, headers map[string]interface{}) (amqp.Publishing, error) {
+	if err := event.Validate(); err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	table := make(amqp.Table, len(headers))
+	for name, value := range headers {
+		if !strings.HasPrefix(strings.ToLower(name), CloudEventsHeaderPrefix) {
+			table[name] = value
+		}
+	}
+
+	publishing := amqp.Publishing{
+		MessageId: event.ID,
+		Timestamp: event.Time,
+		Headers:   table,
+	}
+
+	switch format {
+	case FormatStructured:
+		body, err := json.Marshal(event)
+		if err != nil {
+			return amqp.Publishing{}, err
+		}
+		publishing.ContentType = CloudEventsContentType
+		publishing.Body = body
+	case FormatBinary:
+		attributes := map[string]string{
+			"specversion": event.SpecVersion,
+			"id":          event.ID,
+			"source":      event.Source,
+			"type":        event.Type,
+			"dataschema":  event.DataSchema,
+			"subject":     event.Subject,
+		}
+		if !event.Time.IsZero() {
+			attributes["time"] = event.Time.UTC().Format(time.RFC3339Nano)
+		}
+		for name, value := range event.Extensions {
+			attributes[name] = value
+		}
+		for name, value := range attributes {
+			if value != "" {
+				table[CloudEventsHeaderPrefix+name] = value
+			}
+		}
+		// datacontenttype of the binary mode is content type of the message
+		publishing.ContentType = event.DataContentType
+		publishing.Body = event.Data
+	default:
+		return amqp.Publishing{}, fmt.Errorf("format %q is not a cloudevents format", format)
+	}
+
+	return publishing, nil
+}
+
+// DecodeCloudEvent decodes the CloudEvent of a delivery in either content mode,
+// ErrNotCloudEvent is returned for messages which are not CloudEvents
+func DecodeCloudEvent(delivery amqp.Delivery) (CloudEvent, error) {
+	mediaType, _, _ := mime.ParseMediaType(delivery.ContentType)
+	if mediaType == CloudEventsContentType {
+		var event CloudEvent
+		if err := json.Unmarshal(delivery.Body, &event); err != nil {
+			return CloudEvent{}, err
+		}
+		return event, event.Validate()
+	}
+
+	if _, ok := delivery.Headers[CloudEventsHeaderPrefix+"specversion"]; !ok {
+		return CloudEvent{}, ErrNotCloudEvent
+	}
+
+	event := CloudEvent{DataContentType: delivery.ContentType, Data: delivery.Body}
+	for name, value := range delivery.Headers {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, CloudEventsHeaderPrefix) {
+			continue
+		}
+		if err := event.setAttribute(strings.TrimPrefix(name, CloudEventsHeaderPrefix), canonicalString(value)); err != nil {
+			return CloudEvent{}, err
+		}
+	}
+	return event, event.Validate()
+}
+
+// NewCloudEvent builds the event a queue of a CloudEvents format publishes as message, attributes are taken from ce- headers.
+// Missing id, type and time default to the MessageIDHeader header or a new UUID, routingKey and now, a source is required
+func NewCloudEvent(message string, routingKey string, headers map[string]interface{}) (CloudEvent, error) {
+	event := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		Type:            routingKey,
+		DataContentType: defaultDataContentType,
+		Data:            []byte(message),
+	}
+	for name, value := range headers {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, CloudEventsHeaderPrefix) {
+			continue
+		}
+		if err := event.setAttribute(strings.TrimPrefix(name, CloudEventsHeaderPrefix), canonicalString(value)); err != nil {
+			return CloudEvent{}, err
+		}
+	}
+
+	if event.ID == "" {
+		event.ID = messageID(headers)
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	return event, nil
+}
+
+func setOptional(object map[string]interface{}, name string, value string) {
+	if value != "" {
+		object[name] = value
+	}
+}
+
+// canonicalString returns the string form of an attribute value of a header or a JSON event
+func canonicalString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprint(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// validAttributeName reports whether name consists of lower-case letters and digits as the specification requires
+func validAttributeName(name string) bool {
+	if name == "" || len(name) > 20 {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
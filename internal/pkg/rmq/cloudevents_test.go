@@ -0,0 +1,162 @@
+package rmq_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/rmq"
+)
+
+const sampleUUID = "7b1c4a52-5c1e-4a53-9a8e-0c52d3f0a111"
+
+// deliveryOf returns the delivery a consumer receives of publishing
+func deliveryOf(publishing amqp.Publishing) amqp.Delivery {
+	return amqp.Delivery{
+		Headers:     publishing.Headers,
+		ContentType: publishing.ContentType,
+		MessageId:   publishing.MessageId,
+		Timestamp:   publishing.Timestamp,
+		Body:        publishing.Body,
+	}
+}
+
+func TestCloudEvents(t *testing.T) {
+	occurredAt := time.Date(2026, 10, 19, 8, 0, 0, 123000000, time.UTC)
+	headers := map[string]interface{}{
+		domain.MessageIDHeader: sampleUUID,
+		"ce-id":                sampleUUID,
+		"ce-source":            "/user",
+		"ce-time":              occurredAt.Format(time.RFC3339Nano),
+		"ce-datacontenttype":   "application/json",
+		"ce-eventversion":      "1",
+		"ce-correlationid":     "correlation-1",
+	}
+	data := `{"email_destination":"quoted\"user@mail.com"}`
+
+	for _, format := range []rmq.Format{rmq.FormatStructured, rmq.FormatBinary} {
+		format := format
+
+		t.Run(string(format)+" round trip", func(t *testing.T) {
+			event, err := rmq.NewCloudEvent(data, domain.EventUserRegistered, headers)
+			require.NoError(t, err)
+
+			publishing, err := rmq.EncodeCloudEvent(event, format, headers)
+			require.NoError(t, err)
+			assert.Equal(t, sampleUUID, publishing.MessageId)
+			assert.Equal(t, sampleUUID, publishing.Headers[domain.MessageIDHeader])
+
+			decoded, err := rmq.DecodeCloudEvent(deliveryOf(publishing))
+			require.NoError(t, err)
+			assert.Equal(t, rmq.CloudEventsSpecVersion, decoded.SpecVersion)
+			assert.Equal(t, sampleUUID, decoded.ID)
+			assert.Equal(t, "/user", decoded.Source)
+			assert.Equal(t, domain.EventUserRegistered, decoded.Type)
+			assert.Equal(t, "application/json", decoded.DataContentType)
+			assert.True(t, occurredAt.Equal(decoded.Time))
+			assert.Equal(t, map[string]string{"eventversion": "1", "correlationid": "correlation-1"}, decoded.Extensions)
+			assert.JSONEq(t, data, string(decoded.Data))
+		})
+	}
+
+	t.Run("structured mode message", func(t *testing.T) {
+		event, err := rmq.NewCloudEvent(data, domain.EventUserRegistered, headers)
+		require.NoError(t, err)
+		publishing, err := rmq.EncodeCloudEvent(event, rmq.FormatStructured, headers)
+		require.NoError(t, err)
+
+		assert.Equal(t, rmq.CloudEventsContentType, publishing.ContentType)
+		assert.Equal(t, amqp.Table{domain.MessageIDHeader: sampleUUID}, publishing.Headers)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(publishing.Body, &body))
+		assert.Equal(t, "1.0", body["specversion"])
+		assert.Equal(t, domain.EventUserRegistered, body["type"])
+		assert.Equal(t, "correlation-1", body["correlationid"])
+		assert.Equal(t, map[string]interface{}{"email_destination": `quoted"user@mail.com`}, body["data"])
+	})
+
+	t.Run("binary mode message", func(t *testing.T) {
+		event, err := rmq.NewCloudEvent(data, domain.EventUserRegistered, headers)
+		require.NoError(t, err)
+		publishing, err := rmq.EncodeCloudEvent(event, rmq.FormatBinary, headers)
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json", publishing.ContentType)
+		assert.Equal(t, data, string(publishing.Body))
+		assert.Equal(t, "1.0", publishing.Headers["ce-specversion"])
+		assert.Equal(t, domain.EventUserRegistered, publishing.Headers["ce-type"])
+		assert.Equal(t, "1", publishing.Headers["ce-eventversion"])
+		assert.NotContains(t, publishing.Headers, "ce-datacontenttype")
+	})
+
+	t.Run("success binary data in structured mode", func(t *testing.T) {
+		event := rmq.CloudEvent{SpecVersion: "1.0", ID: "1", Source: "/user", Type: "user.avatar", DataContentType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}
+		publishing, err := rmq.EncodeCloudEvent(event, rmq.FormatStructured, nil)
+		require.NoError(t, err)
+		assert.Contains(t, string(publishing.Body), `"data_base64":"iVBORw=="`)
+
+		decoded, err := rmq.DecodeCloudEvent(deliveryOf(publishing))
+		require.NoError(t, err)
+		assert.Equal(t, event.Data, decoded.Data)
+	})
+
+	t.Run("success defaults of missing attributes", func(t *testing.T) {
+		event, err := rmq.NewCloudEvent(data, domain.EventUserRegistered, map[string]interface{}{"ce-source": "/user"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, event.ID)
+		assert.Equal(t, domain.EventUserRegistered, event.Type)
+		assert.False(t, event.Time.IsZero())
+		assert.NoError(t, event.Validate())
+	})
+
+	t.Run("success message id header of the outbox", func(t *testing.T) {
+		assert.Equal(t, domain.MessageIDHeader, rmq.MessageIDHeader)
+
+		event, err := rmq.NewCloudEvent(data, domain.EventUserRegistered, map[string]interface{}{domain.MessageIDHeader: sampleUUID})
+		require.NoError(t, err)
+		assert.Equal(t, sampleUUID, event.ID)
+	})
+
+	t.Run("error invalid events", func(t *testing.T) {
+		event, err := rmq.NewCloudEvent(data, domain.EventUserRegistered, nil)
+		require.NoError(t, err)
+		_, err = rmq.EncodeCloudEvent(event, rmq.FormatBinary, nil)
+		assert.EqualError(t, err, "cloudevent attribute source is required")
+
+		event.Source = "/user"
+		event.Extensions = map[string]string{"correlation_id": "1"}
+		_, err = rmq.EncodeCloudEvent(event, rmq.FormatBinary, nil)
+		assert.Error(t, err)
+
+		event.Extensions = nil
+		_, err = rmq.EncodeCloudEvent(event, rmq.FormatPlain, nil)
+		assert.Error(t, err)
+
+		_, err = rmq.NewCloudEvent(data, domain.EventUserRegistered, map[string]interface{}{"ce-time": "yesterday"})
+		assert.Error(t, err)
+	})
+
+	t.Run("error decode plain message", func(t *testing.T) {
+		_, err := rmq.DecodeCloudEvent(amqp.Delivery{ContentType: "application/json", Body: []byte(data)})
+		assert.Equal(t, rmq.ErrNotCloudEvent, err)
+	})
+
+	t.Run("parse format", func(t *testing.T) {
+		format, err := rmq.ParseFormat("")
+		require.NoError(t, err)
+		assert.Equal(t, rmq.FormatPlain, format)
+
+		format, err = rmq.ParseFormat(" CloudEvents-Binary ")
+		require.NoError(t, err)
+		assert.Equal(t, rmq.FormatBinary, format)
+
+		_, err = rmq.ParseFormat("avro")
+		assert.Error(t, err)
+	})
+}
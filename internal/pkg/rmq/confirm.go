@@ -0,0 +1,207 @@
+package rmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrUnroutable is returned when the exchange has no queue bound to the routing key of a mandatory message
+	ErrUnroutable = errors.New("message is unroutable")
+	// ErrNacked is returned when the broker could not take responsibility of a message
+	ErrNacked = errors.New("message is nacked by the broker")
+	// ErrConfirmTimeout is returned when the broker does not confirm a message in time, the message may still be delivered
+	ErrConfirmTimeout = errors.New("message is not confirmed in time")
+	// ErrChannelClosed is returned when the channel is closed before a message is confirmed
+	ErrChannelClosed = errors.New("channel is closed")
+)
+
+// PublishError is returned when a message is not confirmed to be routed to a queue, Err is one of the errors above
+// or the error of the channel. ReplyCode and ReplyText are given by the broker for a returned message
+type PublishError struct {
+	Exchange   string
+	RoutingKey string
+	MessageID  string
+	ReplyCode  uint16
+	ReplyText  string
+	Err        error
+}
+
+func (e *PublishError) Error() string {
+	message := fmt.Sprintf("publish message %s to exchange %s with routing key %s: %s", e.MessageID, e.Exchange, e.RoutingKey, e.Err)
+	if e.ReplyText != "" {
+		message = fmt.Sprintf("%s: %d %s", message, e.ReplyCode, e.ReplyText)
+	}
+	return message
+}
+
+// Unwrap returns the cause of the error, so errors.Is(err, ErrUnroutable) is true of unroutable messages
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
+
+// Confirmer matches confirmations and returns of a confirm mode channel to the published messages waiting for them.
+// Confirmations are matched by delivery tag, returns are matched by message id
+type Confirmer struct {
+	mu      sync.Mutex
+	nextTag uint64
+	closed  bool
+	pending map[uint64]*Pending
+	ids     map[string]*Pending
+}
+
+// Pending is a published message waiting for its confirmation
+type Pending struct {
+	confirmer *Confirmer
+	tag       uint64
+	messageID string
+	returned  *amqp.Return
+	done      chan error
+}
+
+// NewConfirmer will create new a Confirmer of the confirmations and returns of a channel. They must be unbuffered,
+// so a return is always received before the confirmation of its message
+func NewConfirmer(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) *Confirmer {
+	c := &Confirmer{
+		nextTag: 1,
+		pending: make(map[uint64]*Pending),
+		ids:     make(map[string]*Pending),
+	}
+	go c.run(confirms, returns)
+	return c
+}
+
+// Expect reserves delivery tag of the next message published on the channel. Messages must be published
+// in the order they are expected, Cancel is called when the publish fails
+func (c *Confirmer) Expect(messageID string) *Pending {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := &Pending{confirmer: c, tag: c.nextTag, messageID: messageID, done: make(chan error, 1)}
+	c.nextTag++
+	if c.closed {
+		p.done <- ErrChannelClosed
+		return p
+	}
+
+	c.pending[p.tag] = p
+	if messageID != "" {
+		c.ids[messageID] = p
+	}
+	return p
+}
+
+// Cancel releases delivery tag of a message which could not be published, it must be the last expected message
+func (p *Pending) Cancel() {
+	c := p.confirmer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p.tag == c.nextTag-1 {
+		c.nextTag--
+	}
+	c.forget(p)
+}
+
+// Wait returns nil when the message is acked and was not returned, otherwise an error of ErrUnroutable, ErrNacked,
+// ErrConfirmTimeout after timeout, or ErrChannelClosed
+func (p *Pending) Wait(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-p.done:
+		return err
+	case <-timer.C:
+		p.confirmer.mu.Lock()
+		p.confirmer.forget(p)
+		p.confirmer.mu.Unlock()
+
+		// the confirmation may have come meanwhile
+		select {
+		case err := <-p.done:
+			return err
+		default:
+			return ErrConfirmTimeout
+		}
+	}
+}
+
+// Returned returns the return of the message by the broker, it is nil unless the message is unroutable
+func (p *Pending) Returned() *amqp.Return {
+	p.confirmer.mu.Lock()
+	defer p.confirmer.mu.Unlock()
+
+	return p.returned
+}
+
+func (c *Confirmer) run(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			c.returned(ret)
+		case confirmation, ok := <-confirms:
+			if !ok {
+				c.close()
+				return
+			}
+			c.confirm(confirmation)
+		}
+	}
+}
+
+func (c *Confirmer) returned(ret amqp.Return) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.ids[ret.MessageId]; ok {
+		p.returned = &ret
+	}
+}
+
+func (c *Confirmer) confirm(confirmation amqp.Confirmation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[confirmation.DeliveryTag]
+	if !ok {
+		// the message has timed out
+		return
+	}
+	c.forget(p)
+
+	switch {
+	case p.returned != nil:
+		p.done <- ErrUnroutable
+	case !confirmation.Ack:
+		p.done <- ErrNacked
+	default:
+		p.done <- nil
+	}
+}
+
+func (c *Confirmer) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for _, p := range c.pending {
+		c.forget(p)
+		p.done <- ErrChannelClosed
+	}
+}
+
+func (c *Confirmer) forget(p *Pending) {
+	delete(c.pending, p.tag)
+	if c.ids[p.messageID] == p {
+		delete(c.ids, p.messageID)
+	}
+}
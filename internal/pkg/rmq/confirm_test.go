@@ -0,0 +1,79 @@
+package rmq_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/pkg/rmq"
+)
+
+func TestConfirmer(t *testing.T) {
+	confirms := make(chan amqp.Confirmation)
+	returns := make(chan amqp.Return)
+	confirmer := rmq.NewConfirmer(confirms, returns)
+
+	t.Run("success acked message", func(t *testing.T) {
+		pending := confirmer.Expect("1")
+		confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+
+		assert.NoError(t, pending.Wait(time.Second))
+		assert.Nil(t, pending.Returned())
+	})
+
+	t.Run("error nacked message", func(t *testing.T) {
+		pending := confirmer.Expect("2")
+		confirms <- amqp.Confirmation{DeliveryTag: 2, Ack: false}
+
+		assert.Equal(t, rmq.ErrNacked, pending.Wait(time.Second))
+	})
+
+	t.Run("error returned message", func(t *testing.T) {
+		pending := confirmer.Expect("3")
+		returns <- amqp.Return{MessageId: "3", ReplyCode: 312, ReplyText: "NO_ROUTE"}
+		confirms <- amqp.Confirmation{DeliveryTag: 3, Ack: true}
+
+		assert.Equal(t, rmq.ErrUnroutable, pending.Wait(time.Second))
+		if assert.NotNil(t, pending.Returned()) {
+			assert.Equal(t, uint16(312), pending.Returned().ReplyCode)
+		}
+	})
+
+	t.Run("success canceled publish releases its delivery tag", func(t *testing.T) {
+		confirmer.Expect("failed").Cancel()
+
+		pending := confirmer.Expect("4")
+		confirms <- amqp.Confirmation{DeliveryTag: 4, Ack: true}
+		assert.NoError(t, pending.Wait(time.Second))
+	})
+
+	t.Run("error timeout ignores late confirmation", func(t *testing.T) {
+		late := confirmer.Expect("5")
+		assert.Equal(t, rmq.ErrConfirmTimeout, late.Wait(time.Millisecond*10))
+
+		pending := confirmer.Expect("6")
+		confirms <- amqp.Confirmation{DeliveryTag: 5, Ack: true}
+		confirms <- amqp.Confirmation{DeliveryTag: 6, Ack: false}
+		assert.Equal(t, rmq.ErrNacked, pending.Wait(time.Second))
+	})
+
+	t.Run("error channel closed", func(t *testing.T) {
+		pending := confirmer.Expect("7")
+		close(confirms)
+
+		assert.Equal(t, rmq.ErrChannelClosed, pending.Wait(time.Second))
+		assert.Equal(t, rmq.ErrChannelClosed, confirmer.Expect("8").Wait(time.Second))
+	})
+
+	t.Run("publish error is typed", func(t *testing.T) {
+		var err error = &rmq.PublishError{Exchange: "events", RoutingKey: "user.register", MessageID: "3", ReplyCode: 312, ReplyText: "NO_ROUTE", Err: rmq.ErrUnroutable}
+
+		var publishErr *rmq.PublishError
+		assert.True(t, errors.As(err, &publishErr))
+		assert.True(t, errors.Is(err, rmq.ErrUnroutable))
+		assert.EqualError(t, err, "publish message 3 to exchange events with routing key user.register: message is unroutable: 312 NO_ROUTE")
+	})
+}
@@ -1,9 +1,13 @@
 package rmq
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 )
 
@@ -15,48 +19,161 @@ type Queue interface {
 	Consume(consumer MsgCons)
 	Publish(message string, routingKey string, header map[string]interface{}) error
 	GetQueueName() string
+	Format() Format
+	State() State
 }
 
-// queue collection
+// queue collection, its channel is reopened by the broker when the connection is recovered
+// MessageIDHeader is header of a message carrying its id, the id is also the message id of the publishing
+// so consumers may drop a message published again by either
+const MessageIDHeader = "message_id"
+
 type queue struct {
 	name           string
+	brokerName     string
+	broadcast      bool
 	exchange       Exchange
 	routingKey     []string
-	connection     *amqp.Connection
-	channel        *amqp.Channel
+	format         Format
+	broker         *Broker
+	isForConsume   bool
+	isForPublish   bool
+	isBuffered     bool
 	consumerThread uint16
+	confirmTimeout time.Duration
+
+	mu        sync.RWMutex
+	channel   *amqp.Channel
+	confirmer *Confirmer
+	consumers []MsgCons
+	publishMu sync.Mutex
 }
 
-// NewQueue will create new an queue object
+// NewQueue will create new an queue object of broker, messages are published in format.
+// A queue for publish waits confirmTimeout for the broker to confirm each message. Messages published to
+// a buffered queue while the connection is down are buffered by the broker, otherwise publishing fails.
+// Buffered messages may be lost, see Broker, a queue fed by the outbox is not buffered
 func NewQueue(
 	qName string,
-	conn *amqp.Connection,
+	broker *Broker,
 	exchange Exchange,
 	routingKey []string,
 	isForConsume bool,
 	isForPublish bool,
+	isBuffered bool,
+	format Format,
+	confirmTimeout time.Duration,
 ) Queue {
 	q := new(queue)
 
 	q.name = qName
-	q.connection = conn
+	q.brokerName = qName
+	q.broker = broker
 	q.exchange = exchange
 	q.routingKey = routingKey
-	q.channel = q.openChannel()
+	q.isForConsume = isForConsume
+	q.isForPublish = isForPublish
+	q.isBuffered = isBuffered
+	q.format = format
+	q.confirmTimeout = confirmTimeout
+
+	broker.register(q)
+	return q
+}
 
-	if isForConsume {
+// NewBroadcastQueue will create new an queue object which receives every message of routingKey.
+// Its broker queue is exclusive to the connection and named by the broker, so each replica has its own.
+// It is declared again on reconnect, messages published meanwhile are not received.
+// It is buffered, so it only suits messages which may be lost, as cache invalidations, see Broker
+func NewBroadcastQueue(qName string, broker *Broker, exchange Exchange, routingKey []string, confirmTimeout time.Duration) Queue {
+	q := new(queue)
+
+	q.name = qName
+	q.broadcast = true
+	q.broker = broker
+	q.exchange = exchange
+	q.routingKey = routingKey
+	q.isForConsume = true
+	q.isForPublish = true
+	q.isBuffered = true
+	q.format = FormatPlain
+	q.confirmTimeout = confirmTimeout
+
+	broker.register(q)
+	return q
+}
+
+/**
+ * recover opens a channel of conn and restores the queue on it. Pseudocode:
+ * - declare exchange, queues and bindings of a queue for consume
+ * - put the channel in confirm mode for a queue for publish
+ * - register the consumers again, and reopen the channel when the broker closes it
+ */
+func (q *queue) recover(conn *amqp.Connection) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	channel, err := conn.Channel()
+	logError("Opening channel failed", err)
+	if err != nil {
+		return
+	}
+	closed := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	q.channel = channel
+	q.confirmer = nil
+	if q.isForConsume {
 		q.declareExchange()
 		q.declareQueue()
 		q.bindQueue()
 	}
+	if q.isForPublish {
+		q.confirmMode()
+	}
+	for _, consumer := range q.consumers {
+		deliveries, err := q.registerQueueConsumer()
+		q.executeRabbitMessageConsumer(err, consumer, deliveries)
+	}
 
-	return q
+	go q.watch(channel, closed)
 }
 
-func (q *queue) openChannel() *amqp.Channel {
-	channel, err := q.connection.Channel()
-	logError("Opening channel failed", err)
-	return channel
+// watch reopens channel when the broker closes it while the connection is up, channels of a lost connection
+// are reopened by the broker once it reconnects
+func (q *queue) watch(channel *amqp.Channel, closed chan *amqp.Error) {
+	err := <-closed
+	if err == nil {
+		return
+	}
+	logError(fmt.Sprintf("Queue: %s. Channel closed", q.name), err)
+
+	time.Sleep(channelRecoveryDelay)
+	conn := q.broker.connection()
+	if conn == nil || conn.IsClosed() {
+		return
+	}
+
+	q.mu.RLock()
+	current := q.channel == channel
+	q.mu.RUnlock()
+	if current {
+		q.recover(conn)
+		q.broker.flush()
+	}
+}
+
+// confirmMode puts the channel in confirm mode, so Publish knows whether the broker took responsibility of a message
+func (q *queue) confirmMode() {
+	err := q.channel.Confirm(false)
+	logError("Enabling publisher confirms failed", err)
+	if err != nil {
+		return
+	}
+
+	q.confirmer = NewConfirmer(
+		q.channel.NotifyPublish(make(chan amqp.Confirmation)),
+		q.channel.NotifyReturn(make(chan amqp.Return)),
+	)
 }
 
 func (q *queue) declareExchange() {
@@ -73,6 +190,20 @@ func (q *queue) declareExchange() {
 }
 
 func (q *queue) declareQueue() {
+	if q.broadcast {
+		declared, err := q.channel.QueueDeclare(
+			"",    // name
+			false, // durable
+			true,  // delete when unused
+			true,  // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		logError("Queue declaration failed", err)
+		q.brokerName = declared.Name
+		return
+	}
+
 	queueDlxName := q.name + ".dlx"
 
 	arguments := make(amqp.Table)
@@ -103,7 +234,7 @@ func (q *queue) declareQueue() {
 func (q *queue) bindQueue() {
 	for _, r := range q.routingKey {
 		err := q.channel.QueueBind(
-			q.name,             // queue name
+			q.brokerName,       // queue name
 			r,                  // routing key
 			q.exchange.ExcName, // exchange
 			false,
@@ -114,13 +245,13 @@ func (q *queue) bindQueue() {
 
 func (q *queue) registerQueueConsumer() (<-chan amqp.Delivery, error) {
 	msgs, err := q.channel.Consume(
-		q.name, // queue
-		"",     // consumer
-		false,  // auto ack
-		false,  // exclusive
-		false,  // no local
-		false,  // no wait
-		nil,    // args
+		q.brokerName, // queue
+		"",           // consumer
+		false,        // auto ack
+		false,        // exclusive
+		false,        // no local
+		false,        // no wait
+		nil,          // args
 	)
 	logError("Consuming messages from queue failed", err)
 	return msgs, err
@@ -137,31 +268,157 @@ func (q *queue) executeRabbitMessageConsumer(err error, consumer MsgCons, delive
 	}
 }
 
-// Consume method for consume message from rabbitmq
+// Consume method for consume message from rabbitmq, the consumer is registered again after a reconnect
 func (q *queue) Consume(consumer MsgCons) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.consumers = append(q.consumers, consumer)
 	q.consumerThread++
+	if q.channel == nil {
+		log.Println(fmt.Sprintf("Queue: %s. Consumer %d is registered once connected", q.name, q.consumerThread))
+		return
+	}
+
 	log.Println(fmt.Sprintf("Queue: %s. Registering consumer %d ...", q.name, q.consumerThread))
 	deliveries, err := q.registerQueueConsumer()
 	log.Println(fmt.Sprintf("Queue: %s. Consumer %d registered! Processing messages...", q.name, q.consumerThread))
 	q.executeRabbitMessageConsumer(err, consumer, deliveries)
 }
 
-// Publish method for publishing message to rabbitmq
+// Publish method for publishing message to rabbitmq. A queue of a CloudEvents format publishes message as data of
+// an event whose attributes are the ce- headers, e.g. ce-id, ce-source and extensions as ce-<name>.
+// Messages are persistent and mandatory, Publish returns a *PublishError unless the broker confirms
+// the message has been routed to a queue. While the connection is down a message of a buffered queue is buffered
+// and nil is returned, it fails with ErrDisconnected otherwise
 func (q *queue) Publish(message string, routingKey string, headers map[string]interface{}) error {
 	log.Println("Sending message...")
 	if routingKey == "" {
 		routingKey = q.routingKey[0]
 	}
-	return q.channel.Publish(
+
+	publishing, err := q.newPublishing(message, routingKey, headers)
+	if err != nil {
+		return err
+	}
+
+	if q.broker.State() == StateConnected {
+		err := q.publish(routingKey, publishing)
+		if !errors.Is(err, ErrChannelClosed) || !q.isBuffered {
+			return err
+		}
+	}
+
+	err = ErrDisconnected
+	if q.isBuffered {
+		err = q.broker.enqueue(q, routingKey, publishing)
+	}
+	if err != nil {
+		return &PublishError{
+			Exchange:   q.exchange.ExcName,
+			RoutingKey: routingKey,
+			MessageID:  publishing.MessageId,
+			Err:        err,
+		}
+	}
+	return nil
+}
+
+// newPublishing returns the persistent publishing of message in format of the queue, its message id is
+// the MessageIDHeader header so a message published again keeps its id
+func (q *queue) newPublishing(message string, routingKey string, headers map[string]interface{}) (amqp.Publishing, error) {
+	if q.format == FormatStructured || q.format == FormatBinary {
+		event, err := NewCloudEvent(message, routingKey, headers)
+		if err != nil {
+			return amqp.Publishing{}, err
+		}
+		publishing, err := EncodeCloudEvent(event, q.format, headers)
+		if err != nil {
+			return amqp.Publishing{}, err
+		}
+		publishing.DeliveryMode = amqp.Persistent
+		return publishing, nil
+	}
+
+	return amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         []byte(message),
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID(headers),
+	}, nil
+}
+
+// messageID returns the MessageIDHeader header of headers, or a new id when it is not set
+func messageID(headers map[string]interface{}) string {
+	if id, ok := headers[MessageIDHeader].(string); ok && id != "" {
+		return id
+	}
+	return uuid.New().String()
+}
+
+// publish publishes on the current channel, and waits for the confirmation of a queue for publish
+func (q *queue) publish(routingKey string, publishing amqp.Publishing) error {
+	q.mu.RLock()
+	channel, confirmer := q.channel, q.confirmer
+	q.mu.RUnlock()
+
+	publishErr := &PublishError{
+		Exchange:   q.exchange.ExcName,
+		RoutingKey: routingKey,
+		MessageID:  publishing.MessageId,
+	}
+	if channel == nil {
+		publishErr.Err = ErrChannelClosed
+		return publishErr
+	}
+
+	if confirmer == nil {
+		err := channel.Publish(
+			q.exchange.ExcName, // exchange
+			routingKey,         // routing key
+			false,              // mandatory
+			false,              // immediate
+			publishing)
+		if err == amqp.ErrClosed {
+			err = ErrChannelClosed
+		}
+		if err != nil {
+			publishErr.Err = err
+			return publishErr
+		}
+		return nil
+	}
+
+	// delivery tags follow the order of publishing on the channel
+	q.publishMu.Lock()
+	pending := confirmer.Expect(publishing.MessageId)
+	err := channel.Publish(
 		q.exchange.ExcName, // exchange
 		routingKey,         // routing key
-		false,              // mandatory
+		true,               // mandatory
 		false,              // immediate
-		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        []byte(message),
-			Headers:     headers,
-		})
+		publishing)
+	if err != nil {
+		pending.Cancel()
+	}
+	q.publishMu.Unlock()
+
+	if err == amqp.ErrClosed {
+		err = ErrChannelClosed
+	}
+	if err == nil {
+		err = pending.Wait(q.confirmTimeout)
+	}
+	if err != nil {
+		publishErr.Err = err
+		if ret := pending.Returned(); ret != nil {
+			publishErr.ReplyCode = ret.ReplyCode
+			publishErr.ReplyText = ret.ReplyText
+		}
+		return publishErr
+	}
+	return nil
 }
 
 // GetQueueName to get queue name
@@ -169,7 +426,23 @@ func (q *queue) GetQueueName() string {
 	return q.name
 }
 
+// Format returns format of published messages
+func (q *queue) Format() Format {
+	return q.format
+}
+
+// State returns state of the connection of the queue
+func (q *queue) State() State {
+	return q.broker.State()
+}
+
 // QueuePurge to close channel
 func (q *queue) QueuePurge() (int, error) {
-	return q.channel.QueuePurge(q.name, true)
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.channel == nil {
+		return 0, ErrDisconnected
+	}
+	return q.channel.QueuePurge(q.brokerName, true)
 }
@@ -0,0 +1,42 @@
+package rmq
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueNewPublishing(t *testing.T) {
+	headers := map[string]interface{}{MessageIDHeader: "7b1c4a52-5c1e-4a53-9a8e-0c52d3f0a111", "ce-source": "/user"}
+
+	for _, format := range []Format{FormatPlain, FormatStructured, FormatBinary} {
+		format := format
+
+		t.Run("success "+string(format)+" message id of header", func(t *testing.T) {
+			q := &queue{format: format}
+
+			first, err := q.newPublishing(`{}`, "user.register", headers)
+			require.NoError(t, err)
+			again, err := q.newPublishing(`{}`, "user.register", headers)
+			require.NoError(t, err)
+
+			assert.Equal(t, "7b1c4a52-5c1e-4a53-9a8e-0c52d3f0a111", first.MessageId)
+			assert.Equal(t, first.MessageId, again.MessageId)
+			assert.Equal(t, amqp.Persistent, first.DeliveryMode)
+		})
+	}
+
+	t.Run("success new message id without header", func(t *testing.T) {
+		q := &queue{format: FormatPlain}
+
+		first, err := q.newPublishing(`{}`, "user.register", nil)
+		require.NoError(t, err)
+		again, err := q.newPublishing(`{}`, "user.register", nil)
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, first.MessageId)
+		assert.NotEqual(t, first.MessageId, again.MessageId)
+	})
+}
@@ -0,0 +1,151 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OperatorEqual matches attribute value equal to filter value
+	OperatorEqual = "eq"
+	// OperatorContains matches attribute value which contains filter value
+	OperatorContains = "co"
+	// OperatorStartsWith matches attribute value which starts with filter value
+	OperatorStartsWith = "sw"
+)
+
+// Filter represent one attribute comparison of filter expression
+type Filter struct {
+	AttrPath string
+	Operator string
+	Value    interface{}
+}
+
+// ParseFilter parse filter expression of list request.
+// Only comparisons with eq, co and sw operator joined by "and" are supported.
+func ParseFilter(expr string) ([]Filter, error) {
+	var (
+		filters []Filter
+		p       = &filterParser{input: expr}
+	)
+
+	for {
+		f, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+
+		p.skipSpaces()
+		if p.eof() {
+			return filters, nil
+		}
+
+		if word := p.word(); !strings.EqualFold(word, "and") {
+			return nil, invalidFilter("unsupported logical operator " + strconv.Quote(word))
+		}
+	}
+}
+
+type filterParser struct {
+	input string
+	pos   int
+}
+
+func (p *filterParser) comparison() (Filter, error) {
+	var f Filter
+
+	p.skipSpaces()
+	f.AttrPath = p.word()
+	if f.AttrPath == "" || strings.ContainsAny(f.AttrPath, "()[]\"") {
+		return f, invalidFilter("invalid attribute path " + strconv.Quote(f.AttrPath))
+	}
+
+	p.skipSpaces()
+	f.Operator = strings.ToLower(p.word())
+	switch f.Operator {
+	case OperatorEqual, OperatorContains, OperatorStartsWith:
+	default:
+		return f, invalidFilter("unsupported operator " + strconv.Quote(f.Operator))
+	}
+
+	p.skipSpaces()
+	value, err := p.value()
+	if err != nil {
+		return f, err
+	}
+	f.Value = value
+
+	return f, nil
+}
+
+func (p *filterParser) value() (interface{}, error) {
+	if p.eof() {
+		return nil, invalidFilter("missing comparison value")
+	}
+
+	if p.input[p.pos] != '"' {
+		word := p.word()
+		switch strings.ToLower(word) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+
+		number, err := strconv.ParseFloat(word, 64)
+		if err != nil {
+			return nil, invalidFilter("invalid comparison value " + strconv.Quote(word))
+		}
+		return number, nil
+	}
+
+	// find closing quote, skipping escaped characters
+	end := p.pos + 1
+	for ; end < len(p.input); end++ {
+		if p.input[end] == '\\' {
+			end++
+			continue
+		}
+		if p.input[end] == '"' {
+			break
+		}
+	}
+	if end >= len(p.input) {
+		return nil, invalidFilter("unterminated string value")
+	}
+
+	var value string
+	if err := json.Unmarshal([]byte(p.input[p.pos:end+1]), &value); err != nil {
+		return nil, invalidFilter("invalid string value")
+	}
+	p.pos = end + 1
+
+	return value, nil
+}
+
+func (p *filterParser) word() string {
+	start := p.pos
+	for !p.eof() && p.input[p.pos] != ' ' {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *filterParser) skipSpaces() {
+	for !p.eof() && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *filterParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func invalidFilter(detail string) error {
+	return NewError(http.StatusBadRequest, "invalidFilter", detail)
+}
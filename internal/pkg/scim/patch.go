@@ -0,0 +1,382 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	opAdd     = "add"
+	opReplace = "replace"
+	opRemove  = "remove"
+)
+
+var (
+	// attribute path, optionally with type filter and sub attribute. e.g. phoneNumbers[type eq "work"].value
+	pathPattern = regexp.MustCompile(`^(\w+)(?:\[type eq "([^"]*)"\])?(?:\.(\w+))?$`)
+	// path of a single group member. e.g. members[value eq "2819c223-7f76-453a-919d-413861904646"]
+	memberPathPattern = regexp.MustCompile(`^members\[value eq "([^"]*)"\]$`)
+)
+
+// PatchRequest represent body of PATCH request
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation represent one operation of PATCH request
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type patchFunc func(op string, path string, value json.RawMessage) error
+
+// applyPatch validates every operation, then calls fn for each targeted attribute path.
+// An operation without path carries a partial resource, each of its attributes is patched separately.
+func applyPatch(ops []PatchOperation, fn patchFunc) error {
+	for _, operation := range ops {
+		op := strings.ToLower(operation.Op)
+		switch op {
+		case opAdd, opReplace, opRemove:
+		default:
+			return NewError(http.StatusBadRequest, "invalidSyntax", "unsupported patch operation "+strconv.Quote(operation.Op))
+		}
+
+		if operation.Path != "" {
+			if err := fn(op, operation.Path, operation.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op == opRemove {
+			return NewError(http.StatusBadRequest, "noTarget", "remove operation requires path")
+		}
+
+		attrs := map[string]json.RawMessage{}
+		if err := json.Unmarshal(operation.Value, &attrs); err != nil {
+			return invalidValue("value of operation without path must be an object")
+		}
+		for path, value := range attrs {
+			if strings.EqualFold(path, "schemas") {
+				continue
+			}
+			if err := fn(op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Patch applies PATCH operations to user resource
+func (u *User) Patch(ops []PatchOperation) error {
+	return applyPatch(ops, u.patch)
+}
+
+func (u *User) patch(op string, path string, value json.RawMessage) error {
+	attr, typ, sub, err := parsePath(path, SchemaUser)
+	if err != nil {
+		return err
+	}
+
+	switch attr {
+	case "active":
+		active := false
+		if op != opRemove {
+			if active, err = decodeBool(value); err != nil {
+				return err
+			}
+		}
+		u.Active = &active
+	case "username":
+		if op == opRemove {
+			return NewError(http.StatusBadRequest, "mutability", "userName is required")
+		}
+		return decodeString(value, &u.UserName)
+	case "externalid":
+		u.ExternalID = ""
+		if op != opRemove {
+			return decodeString(value, &u.ExternalID)
+		}
+	case "name":
+		return u.patchName(op, sub, value)
+	case "emails":
+		u.Emails, err = patchMultiValued(u.Emails, op, typ, sub, value)
+	case "phonenumbers":
+		u.PhoneNumbers, err = patchMultiValued(u.PhoneNumbers, op, typ, sub, value)
+	case "addresses":
+		u.Addresses, err = patchAddresses(u.Addresses, op, typ, sub, value)
+	default:
+		return invalidPath(path)
+	}
+
+	return err
+}
+
+func (u *User) patchName(op string, sub string, value json.RawMessage) error {
+	if sub == "" {
+		if op == opRemove {
+			u.Name = nil
+			return nil
+		}
+
+		name := new(Name)
+		if op == opAdd && u.Name != nil {
+			*name = *u.Name
+		}
+		if err := json.Unmarshal(value, name); err != nil {
+			return invalidValue("name must be an object")
+		}
+		u.Name = name
+		return nil
+	}
+
+	if u.Name == nil {
+		u.Name = new(Name)
+	}
+
+	var field *string
+	switch sub {
+	case "givenname":
+		field = &u.Name.GivenName
+	case "familyname":
+		field = &u.Name.FamilyName
+	case "formatted":
+		field = &u.Name.Formatted
+	default:
+		return invalidPath("name." + sub)
+	}
+
+	*field = ""
+	if op == opRemove {
+		return nil
+	}
+	return decodeString(value, field)
+}
+
+func patchMultiValued(values []MultiValued, op string, typ string, sub string, value json.RawMessage) ([]MultiValued, error) {
+	if typ == "" {
+		if sub != "" {
+			return nil, invalidPath(sub)
+		}
+
+		if op == opRemove {
+			return nil, nil
+		}
+
+		var items []MultiValued
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, invalidValue("value must be an array")
+		}
+		if op == opAdd {
+			return append(values, items...), nil
+		}
+		return items, nil
+	}
+
+	var result []MultiValued
+	for _, v := range values {
+		if !strings.EqualFold(v.Type, typ) {
+			result = append(result, v)
+		}
+	}
+	if op == opRemove {
+		return result, nil
+	}
+
+	item := MultiValued{Type: typ}
+	switch sub {
+	case "":
+		if err := json.Unmarshal(value, &item); err != nil {
+			return nil, invalidValue("value must be an object")
+		}
+		item.Type = typ
+	case "value":
+		if err := decodeString(value, &item.Value); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, invalidPath(sub)
+	}
+
+	return append(result, item), nil
+}
+
+func patchAddresses(values []Address, op string, typ string, sub string, value json.RawMessage) ([]Address, error) {
+	if typ == "" {
+		if sub != "" {
+			return nil, invalidPath(sub)
+		}
+
+		if op == opRemove {
+			return nil, nil
+		}
+
+		var items []Address
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, invalidValue("value must be an array")
+		}
+		if op == opAdd {
+			return append(values, items...), nil
+		}
+		return items, nil
+	}
+
+	var result []Address
+	for _, v := range values {
+		if !strings.EqualFold(v.Type, typ) {
+			result = append(result, v)
+		}
+	}
+	if op == opRemove {
+		return result, nil
+	}
+
+	item := Address{Type: typ}
+	switch sub {
+	case "":
+		if err := json.Unmarshal(value, &item); err != nil {
+			return nil, invalidValue("value must be an object")
+		}
+		item.Type = typ
+	case "formatted":
+		if err := decodeString(value, &item.Formatted); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, invalidPath(sub)
+	}
+
+	return append(result, item), nil
+}
+
+// Patch applies PATCH operations to group resource
+func (g *Group) Patch(ops []PatchOperation) error {
+	return applyPatch(ops, g.patch)
+}
+
+func (g *Group) patch(op string, path string, value json.RawMessage) error {
+	if m := memberPathPattern.FindStringSubmatch(path); m != nil {
+		if op != opRemove {
+			return invalidPath(path)
+		}
+		g.removeMembers([]Member{{Value: m[1]}})
+		return nil
+	}
+
+	attr, _, sub, err := parsePath(path, SchemaGroup)
+	if err != nil {
+		return err
+	}
+	if sub != "" {
+		return invalidPath(path)
+	}
+
+	switch attr {
+	case "displayname":
+		if op == opRemove {
+			return NewError(http.StatusBadRequest, "mutability", "displayName is required")
+		}
+		return decodeString(value, &g.DisplayName)
+	case "externalid":
+		g.ExternalID = ""
+		if op != opRemove {
+			return decodeString(value, &g.ExternalID)
+		}
+	case "members":
+		var members []Member
+		if len(value) > 0 {
+			if err := json.Unmarshal(value, &members); err != nil {
+				return invalidValue("members must be an array")
+			}
+		}
+
+		switch op {
+		case opAdd:
+			g.removeMembers(members)
+			g.Members = append(g.Members, members...)
+		case opReplace:
+			g.Members = members
+		case opRemove:
+			if len(members) == 0 {
+				g.Members = nil
+			} else {
+				g.removeMembers(members)
+			}
+		}
+	default:
+		return invalidPath(path)
+	}
+
+	return nil
+}
+
+func (g *Group) removeMembers(members []Member) {
+	var result []Member
+	for _, current := range g.Members {
+		keep := true
+		for _, m := range members {
+			if current.Value == m.Value {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, current)
+		}
+	}
+	g.Members = result
+}
+
+// parsePath returns lower cased attribute name, type filter and lower cased sub attribute of the given path
+func parsePath(path string, schema string) (attr string, typ string, sub string, err error) {
+	if strings.HasPrefix(strings.ToLower(path), strings.ToLower(schema)+":") {
+		path = path[len(schema)+1:]
+	}
+
+	m := pathPattern.FindStringSubmatch(path)
+	if m == nil {
+		return "", "", "", invalidPath(path)
+	}
+
+	return strings.ToLower(m[1]), m[2], strings.ToLower(m[3]), nil
+}
+
+// decodeBool accepts json boolean, or string "true"/"false" sent by some identity providers
+func decodeBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+			return b, nil
+		}
+	}
+
+	return false, invalidValue("value must be a boolean")
+}
+
+func decodeString(value json.RawMessage, dst *string) error {
+	if err := json.Unmarshal(value, dst); err != nil {
+		return invalidValue("value must be a string")
+	}
+	return nil
+}
+
+func invalidPath(path string) error {
+	return NewError(http.StatusBadRequest, "invalidPath", "unsupported attribute path "+strconv.Quote(path))
+}
+
+func invalidValue(detail string) error {
+	return NewError(http.StatusBadRequest, "invalidValue", detail)
+}
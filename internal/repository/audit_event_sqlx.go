@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// auditChainLock is key of advisory lock serializing appends to the hash chain
+const auditChainLock = 7283001
+
+// auditEventColumns are columns of audit_events which may be queried
+var auditEventColumns = newQueryColumns("uuid", "seq", "actor_uuid", "target_uuid", "action", "outcome", "created_at")
+
+// auditEventSqlxRepository does not join the unit of work of context, events of rolled back actions are kept
+type auditEventSqlxRepository struct {
+	conn *sqlx.DB
+}
+
+// NewAuditEventSqlxRepository will create new an auditEventSqlxRepository object representation of domain.AuditEventRepository interface
+func NewAuditEventSqlxRepository(conn *sqlx.DB) domain.AuditEventRepository {
+	return &auditEventSqlxRepository{conn}
+}
+
+// Store append event to the chain, event is linked to hash of the latest event
+func (db *auditEventSqlxRepository) Store(ctx context.Context, event *domain.AuditEvent) (*domain.AuditEvent, error) {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "begin transaction")
+	}
+	defer tx.Rollback()
+
+	// a sqlite write transaction locks the whole database, there is no advisory lock to take
+	if db.conn.DriverName() == "postgres" {
+		_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock)
+		if err != nil {
+			return nil, errors.Wrap(err, "lock audit chain")
+		}
+	}
+
+	err = tx.GetContext(ctx, &event.PrevHash, `SELECT hash FROM audit_events ORDER BY seq DESC LIMIT 1`)
+	if err == sql.ErrNoRows {
+		event.PrevHash = domain.AuditGenesisHash
+	} else if err != nil {
+		return nil, errors.Wrap(err, "select latest audit event")
+	}
+
+	if len(event.Metadata) == 0 {
+		event.Metadata = []byte("{}")
+	}
+	event.UUID = uuid.New().String()
+	// postgres keeps microseconds, hash must be computed of the stored value
+	event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	event.Hash = event.ChainHash()
+
+	err = tx.GetContext(ctx, &event.Seq, `INSERT INTO audit_events (uuid, actor_uuid, target_uuid, action, outcome, reason, ip_address, user_agent, metadata, prev_hash, hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING seq`,
+		event.UUID, event.ActorUUID, event.TargetUUID, event.Action, event.Outcome, event.Reason, event.IPAddress, event.UserAgent,
+		string(event.Metadata), event.PrevHash, event.Hash, event.CreatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "insert audit_events")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit transaction")
+	}
+
+	return event, nil
+}
+
+func (db *auditEventSqlxRepository) FindBy(ctx context.Context, filter domain.AuditEventFilter) ([]*domain.AuditEvent, error) {
+	return db.find(ctx, auditQuery(filter).OrderBy(domain.Desc("seq")))
+}
+
+// FindPage returns a page of events matching filter in (created_at, uuid) order
+func (db *auditEventSqlxRepository) FindPage(ctx context.Context, filter domain.AuditEventFilter, page domain.PageRequest) ([]*domain.AuditEvent, *domain.Page, error) {
+	query := auditQuery(filter)
+
+	events, err := db.find(ctx, keysetQuery(query, page, "created_at"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&events, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: events[i].CreatedAt, UUID: events[i].UUID}
+	})
+
+	if page.WithTotal {
+		var total int
+
+		filterQuery, args, err := buildWhere(query, auditEventColumns)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = db.conn.GetContext(ctx, &total, `SELECT COUNT(*) FROM audit_events WHERE 1=1`+filterQuery, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return events, result, nil
+}
+
+func (db *auditEventSqlxRepository) find(ctx context.Context, query *domain.Query) ([]*domain.AuditEvent, error) {
+	events := []*domain.AuditEvent{}
+
+	filterQuery, args, err := buildQuery(query, auditEventColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.conn.SelectContext(ctx, &events, `SELECT * FROM audit_events WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// auditQuery converts filter to query conditions, zero value fields are not filtered
+func auditQuery(filter domain.AuditEventFilter) *domain.Query {
+	query := domain.Where()
+
+	if filter.From != nil {
+		query = query.And(domain.Gte("created_at", *filter.From))
+	}
+	if filter.To != nil {
+		query = query.And(domain.Lt("created_at", *filter.To))
+	}
+	if len(filter.Actions) > 0 {
+		actions := make([]interface{}, len(filter.Actions))
+		for i, action := range filter.Actions {
+			actions[i] = action
+		}
+		query = query.And(domain.In("action", actions...))
+	}
+	if filter.ActorUUID != "" {
+		query = query.And(domain.Eq("actor_uuid", filter.ActorUUID))
+	}
+	if filter.TargetUUID != "" {
+		query = query.And(domain.Eq("target_uuid", filter.TargetUUID))
+	}
+	if filter.UserUUID != "" {
+		query = query.And(domain.Or(
+			domain.Eq("actor_uuid", filter.UserUUID),
+			domain.Eq("target_uuid", filter.UserUUID),
+		))
+	}
+
+	return query
+}
+
+// FindAfter returns events following seq in chain order
+func (db *auditEventSqlxRepository) FindAfter(ctx context.Context, seq int64, limit uint) ([]*domain.AuditEvent, error) {
+	events := []*domain.AuditEvent{}
+
+	err := db.conn.SelectContext(ctx, &events, `SELECT * FROM audit_events WHERE seq > $1 ORDER BY seq LIMIT $2`, seq, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// cacheLayer is the read-through cache of a caching decorator. Concurrent misses of a key share a single load,
+// changes are published so other replicas drop their entries too
+type cacheLayer struct {
+	cache     domain.Cache
+	publisher domain.Publisher
+	origin    string
+	group     singleflight.Group
+}
+
+func newCacheLayer(cache domain.Cache, publisher domain.Publisher, origin string) *cacheLayer {
+	return &cacheLayer{cache: cache, publisher: publisher, origin: origin}
+}
+
+// load returns value of key from the cache, or loads it by fn and caches it tagged by tags of the value.
+// Nil values are not cached, a record stored later would not be found. Reads of a transaction or requiring
+// read-your-writes bypass the cache, an invalidation broadcast by another replica may be late or lost. Entries are
+// loaded requiring read-your-writes so a lagging replica does not cache records older than an invalidation.
+// A value whose tags are invalidated while it is loaded is returned but not cached, it may predate the change
+func (c *cacheLayer) load(ctx context.Context, key string, tags func(value interface{}) []string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if ctx.Value(txKey{}) != nil || domain.ReadYourWritesFromContext(ctx) {
+		return fn(ctx)
+	}
+	if value, ok := c.cache.Get(key); ok {
+		return value, nil
+	}
+
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		if value, ok := c.cache.Get(key); ok {
+			return value, nil
+		}
+
+		generation := c.cache.Generation()
+		value, err := fn(domain.WithReadYourWrites(ctx))
+		if err != nil || value == nil {
+			return value, err
+		}
+		c.cache.SetLoaded(key, value, generation, tags(value)...)
+		return value, nil
+	})
+	return value, err
+}
+
+// invalidate drops entries of tags, and again once the transaction of ctx is committed
+// as entries may have been loaded before it. Other replicas are told after commit
+func (c *cacheLayer) invalidate(ctx context.Context, tags ...string) {
+	for _, tag := range tags {
+		c.cache.Invalidate(tag)
+	}
+
+	afterCommit(ctx, func() {
+		for _, tag := range tags {
+			c.cache.Invalidate(tag)
+		}
+		c.publish(tags)
+	})
+}
+
+func (c *cacheLayer) publish(tags []string) {
+	if c.publisher == nil {
+		return
+	}
+
+	message, err := json.Marshal(domain.CacheInvalidation{Origin: c.origin, Tags: tags})
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	err = c.publisher.Publish(string(message), domain.CacheInvalidationRoutingKey, make(map[string]interface{}))
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+// cacheKey returns key of query, queries rendering the same SQL of equal arguments share a key.
+// ok is false when query is invalid, the decorated repository reports it
+func cacheKey(prefix string, query *domain.Query, columns queryColumns) (string, bool) {
+	sql, args, err := buildQuery(query, columns)
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteString(sql)
+	for _, arg := range args {
+		value := memoryValue(arg)
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		fmt.Fprintf(&b, "|%T:%v", value, value)
+	}
+	return b.String(), true
+}
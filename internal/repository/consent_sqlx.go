@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// consentView is consents joined with their policy documents, it is selected as a table so the query builder can filter it
+const consentView = `SELECT * FROM (SELECT c.uuid, c.user_uuid, c.document_uuid, d.kind, d.version, c.ip_address, c.user_agent, c.accepted_at
+	FROM consents c JOIN policy_documents d ON d.uuid = c.document_uuid) consents`
+
+// consentColumns are columns of consentView which may be queried
+var consentColumns = newQueryColumns("uuid", "user_uuid", "document_uuid", "kind", "version", "accepted_at")
+
+type consentSqlxRepository struct {
+	conn txConn
+}
+
+// NewConsentSqlxRepository will create new an consentSqlxRepository object representation of domain.ConsentRepository interface
+func NewConsentSqlxRepository(conn *sqlx.DB) domain.ConsentRepository {
+	return &consentSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *consentSqlxRepository) FindByUser(ctx context.Context, userUUID string) ([]*domain.Consent, error) {
+	consents := []*domain.Consent{}
+
+	err := db.conn.SelectContext(ctx, &consents, `SELECT c.uuid, c.user_uuid, c.document_uuid, d.kind, d.version, c.ip_address, c.user_agent, c.accepted_at
+		FROM consents c JOIN policy_documents d ON d.uuid = c.document_uuid
+		WHERE c.user_uuid=$1
+		ORDER BY c.accepted_at DESC, d.kind`, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return consents, nil
+}
+
+// FindPageByUser returns a page of consents of user in (accepted_at, uuid) order
+func (db *consentSqlxRepository) FindPageByUser(ctx context.Context, userUUID string, page domain.PageRequest) ([]*domain.Consent, *domain.Page, error) {
+	query := domain.Where(domain.Eq("user_uuid", userUUID))
+
+	filterQuery, args, err := buildQuery(keysetQuery(query, page, "accepted_at"), consentColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	consents := []*domain.Consent{}
+	err = db.conn.SelectContext(ctx, &consents, consentView+` WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&consents, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: consents[i].AcceptedAt, UUID: consents[i].UUID}
+	})
+
+	if page.WithTotal {
+		var total int
+		err = db.conn.GetContext(ctx, &total, `SELECT COUNT(*) FROM consents WHERE user_uuid=$1`, userUUID)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return consents, result, nil
+}
+
+// Store persist consent, accepting the same document again keeps the first record
+func (db *consentSqlxRepository) Store(ctx context.Context, consent *domain.Consent) (*domain.Consent, error) {
+	_, err := db.conn.ExecContext(ctx, `INSERT INTO consents (user_uuid, document_uuid, ip_address, user_agent) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_uuid, document_uuid) DO NOTHING`,
+		consent.UserUUID, consent.DocumentUUID, consent.IPAddress, consent.UserAgent)
+	if err != nil {
+		return nil, errors.Wrap(err, "insert consents")
+	}
+
+	err = db.conn.GetContext(ctx, consent, `SELECT c.uuid, c.user_uuid, c.document_uuid, d.kind, d.version, c.ip_address, c.user_agent, c.accepted_at
+		FROM consents c JOIN policy_documents d ON d.uuid = c.document_uuid
+		WHERE c.user_uuid=$1 AND c.document_uuid=$2`, consent.UserUUID, consent.DocumentUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "select consents")
+	}
+
+	return consent, nil
+}
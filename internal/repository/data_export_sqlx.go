@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// dataExportColumns are columns of data_exports which may be queried
+var dataExportColumns = newQueryColumns("uuid", "user_uuid", "status", "completed_at", "expires_at", "created_at", "updated_at")
+
+type dataExportSqlxRepository struct {
+	conn txConn
+}
+
+// NewDataExportSqlxRepository will create new an dataExportSqlxRepository object representation of domain.DataExportRepository interface
+func NewDataExportSqlxRepository(conn *sqlx.DB) domain.DataExportRepository {
+	return &dataExportSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *dataExportSqlxRepository) Find(ctx context.Context, uuid string) (*domain.DataExport, error) {
+	export := new(domain.DataExport)
+	err := db.conn.GetContext(ctx, export, `SELECT * FROM data_exports WHERE uuid=$1`, uuid)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (db *dataExportSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.DataExport, error) {
+	filterQuery, args, err := buildQuery(query, dataExportColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	export := new(domain.DataExport)
+	err = db.conn.GetContext(ctx, export, `SELECT * FROM data_exports WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (db *dataExportSqlxRepository) Store(ctx context.Context, export *domain.DataExport) (*domain.DataExport, error) {
+	stmt, err := db.conn.PrepareContext(ctx, `INSERT INTO data_exports (user_uuid, status) VALUES ($1, $2) RETURNING uuid, created_at, updated_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare data_exports insertion")
+	}
+
+	row := stmt.QueryRowContext(ctx, export.UserUUID, export.Status)
+
+	if err = row.Scan(&export.UUID, &export.CreatedAt, &export.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	return export, nil
+}
+
+func (db *dataExportSqlxRepository) Update(ctx context.Context, export *domain.DataExport) (*domain.DataExport, error) {
+	stmt, err := db.conn.PrepareContext(ctx, `UPDATE data_exports SET status=$1, file_path=$2, error=$3, completed_at=$4, expires_at=$5 WHERE uuid=$6 RETURNING updated_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare data_exports update")
+	}
+
+	row := stmt.QueryRowContext(ctx, export.Status, export.FilePath, export.Error, export.CompletedAt, export.ExpiresAt, export.UUID)
+
+	if err = row.Scan(&export.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	return export, nil
+}
+
+func (db *dataExportSqlxRepository) FailStale(ctx context.Context, before time.Time, reason string) (int, error) {
+	result, err := db.conn.ExecContext(ctx, `UPDATE data_exports SET status=$1, error=$2 WHERE status=$3 AND created_at < $4`,
+		domain.ExportFailed, reason, domain.ExportPending, before.UTC())
+	if err != nil {
+		return 0, errors.Wrap(err, "fail stale data exports")
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
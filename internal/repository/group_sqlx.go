@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// groupColumns are columns of groups which may be queried
+var groupColumns = newQueryColumns("uuid", "tenant_uuid", "display_name", "external_id", "created_at", "updated_at")
+
+type groupSqlxRepository struct {
+	conn txConn
+}
+
+// NewGroupSqlxRepository will create new an groupSqlxRepository object representation of domain.GroupRepository interface
+func NewGroupSqlxRepository(conn *sqlx.DB) domain.GroupRepository {
+	return &groupSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *groupSqlxRepository) Find(ctx context.Context, uuid string) (*domain.Group, error) {
+	group := new(domain.Group)
+	err := db.conn.GetContext(ctx, group, `SELECT * FROM groups WHERE uuid=$1`, uuid)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return group, db.fetchMembers(ctx, group)
+}
+
+func (db *groupSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.Group, error) {
+	filterQuery, args, err := buildQuery(query, groupColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	group := new(domain.Group)
+	err = db.conn.GetContext(ctx, group, `SELECT * FROM groups WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return group, db.fetchMembers(ctx, group)
+}
+
+func (db *groupSqlxRepository) FindBy(ctx context.Context, query *domain.Query) ([]*domain.Group, error) {
+	var groups []*domain.Group
+
+	filterQuery, args, err := buildQuery(query, groupColumns)
+	if err != nil {
+		return groups, err
+	}
+
+	err = db.conn.SelectContext(ctx, &groups, `SELECT * FROM groups WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return groups, err
+	}
+
+	for _, group := range groups {
+		if err := db.fetchMembers(ctx, group); err != nil {
+			return groups, err
+		}
+	}
+	return groups, nil
+}
+
+func (db *groupSqlxRepository) Count(ctx context.Context, query *domain.Query) (int, error) {
+	var count int
+
+	filterQuery, args, err := buildWhere(query, groupColumns)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.conn.GetContext(ctx, &count, `SELECT COUNT(*) FROM groups WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindPage returns a page of groups matching query in (created_at, uuid) order
+func (db *groupSqlxRepository) FindPage(ctx context.Context, query *domain.Query, page domain.PageRequest) ([]*domain.Group, *domain.Page, error) {
+	groups, err := db.FindBy(ctx, keysetQuery(query, page, "created_at"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&groups, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: groups[i].CreatedAt, UUID: groups[i].UUID}
+	})
+
+	if page.WithTotal {
+		total, err := db.Count(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return groups, result, nil
+}
+
+func (db *groupSqlxRepository) Store(ctx context.Context, group *domain.Group) (*domain.Group, error) {
+	stmt, err := db.conn.PrepareContext(ctx, "INSERT INTO groups (tenant_uuid, display_name, external_id) VALUES ($1, $2, $3) RETURNING uuid, created_at, updated_at")
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare groups insertion")
+	}
+
+	row := stmt.QueryRowContext(ctx, group.TenantUUID, group.DisplayName, group.ExternalID)
+
+	if err = row.Scan(&group.UUID, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	if err := db.storeMembers(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (db *groupSqlxRepository) Update(ctx context.Context, group *domain.Group) (*domain.Group, error) {
+	stmt, err := db.conn.PrepareContext(ctx, `UPDATE groups SET display_name=$1, external_id=$2 WHERE uuid=$3 RETURNING uuid, tenant_uuid, created_at, updated_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare groups update")
+	}
+
+	row := stmt.QueryRowContext(ctx, group.DisplayName, group.ExternalID, group.UUID)
+
+	if err = row.Scan(&group.UUID, &group.TenantUUID, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	_, err = db.conn.ExecContext(ctx, `DELETE FROM group_members WHERE group_uuid=$1`, group.UUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "delete group members")
+	}
+
+	if err := db.storeMembers(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (db *groupSqlxRepository) Delete(ctx context.Context, uuid string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM groups WHERE uuid=$1`, uuid)
+	if err != nil {
+		return errors.Wrap(err, "executes a delete query")
+	}
+	return nil
+}
+
+func (db *groupSqlxRepository) DeleteMember(ctx context.Context, userUUID string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM group_members WHERE user_uuid=$1`, userUUID)
+	if err != nil {
+		return errors.Wrap(err, "delete group membership")
+	}
+	return nil
+}
+
+func (db *groupSqlxRepository) fetchMembers(ctx context.Context, group *domain.Group) error {
+	group.Members = []string{}
+	err := db.conn.SelectContext(ctx, &group.Members, `SELECT user_uuid FROM group_members WHERE group_uuid=$1 ORDER BY created_at, user_uuid`, group.UUID)
+	if err != nil {
+		return errors.Wrap(err, "fetch group members")
+	}
+	return nil
+}
+
+func (db *groupSqlxRepository) storeMembers(ctx context.Context, group *domain.Group) error {
+	for _, member := range group.Members {
+		_, err := db.conn.ExecContext(ctx, `INSERT INTO group_members (group_uuid, user_uuid) VALUES ($1, $2) ON CONFLICT DO NOTHING`, group.UUID, member)
+		if err != nil {
+			return errors.Wrap(err, "store group member")
+		}
+	}
+	return nil
+}
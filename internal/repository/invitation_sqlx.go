@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// invitationColumns are columns of invitations which may be queried
+var invitationColumns = newQueryColumns("uuid", "email", "token", "invited_by", "expires_at", "accepted_at", "created_at", "updated_at")
+
+type invitationSqlxRepository struct {
+	conn txConn
+}
+
+// NewInvitationSqlxRepository will create new an invitationSqlxRepository object representation of domain.InvitationRepository interface
+func NewInvitationSqlxRepository(conn *sqlx.DB) domain.InvitationRepository {
+	return &invitationSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *invitationSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.Invitation, error) {
+	filterQuery, args, err := buildQuery(query, invitationColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := new(domain.Invitation)
+	err = db.conn.GetContext(ctx, invitation, `SELECT * FROM invitations WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (db *invitationSqlxRepository) Store(ctx context.Context, invitation *domain.Invitation) (*domain.Invitation, error) {
+	stmt, err := db.conn.PrepareContext(ctx, `INSERT INTO invitations (email, token, invited_by, expires_at) VALUES ($1, $2, $3, $4) RETURNING uuid, created_at, updated_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare invitations insertion")
+	}
+
+	row := stmt.QueryRowContext(ctx, invitation.Email, invitation.Token, invitation.InvitedBy, invitation.ExpiresAt)
+
+	if err = row.Scan(&invitation.UUID, &invitation.CreatedAt, &invitation.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	return invitation, nil
+}
+
+func (db *invitationSqlxRepository) Update(ctx context.Context, invitation *domain.Invitation) (*domain.Invitation, error) {
+	stmt, err := db.conn.PrepareContext(ctx, `UPDATE invitations SET expires_at=$1, accepted_at=$2 WHERE uuid=$3 RETURNING updated_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare invitations update")
+	}
+
+	row := stmt.QueryRowContext(ctx, invitation.ExpiresAt, invitation.AcceptedAt, invitation.UUID)
+
+	if err = row.Scan(&invitation.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// loginAttemptColumns are columns of login_attempts which may be queried
+var loginAttemptColumns = newQueryColumns("uuid", "user_uuid", "email", "method", "success", "ip_address", "browser", "os", "device",
+	"device_fingerprint", "country_code", "created_at")
+
+type loginAttemptSqlxRepository struct {
+	conn txConn
+}
+
+// NewLoginAttemptSqlxRepository will create new an loginAttemptSqlxRepository object representation of domain.LoginAttemptRepository interface
+func NewLoginAttemptSqlxRepository(conn *sqlx.DB) domain.LoginAttemptRepository {
+	return &loginAttemptSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *loginAttemptSqlxRepository) FindBy(ctx context.Context, query *domain.Query) ([]*domain.LoginAttempt, error) {
+	attempts := []*domain.LoginAttempt{}
+
+	filterQuery, args, err := buildQuery(query, loginAttemptColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.conn.SelectContext(ctx, &attempts, `SELECT * FROM login_attempts WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// FindPage returns a page of login attempts matching query in (created_at, uuid) order
+func (db *loginAttemptSqlxRepository) FindPage(ctx context.Context, query *domain.Query, page domain.PageRequest) ([]*domain.LoginAttempt, *domain.Page, error) {
+	attempts, err := db.FindBy(ctx, keysetQuery(query, page, "created_at"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&attempts, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: attempts[i].CreatedAt, UUID: attempts[i].UUID}
+	})
+
+	if page.WithTotal {
+		total, err := db.Count(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return attempts, result, nil
+}
+
+func (db *loginAttemptSqlxRepository) Count(ctx context.Context, query *domain.Query) (int, error) {
+	var count int
+
+	filterQuery, args, err := buildWhere(query, loginAttemptColumns)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.conn.GetContext(ctx, &count, `SELECT COUNT(*) FROM login_attempts WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (db *loginAttemptSqlxRepository) Store(ctx context.Context, attempt *domain.LoginAttempt) (*domain.LoginAttempt, error) {
+	stmt, err := db.conn.PrepareContext(ctx, `INSERT INTO login_attempts (user_uuid, email, method, success, failure_reason, ip_address, user_agent,
+		browser, browser_version, os, device, device_fingerprint, country_code, country, city)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING uuid, created_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare login_attempts insertion")
+	}
+
+	row := stmt.QueryRowContext(ctx, attempt.UserUUID, attempt.Email, attempt.Method, attempt.Success, attempt.FailureReason, attempt.IPAddress, attempt.UserAgent,
+		attempt.Browser, attempt.BrowserVersion, attempt.OS, attempt.Device, attempt.DeviceFingerprint, attempt.CountryCode, attempt.Country, attempt.City)
+
+	if err = row.Scan(&attempt.UUID, &attempt.CreatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	return attempt, nil
+}
@@ -0,0 +1,341 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// Constraint violations of records of memory repositories, they are the errors the database raises
+var (
+	errUniqueViolation     = errors.New("duplicate key value violates unique constraint")
+	errCheckViolation      = errors.New("new row violates check constraint")
+	errForeignKeyViolation = errors.New("insert or update violates foreign key constraint")
+)
+
+// memoryRow is columns of a record kept by a memory repository
+type memoryRow map[string]interface{}
+
+// selectRows returns indexes of the n rows which match query, sorted and paginated the way buildQuery renders it in SQL.
+// Rows are in insertion order when query has no sort
+func selectRows(n int, row func(i int) memoryRow, query *domain.Query, columns queryColumns) ([]int, error) {
+	if query == nil {
+		query = new(domain.Query)
+	}
+
+	matched := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		ok, err := matchAll(row(i), query.Conditions, columns)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, i)
+		}
+	}
+
+	orders, err := memoryOrders(query.Orders, columns)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) > 0 {
+		sort.SliceStable(matched, func(a, b int) bool {
+			return lessRow(row(matched[a]), row(matched[b]), orders)
+		})
+	}
+
+	if query.Offset != nil {
+		if int(*query.Offset) >= len(matched) {
+			return nil, nil
+		}
+		matched = matched[*query.Offset:]
+	}
+	if query.Limit != nil && int(*query.Limit) < len(matched) {
+		matched = matched[:*query.Limit]
+	}
+
+	return matched, nil
+}
+
+// countRows returns number of the n rows which match conditions of query, as buildWhere does
+func countRows(n int, row func(i int) memoryRow, query *domain.Query, columns queryColumns) (int, error) {
+	if query != nil {
+		query = &domain.Query{Conditions: query.Conditions}
+	}
+
+	matched, err := selectRows(n, row, query, columns)
+	return len(matched), err
+}
+
+func matchAll(r memoryRow, conditions []domain.Condition, columns queryColumns) (bool, error) {
+	for _, c := range conditions {
+		ok, err := matchRow(r, c, columns)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchRow evaluates condition c on row r, comparison with NULL never matches
+func matchRow(r memoryRow, c domain.Condition, columns queryColumns) (bool, error) {
+	switch c.Operator {
+	case domain.OpAnd:
+		return matchAll(r, c.Conditions, columns)
+	case domain.OpOr:
+		for _, sub := range c.Conditions {
+			ok, err := matchRow(r, sub, columns)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	if !columns[c.Column] {
+		return false, domain.ErrInvalidQuery
+	}
+	value := memoryValue(r[c.Column])
+
+	switch c.Operator {
+	case domain.OpEqual, domain.OpNotEqual, domain.OpGreater, domain.OpGreaterEqual, domain.OpLess, domain.OpLessEqual:
+		if c.Value == nil {
+			return false, domain.ErrInvalidQuery
+		}
+		if value == nil {
+			return false, nil
+		}
+		cmp, err := compareValues(value, memoryValue(c.Value))
+		if err != nil {
+			return false, err
+		}
+		switch c.Operator {
+		case domain.OpEqual:
+			return cmp == 0, nil
+		case domain.OpNotEqual:
+			return cmp != 0, nil
+		case domain.OpGreater:
+			return cmp > 0, nil
+		case domain.OpGreaterEqual:
+			return cmp >= 0, nil
+		case domain.OpLess:
+			return cmp < 0, nil
+		}
+		return cmp <= 0, nil
+	case domain.OpLike:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return false, domain.ErrInvalidQuery
+		}
+		if value == nil {
+			return false, nil
+		}
+		return likePattern(pattern).MatchString(fmt.Sprint(value)), nil
+	case domain.OpIn:
+		values, ok := c.Value.([]interface{})
+		if !ok {
+			return false, domain.ErrInvalidQuery
+		}
+		if value == nil {
+			return false, nil
+		}
+		for _, v := range values {
+			cmp, err := compareValues(value, memoryValue(v))
+			if err != nil {
+				return false, err
+			}
+			if cmp == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case domain.OpBetween:
+		bounds, ok := c.Value.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return false, domain.ErrInvalidQuery
+		}
+		if value == nil {
+			return false, nil
+		}
+		from, err := compareValues(value, memoryValue(bounds[0]))
+		if err != nil {
+			return false, err
+		}
+		to, err := compareValues(value, memoryValue(bounds[1]))
+		if err != nil {
+			return false, err
+		}
+		return from >= 0 && to <= 0, nil
+	case domain.OpIsNull:
+		return value == nil, nil
+	case domain.OpIsNotNull:
+		return value != nil, nil
+	}
+
+	return false, domain.ErrInvalidQuery
+}
+
+// memoryOrders validates orders and appends uuid, as queryBuilder.orderBy does
+func memoryOrders(orders []domain.Order, columns queryColumns) ([]domain.Order, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	byUUID := false
+	for _, o := range orders {
+		if !columns[o.Column] {
+			return nil, domain.ErrInvalidQuery
+		}
+		byUUID = byUUID || o.Column == "uuid"
+	}
+
+	if !byUUID && columns["uuid"] {
+		orders = append(append([]domain.Order(nil), orders...), domain.Asc("uuid"))
+	}
+	return orders, nil
+}
+
+// lessRow reports whether row a sorts before row b, NULL is the largest value as in Postgres
+func lessRow(a memoryRow, b memoryRow, orders []domain.Order) bool {
+	for _, o := range orders {
+		va, vb := memoryValue(a[o.Column]), memoryValue(b[o.Column])
+
+		var cmp int
+		switch {
+		case va == nil && vb == nil:
+			cmp = 0
+		case va == nil:
+			cmp = 1
+		case vb == nil:
+			cmp = -1
+		default:
+			cmp, _ = compareValues(va, vb)
+		}
+
+		if o.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return false
+}
+
+// memoryValue dereference pointer values and widen numbers, nil pointer is NULL
+func memoryValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return v.Interface()
+}
+
+// compareValues returns -1, 0 or 1 when a is less than, equal to or greater than b.
+// Values of different types can not be compared, like a query of mismatched type fails in the database
+func compareValues(a interface{}, b interface{}) (int, error) {
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, nil
+			case y:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, nil
+			case x.After(y):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, domain.ErrInvalidQuery
+}
+
+// likePattern compiles a LIKE pattern, escaped by domain.EscapeLike, into a case insensitive regexp as ILIKE matches
+func likePattern(pattern string) *regexp.Regexp {
+	var (
+		expr    strings.Builder
+		escaped bool
+	)
+
+	expr.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(".*")
+		case r == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String())
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type outboxSqlxRepository struct {
+	conn txConn
+}
+
+// NewOutboxSqlxRepository will create new an outboxSqlxRepository object representation of domain.OutboxRepository interface.
+// Times are bound in UTC, so they compare with the times stored by sqlite as well
+func NewOutboxSqlxRepository(conn *sqlx.DB) domain.OutboxRepository {
+	return &outboxSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *outboxSqlxRepository) Store(ctx context.Context, message *domain.OutboxMessage) (*domain.OutboxMessage, error) {
+	row := db.conn.QueryRowxContext(ctx, `INSERT INTO outbox_messages (routing_key, version, correlation_id, payload) VALUES ($1, $2, $3, $4)
+		RETURNING uuid, attempts, next_attempt_at, created_at`, message.RoutingKey, message.Version, message.CorrelationID, message.Payload)
+
+	if err := row.Scan(&message.UUID, &message.Attempts, &message.NextAttemptAt, &message.CreatedAt); err != nil {
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	return message, nil
+}
+
+func (db *outboxSqlxRepository) Claim(ctx context.Context, limit uint, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	messages := []*domain.OutboxMessage{}
+
+	// a sqlite write transaction locks the whole database, rows are not locked
+	lockRows := ""
+	if db.conn.DriverName() == "postgres" {
+		lockRows = postgresDialect.lockRows
+	}
+
+	now := timestampNow()
+	err := db.conn.SelectContext(ctx, &messages, `UPDATE outbox_messages SET locked_until=$1 WHERE uuid IN (
+		SELECT uuid FROM outbox_messages WHERE sent_at IS NULL AND next_attempt_at <= $2 AND (locked_until IS NULL OR locked_until <= $2)
+		ORDER BY created_at, uuid LIMIT $3`+lockRows+`) RETURNING *`, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// returned rows are not ordered
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].UUID < messages[j].UUID
+		}
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages, nil
+}
+
+func (db *outboxSqlxRepository) Update(ctx context.Context, message *domain.OutboxMessage) (*domain.OutboxMessage, error) {
+	var sentAt *time.Time
+	if message.SentAt != nil {
+		t := message.SentAt.UTC()
+		sentAt = &t
+	}
+
+	_, err := db.conn.ExecContext(ctx, `UPDATE outbox_messages SET attempts=$1, last_error=$2, next_attempt_at=$3, sent_at=$4, locked_until=NULL WHERE uuid=$5`,
+		message.Attempts, message.LastError, message.NextAttemptAt.UTC(), sentAt, message.UUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "update outbox message")
+	}
+
+	message.LockedUntil = nil
+	return message, nil
+}
+
+func (db *outboxSqlxRepository) DeleteSent(ctx context.Context, before time.Time) (int, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM outbox_messages WHERE sent_at < $1`, before.UTC())
+	if err != nil {
+		return 0, errors.Wrap(err, "delete sent outbox messages")
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
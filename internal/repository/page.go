@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"reflect"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// keysetQuery returns query restricted to the requested page in (timeColumn, uuid) order, it replaces sort and pagination of query.
+// A backward page is read in reverse order, one more record than limit is read to know whether another page follows
+func keysetQuery(query *domain.Query, page domain.PageRequest, timeColumn string) *domain.Query {
+	var (
+		desc   = page.Desc
+		cursor = page.After
+	)
+	if page.Before != nil {
+		desc = !desc
+		cursor = page.Before
+	}
+
+	compare, order := domain.Gt, domain.Asc
+	if desc {
+		compare, order = domain.Lt, domain.Desc
+	}
+
+	keyset := query.And()
+	if cursor != nil {
+		keyset = keyset.And(domain.Or(
+			compare(timeColumn, cursor.Time),
+			domain.And(domain.Eq(timeColumn, cursor.Time), compare("uuid", cursor.UUID)),
+		))
+	}
+
+	keyset.Orders = []domain.Order{order(timeColumn), order("uuid")}
+	return keyset.Paginate(page.Size()+1, 0)
+}
+
+// pageOf trims the extra record of rows read by keysetQuery, restores order of a backward page and returns cursors of the page.
+// rows is pointer to slice of records, cursorAt returns cursor of i-th record of the trimmed slice
+func pageOf(rows interface{}, page domain.PageRequest, cursorAt func(i int) domain.Cursor) *domain.Page {
+	v := reflect.ValueOf(rows).Elem()
+
+	more := uint(v.Len()) > page.Size()
+	if more {
+		v.Set(v.Slice(0, int(page.Size())))
+	}
+
+	if page.Before != nil {
+		swap := reflect.Swapper(v.Interface())
+		for i, j := 0, v.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	result := new(domain.Page)
+	if v.Len() == 0 {
+		return result
+	}
+
+	first, last := cursorAt(0), cursorAt(v.Len()-1)
+	if page.Before != nil {
+		// the record of cursor follows this page
+		result.Next = &last
+		if more {
+			result.Prev = &first
+		}
+	} else {
+		if more {
+			result.Next = &last
+		}
+		if page.After != nil {
+			result.Prev = &first
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type passwordHistorySqlxRepository struct {
+	conn txConn
+}
+
+// NewPasswordHistorySqlxRepository will create new an passwordHistorySqlxRepository object representation of domain.PasswordHistoryRepository interface
+func NewPasswordHistorySqlxRepository(conn *sqlx.DB) domain.PasswordHistoryRepository {
+	return &passwordHistorySqlxRepository{txConn{DB: conn}}
+}
+
+// FindRecent returns the latest replaced passwords of user, newest first
+func (db *passwordHistorySqlxRepository) FindRecent(ctx context.Context, userUUID string, limit uint) ([]*domain.PasswordHistory, error) {
+	histories := []*domain.PasswordHistory{}
+
+	err := db.conn.SelectContext(ctx, &histories, `SELECT * FROM password_history WHERE user_uuid=$1 ORDER BY created_at DESC LIMIT $2`, userUUID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+func (db *passwordHistorySqlxRepository) Store(ctx context.Context, history *domain.PasswordHistory) (*domain.PasswordHistory, error) {
+	err := db.conn.QueryRowxContext(ctx, `INSERT INTO password_history (user_uuid, password) VALUES ($1, $2) RETURNING uuid, created_at`,
+		history.UserUUID, history.Password).Scan(&history.UUID, &history.CreatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "insert password_history")
+	}
+
+	return history, nil
+}
+
+// Prune removes passwords of user older than the latest keep ones
+func (db *passwordHistorySqlxRepository) Prune(ctx context.Context, userUUID string, keep uint) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM password_history WHERE user_uuid=$1 AND uuid NOT IN (
+		SELECT uuid FROM password_history WHERE user_uuid=$1 ORDER BY created_at DESC LIMIT $2)`, userUUID, keep)
+	if err != nil {
+		return errors.Wrap(err, "delete password_history")
+	}
+
+	return nil
+}
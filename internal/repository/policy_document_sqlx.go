@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// policyDocumentColumns are columns of policy_documents which may be queried
+var policyDocumentColumns = newQueryColumns("uuid", "kind", "version", "published_at", "created_at", "updated_at")
+
+type policyDocumentSqlxRepository struct {
+	conn txConn
+}
+
+// NewPolicyDocumentSqlxRepository will create new an policyDocumentSqlxRepository object representation of domain.PolicyDocumentRepository interface
+func NewPolicyDocumentSqlxRepository(conn *sqlx.DB) domain.PolicyDocumentRepository {
+	return &policyDocumentSqlxRepository{txConn{DB: conn}}
+}
+
+// FindCurrent returns the latest published document of each kind, a document is current when no later one of its kind is published
+func (db *policyDocumentSqlxRepository) FindCurrent(ctx context.Context) ([]*domain.PolicyDocument, error) {
+	documents := []*domain.PolicyDocument{}
+
+	err := db.conn.SelectContext(ctx, &documents, `SELECT d.* FROM policy_documents d
+		WHERE d.published_at <= $1 AND NOT EXISTS (
+			SELECT 1 FROM policy_documents n WHERE n.kind = d.kind AND n.published_at <= $1
+			AND (n.published_at > d.published_at OR (n.published_at = d.published_at AND n.uuid > d.uuid))
+		)
+		ORDER BY d.kind`, timestampNow())
+	if err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (db *policyDocumentSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.PolicyDocument, error) {
+	filterQuery, args, err := buildQuery(query, policyDocumentColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	document := new(domain.PolicyDocument)
+	err = db.conn.GetContext(ctx, document, `SELECT * FROM policy_documents WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return document, nil
+}
+
+func (db *policyDocumentSqlxRepository) Store(ctx context.Context, document *domain.PolicyDocument) (*domain.PolicyDocument, error) {
+	stmt, err := db.conn.PrepareContext(ctx, `INSERT INTO policy_documents (kind, version, url, published_at) VALUES ($1, $2, $3, $4) RETURNING uuid, created_at, updated_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare policy_documents insertion")
+	}
+
+	row := stmt.QueryRowContext(ctx, document.Kind, document.Version, document.URL, document.PublishedAt)
+
+	if err = row.Scan(&document.UUID, &document.CreatedAt, &document.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	return document, nil
+}
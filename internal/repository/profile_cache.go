@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// profileCacheRepository caches profiles found by Find and FindOneBy, other reads go to the decorated repository
+type profileCacheRepository struct {
+	domain.ProfileRepository
+	layer *cacheLayer
+}
+
+// NewProfileCacheRepository will create new an profileCacheRepository object representation of domain.ProfileRepository interface.
+// Profiles read from next are kept in cache, changes are published by publisher as origin
+func NewProfileCacheRepository(next domain.ProfileRepository, cache domain.Cache, publisher domain.Publisher, origin string) domain.ProfileRepository {
+	return &profileCacheRepository{next, newCacheLayer(cache, publisher, origin)}
+}
+
+func (r *profileCacheRepository) Find(ctx context.Context, uuid string) (*domain.Profile, error) {
+	return r.load(ctx, "profile:"+uuid, func(ctx context.Context) (*domain.Profile, error) {
+		return r.ProfileRepository.Find(ctx, uuid)
+	})
+}
+
+func (r *profileCacheRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.Profile, error) {
+	key, ok := cacheKey("profile:query:", query, profileColumns)
+	if !ok {
+		return r.ProfileRepository.FindOneBy(ctx, query)
+	}
+
+	return r.load(ctx, key, func(ctx context.Context) (*domain.Profile, error) {
+		return r.ProfileRepository.FindOneBy(ctx, query)
+	})
+}
+
+func (r *profileCacheRepository) Store(ctx context.Context, profile *domain.Profile) (*domain.Profile, error) {
+	stored, err := r.ProfileRepository.Store(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	r.layer.invalidate(ctx, profileTag(stored.UUID))
+	return stored, nil
+}
+
+func (r *profileCacheRepository) Update(ctx context.Context, profile *domain.Profile) error {
+	if err := r.ProfileRepository.Update(ctx, profile); err != nil {
+		return err
+	}
+
+	r.layer.invalidate(ctx, profileTag(profile.UUID))
+	return nil
+}
+
+// load returns copy of the cached profile, usecases change profiles they find
+func (r *profileCacheRepository) load(ctx context.Context, key string, fn func(ctx context.Context) (*domain.Profile, error)) (*domain.Profile, error) {
+	value, err := r.layer.load(ctx, key, func(value interface{}) []string {
+		return []string{profileTag(value.(*domain.Profile).UUID)}
+	}, func(ctx context.Context) (interface{}, error) {
+		profile, err := fn(ctx)
+		if err != nil || profile == nil {
+			return nil, err
+		}
+		return copyProfile(profile), nil
+	})
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return copyProfile(value.(*domain.Profile)), nil
+}
+
+// profileTag is cache tag of entries holding profile of uuid
+func profileTag(uuid string) string {
+	return "profile:" + uuid
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type profileMemoryRepository struct {
+	mu       sync.RWMutex
+	profiles []*domain.Profile
+	userRepo domain.UserRepository
+}
+
+// NewProfileMemoryRepository will create new an profileMemoryRepository object representation of domain.ProfileRepository interface.
+// It behaves as the profiles table of plain text personal data, user of a profile has to exist in userRepo
+func NewProfileMemoryRepository(userRepo domain.UserRepository) domain.ProfileRepository {
+	return &profileMemoryRepository{userRepo: userRepo}
+}
+
+func (m *profileMemoryRepository) Find(ctx context.Context, uuid string) (*domain.Profile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if i := m.index(uuid); i >= 0 {
+		return copyProfile(m.profiles[i]), nil
+	}
+	return nil, nil
+}
+
+func (m *profileMemoryRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.Profile, error) {
+	profiles, err := m.FindBy(ctx, query)
+	if err != nil || len(profiles) == 0 {
+		return nil, err
+	}
+	return profiles[0], nil
+}
+
+func (m *profileMemoryRepository) FindAll(ctx context.Context) ([]*domain.Profile, error) {
+	return m.FindBy(ctx, nil)
+}
+
+func (m *profileMemoryRepository) FindBy(ctx context.Context, query *domain.Query) ([]*domain.Profile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched, err := selectRows(len(m.profiles), m.row, query, profileColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []*domain.Profile
+	for _, i := range matched {
+		profiles = append(profiles, copyProfile(m.profiles[i]))
+	}
+	return profiles, nil
+}
+
+func (m *profileMemoryRepository) Count(ctx context.Context, query *domain.Query) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return countRows(len(m.profiles), m.row, query, profileColumns)
+}
+
+// FindPage returns a page of profiles matching query in (created_at, uuid) order
+func (m *profileMemoryRepository) FindPage(ctx context.Context, query *domain.Query, page domain.PageRequest) ([]*domain.Profile, *domain.Page, error) {
+	profiles, err := m.FindBy(ctx, keysetQuery(query, page, "created_at"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&profiles, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: profiles[i].CreatedAt, UUID: profiles[i].UUID}
+	})
+
+	if page.WithTotal {
+		total, err := m.Count(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return profiles, result, nil
+}
+
+func (m *profileMemoryRepository) Store(ctx context.Context, profile *domain.Profile) (*domain.Profile, error) {
+	if err := m.check(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.userTaken(profile.User.UUID, "") {
+		return nil, errors.Wrap(errUniqueViolation, "row scan")
+	}
+
+	now := timestampNow()
+	stored := copyProfile(profile)
+	stored.UUID = newUUID()
+	stored.UserUUID = profile.User.UUID
+	stored.Dob = dateOf(profile.Dob)
+	stored.Version = 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	m.profiles = append(m.profiles, stored)
+
+	profile.UUID = stored.UUID
+	profile.UserUUID = stored.UserUUID
+	profile.Version = stored.Version
+	profile.CreatedAt = stored.CreatedAt
+	profile.UpdatedAt = stored.UpdatedAt
+	return profile, nil
+}
+
+func (m *profileMemoryRepository) Update(ctx context.Context, profile *domain.Profile) error {
+	if err := m.check(ctx, profile); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(profile.UUID)
+	if i < 0 {
+		return domain.ErrProfileNotFound
+	}
+	stored := m.profiles[i]
+	if stored.Version != profile.Version {
+		return domain.ErrConflict
+	}
+	if m.userTaken(profile.User.UUID, profile.UUID) {
+		return errors.Wrap(errUniqueViolation, "executes a update query")
+	}
+
+	updated := copyProfile(profile)
+	updated.UserUUID = profile.User.UUID
+	updated.Dob = dateOf(profile.Dob)
+	updated.Version = stored.Version + 1
+	updated.CreatedAt = stored.CreatedAt
+	updated.UpdatedAt = timestampNow()
+	m.profiles[i] = updated
+
+	profile.UserUUID = updated.UserUUID
+	profile.Version = updated.Version
+	profile.UpdatedAt = updated.UpdatedAt
+	return nil
+}
+
+// check validates profile as constraints of profiles do, its user has to exist
+func (m *profileMemoryRepository) check(ctx context.Context, profile *domain.Profile) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if profile.Gender != nil && *profile.Gender != "m" && *profile.Gender != "f" {
+		return errors.Wrap(errCheckViolation, "row scan")
+	}
+
+	user, err := m.userRepo.Find(ctx, profile.User.UUID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.Wrap(errForeignKeyViolation, "row scan")
+	}
+	return nil
+}
+
+func (m *profileMemoryRepository) index(uuid string) int {
+	for i, profile := range m.profiles {
+		if profile.UUID == uuid {
+			return i
+		}
+	}
+	return -1
+}
+
+// userTaken reports whether user has a profile other than uuid
+func (m *profileMemoryRepository) userTaken(userUUID string, uuid string) bool {
+	for _, profile := range m.profiles {
+		if profile.UserUUID == userUUID && profile.UUID != uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// row returns columns of the i-th profile, encrypted columns are NULL as personal data is kept in plain text
+func (m *profileMemoryRepository) row(i int) memoryRow {
+	profile := m.profiles[i]
+	return memoryRow{
+		"uuid":        profile.UUID,
+		"user_uuid":   profile.UserUUID,
+		"first_name":  profile.FirstName,
+		"last_name":   profile.LastName,
+		"address":     profile.Address,
+		"phone":       profile.Phone,
+		"gender":      profile.Gender,
+		"dob":         profile.Dob,
+		"created_at":  profile.CreatedAt,
+		"updated_at":  profile.UpdatedAt,
+		"address_enc": nil,
+		"phone_enc":   nil,
+		"dob_enc":     nil,
+		"phone_bidx":  nil,
+		"key_version": nil,
+	}
+}
+
+// copyProfile returns copy of profile without its user, as profiles are read from the database
+func copyProfile(profile *domain.Profile) *domain.Profile {
+	c := *profile
+	c.User = domain.User{}
+	c.FirstName = copyString(profile.FirstName)
+	c.LastName = copyString(profile.LastName)
+	c.Phone = copyString(profile.Phone)
+	c.Address = copyString(profile.Address)
+	c.Gender = copyString(profile.Gender)
+	c.Dob = copyTime(profile.Dob)
+	return &c
+}
+
+// dateOf returns date of t, as a DATE column keeps it
+func dateOf(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return &date
+}
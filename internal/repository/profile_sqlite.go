@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/envelope"
+)
+
+// profileSqliteRepository reads profiles as profileSqlxRepository does, uuid and timestamps
+// generated by defaults and triggers of postgres are generated on write
+type profileSqliteRepository struct {
+	*profileSqlxRepository
+}
+
+// NewProfileSqliteRepository will create new an profileSqliteRepository object representation of domain.ProfileRepository interface.
+// Personal data is stored in plain text when keyring is nil
+func NewProfileSqliteRepository(conn *sqlx.DB, keyring *envelope.Keyring) domain.ProfileRepository {
+	return &profileSqliteRepository{&profileSqlxRepository{txConn{DB: conn}, keyring, sqliteDialect}}
+}
+
+// NewProfileKeySqliteRepository will create new an profileSqliteRepository object representation of domain.ProfileKeyRepository interface
+func NewProfileKeySqliteRepository(conn *sqlx.DB, keyring *envelope.Keyring) domain.ProfileKeyRepository {
+	return &profileSqliteRepository{&profileSqlxRepository{txConn{DB: conn}, keyring, sqliteDialect}}
+}
+
+func (db *profileSqliteRepository) Store(ctx context.Context, profile *domain.Profile) (*domain.Profile, error) {
+	row, err := db.encrypt(profile.User.UUID, profile)
+	if err != nil {
+		return nil, err
+	}
+	now := timestampNow()
+
+	result := db.conn.QueryRowxContext(ctx, `INSERT INTO profiles (uuid, user_uuid, first_name, last_name, address, phone, gender, dob, address_enc, phone_enc, dob_enc,
+		data_key, key_version, phone_bidx, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING uuid, user_uuid, version, created_at, updated_at`,
+		newUUID(), profile.User.UUID, profile.FirstName, profile.LastName, row.Address, row.Phone, profile.Gender, dateOf(row.Dob),
+		row.AddressEnc, row.PhoneEnc, row.DobEnc, row.DataKey, row.KeyVersion, row.PhoneBidx, now, now)
+
+	if err := result.Scan(&profile.UUID, &profile.UserUUID, &profile.Version, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	return profile, nil
+}
+
+func (db *profileSqliteRepository) Update(ctx context.Context, profile *domain.Profile) error {
+	row, err := db.encrypt(profile.User.UUID, profile)
+	if err != nil {
+		return err
+	}
+
+	result := db.conn.QueryRowxContext(ctx, `UPDATE profiles SET first_name=$1, last_name=$2, address=$3, phone=$4, gender=$5, dob=$6, user_uuid=$7,
+		address_enc=$8, phone_enc=$9, dob_enc=$10, data_key=$11, key_version=$12, phone_bidx=$13, updated_at=$14, version=version+1 WHERE uuid=$15 AND version=$16
+		RETURNING user_uuid, version, updated_at`,
+		profile.FirstName,
+		profile.LastName,
+		row.Address,
+		row.Phone,
+		profile.Gender,
+		dateOf(row.Dob),
+		profile.User.UUID,
+		row.AddressEnc,
+		row.PhoneEnc,
+		row.DobEnc,
+		row.DataKey,
+		row.KeyVersion,
+		row.PhoneBidx,
+		timestampNow(),
+		profile.UUID,
+		profile.Version,
+	)
+
+	if err := result.Scan(&profile.UserUUID, &profile.Version, &profile.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return updateConflict(ctx, db.conn, "profiles", profile.UUID, domain.ErrProfileNotFound)
+		}
+		return errors.Wrap(err, "executes a update query")
+	}
+
+	return nil
+}
@@ -3,26 +3,68 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/envelope"
 )
 
+// dobLayout is format of encrypted date of birth
+const dobLayout = "2006-01-02"
+
+// ErrEncryptedCriteria will throw if records are filtered by value of an encrypted column which has no blind index
+var ErrEncryptedCriteria = errors.New("encrypted column can only be filtered by NULL")
+
+// profileColumns are columns of profiles which may be queried
+var profileColumns = newQueryColumns("uuid", "user_uuid", "first_name", "last_name", "address", "phone", "gender", "dob", "created_at", "updated_at",
+	"address_enc", "phone_enc", "dob_enc", "phone_bidx", "key_version")
+
+// encryptedColumns are columns of profiles which are encrypted when keyring is set
+var encryptedColumns = map[string]bool{"phone": true, "address": true, "dob": true}
+
 type profileSqlxRepository struct {
-	conn *sqlx.DB
+	conn    txConn
+	keyring *envelope.Keyring
+	dialect sqlDialect
+}
+
+// profileRow is a profiles record, phone, address and dob are kept in the *_enc columns
+// encrypted by data_key, which is wrapped by master key of key_version.
+// Rows with NULL key_version are not encrypted yet
+type profileRow struct {
+	domain.Profile
+	PhoneEnc   []byte  `db:"phone_enc"`
+	AddressEnc []byte  `db:"address_enc"`
+	DobEnc     []byte  `db:"dob_enc"`
+	DataKey    []byte  `db:"data_key"`
+	KeyVersion *int    `db:"key_version"`
+	PhoneBidx  *string `db:"phone_bidx"`
+}
+
+// NewProfileSqlxRepository will create new an profileSqlxRepository object representation of domain.ProfileRepository interface.
+// Personal data is stored in plain text when keyring is nil
+func NewProfileSqlxRepository(conn *sqlx.DB, keyring *envelope.Keyring) domain.ProfileRepository {
+	return &profileSqlxRepository{txConn{DB: conn}, keyring, postgresDialect}
+}
+
+// NewProfileSqlxReplicaRepository will create new an profileSqlxRepository object representation of domain.ProfileRepository interface
+// whose reads are routed to replicas, writes and reads requiring read-your-writes use conn
+func NewProfileSqlxReplicaRepository(conn *sqlx.DB, replicas *ReplicaSet, keyring *envelope.Keyring) domain.ProfileRepository {
+	return &profileSqlxRepository{txConn{conn, replicas}, keyring, postgresDialect}
 }
 
-// NewProfileSqlxRepository will create new an profileSqlxRepository object representation of domain.ProfileRepository interface
-func NewProfileSqlxRepository(conn *sqlx.DB) domain.ProfileRepository {
-	return &profileSqlxRepository{conn}
+// NewProfileKeySqlxRepository will create new an profileSqlxRepository object representation of domain.ProfileKeyRepository interface
+func NewProfileKeySqlxRepository(conn *sqlx.DB, keyring *envelope.Keyring) domain.ProfileKeyRepository {
+	return &profileSqlxRepository{txConn{DB: conn}, keyring, postgresDialect}
 }
 
 func (db *profileSqlxRepository) Find(ctx context.Context, uuid string) (*domain.Profile, error) {
-	profile := new(domain.Profile)
-	err := db.conn.GetContext(ctx, profile, `SELECT * FROM profiles WHERE uuid=$1`, uuid)
+	row := new(profileRow)
+	err := db.conn.GetContext(ctx, row, `SELECT * FROM profiles WHERE uuid=$1`, uuid)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -31,16 +73,22 @@ func (db *profileSqlxRepository) Find(ctx context.Context, uuid string) (*domain
 		return nil, err
 	}
 
-	return profile, nil
+	return db.decrypt(row)
 }
 
-func (db *profileSqlxRepository) FindOneBy(ctx context.Context, criteria map[string]interface{}, orderBy *map[string]string) (*domain.Profile, error) {
-	var (
-		profile           = new(domain.Profile)
-		filterQuery, args = filterRecordsQuery(criteria, orderBy)
-	)
+func (db *profileSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.Profile, error) {
+	query, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	filterQuery, args, err := db.dialect.buildQuery(query, profileColumns)
+	if err != nil {
+		return nil, err
+	}
 
-	err := db.conn.GetContext(ctx, profile, `SELECT * FROM profiles WHERE 1=1`+filterQuery, args...)
+	row := new(profileRow)
+	err = db.conn.GetContext(ctx, row, `SELECT * FROM profiles WHERE 1=1`+filterQuery, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -48,50 +96,95 @@ func (db *profileSqlxRepository) FindOneBy(ctx context.Context, criteria map[str
 		return nil, err
 	}
 
-	return profile, nil
+	return db.decrypt(row)
 }
 
 func (db *profileSqlxRepository) FindAll(ctx context.Context) ([]*domain.Profile, error) {
-	var profiles []*domain.Profile
+	var rows []*profileRow
 
-	err := db.conn.SelectContext(ctx, &profiles, `SELECT * FROM profiles`)
+	err := db.conn.SelectContext(ctx, &rows, `SELECT * FROM profiles`)
 	if err != nil {
-		return profiles, err
+		return nil, err
 	}
-	return profiles, nil
+	return db.decryptAll(rows)
 }
 
-func (db *profileSqlxRepository) FindBy(ctx context.Context, criterias map[string]interface{}, orderBy *map[string]string, limit *uint, offset *uint) ([]*domain.Profile, error) {
-	var (
-		profiles          []*domain.Profile
-		filterQuery, args = filterRecordsQuery(criterias, orderBy)
-		offsetAndLimit    string
-	)
+func (db *profileSqlxRepository) FindBy(ctx context.Context, query *domain.Query) ([]*domain.Profile, error) {
+	query, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
 
-	if nil != limit {
-		offsetAndLimit = offsetAndLimit + fmt.Sprintf(" LIMIT %d", *limit)
+	filterQuery, args, err := db.dialect.buildQuery(query, profileColumns)
+	if err != nil {
+		return nil, err
 	}
 
-	if nil != offset {
-		offsetAndLimit = offsetAndLimit + fmt.Sprintf(" OFFSET %d", *offset)
+	var rows []*profileRow
+	err = db.conn.SelectContext(ctx, &rows, `SELECT * FROM profiles WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return nil, err
 	}
+	return db.decryptAll(rows)
+}
 
-	err := db.conn.SelectContext(ctx, &profiles, `SELECT * FROM profiles WHERE 1=1`+filterQuery+offsetAndLimit, args...)
+func (db *profileSqlxRepository) Count(ctx context.Context, query *domain.Query) (int, error) {
+	query, err := db.query(query)
 	if err != nil {
-		return profiles, err
+		return 0, err
 	}
-	return profiles, nil
+
+	filterQuery, args, err := db.dialect.buildWhere(query, profileColumns)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = db.conn.GetContext(ctx, &count, `SELECT COUNT(*) FROM profiles WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindPage returns a page of profiles matching query in (created_at, uuid) order
+func (db *profileSqlxRepository) FindPage(ctx context.Context, query *domain.Query, page domain.PageRequest) ([]*domain.Profile, *domain.Page, error) {
+	profiles, err := db.FindBy(ctx, keysetQuery(query, page, "created_at"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&profiles, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: profiles[i].CreatedAt, UUID: profiles[i].UUID}
+	})
+
+	if page.WithTotal {
+		total, err := db.Count(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return profiles, result, nil
 }
 
 func (db *profileSqlxRepository) Store(ctx context.Context, profile *domain.Profile) (*domain.Profile, error) {
-	stmt, err := db.conn.Prepare("INSERT INTO profiles (user_uuid, first_name, last_name, address, phone, gender, dob) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING uuid, user_uuid, created_at, updated_at")
+	row, err := db.encrypt(profile.User.UUID, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.conn.PrepareContext(ctx, `INSERT INTO profiles (user_uuid, first_name, last_name, address, phone, gender, dob, address_enc, phone_enc, dob_enc, data_key, key_version, phone_bidx)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING uuid, user_uuid, version, created_at, updated_at`)
 	if err != nil {
 		return nil, errors.Wrap(err, "prepare profiles insertion")
 	}
 
-	row := stmt.QueryRow(profile.User.UUID, profile.FirstName, profile.LastName, profile.Address, profile.Phone, profile.Gender, profile.Dob)
+	result := stmt.QueryRowContext(ctx, profile.User.UUID, profile.FirstName, profile.LastName, row.Address, row.Phone, profile.Gender, row.Dob,
+		row.AddressEnc, row.PhoneEnc, row.DobEnc, row.DataKey, row.KeyVersion, row.PhoneBidx)
 
-	if err = row.Scan(&profile.UUID, &profile.UserUUID, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
+	if err = result.Scan(&profile.UUID, &profile.UserUUID, &profile.Version, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
 		if err := stmt.Close(); err != nil {
 			return nil, errors.Wrap(err, "close psql statement")
 		}
@@ -107,24 +200,45 @@ func (db *profileSqlxRepository) Store(ctx context.Context, profile *domain.Prof
 }
 
 func (db *profileSqlxRepository) Update(ctx context.Context, profile *domain.Profile) error {
-	stmt, err := db.conn.PrepareContext(ctx, `UPDATE profiles SET first_name=$1, last_name=$2, address=$3, phone=$4, gender=$5, dob=$6, user_uuid=$7 WHERE uuid=$8`)
+	row, err := db.encrypt(profile.User.UUID, profile)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := db.conn.PrepareContext(ctx, `UPDATE profiles SET first_name=$1, last_name=$2, address=$3, phone=$4, gender=$5, dob=$6, user_uuid=$7,
+		address_enc=$8, phone_enc=$9, dob_enc=$10, data_key=$11, key_version=$12, phone_bidx=$13, version=version+1 WHERE uuid=$14 AND version=$15
+		RETURNING user_uuid, version, updated_at`)
 	if err != nil {
 		return errors.Wrap(err, "prepare profiles update")
 	}
 
-	_, err = stmt.ExecContext(
+	result := stmt.QueryRowContext(
 		ctx,
 		profile.FirstName,
 		profile.LastName,
-		profile.Address,
-		profile.Phone,
+		row.Address,
+		row.Phone,
 		profile.Gender,
-		profile.Dob,
+		row.Dob,
 		profile.User.UUID,
+		row.AddressEnc,
+		row.PhoneEnc,
+		row.DobEnc,
+		row.DataKey,
+		row.KeyVersion,
+		row.PhoneBidx,
 		profile.UUID,
+		profile.Version,
 	)
 
-	if err != nil {
+	if err = result.Scan(&profile.UserUUID, &profile.Version, &profile.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return errors.Wrap(err, "close psql statement")
+		}
+
+		if err == sql.ErrNoRows {
+			return updateConflict(ctx, db.conn, "profiles", profile.UUID, domain.ErrProfileNotFound)
+		}
 		return errors.Wrap(err, "executes a update query")
 	}
 
@@ -132,5 +246,261 @@ func (db *profileSqlxRepository) Update(ctx context.Context, profile *domain.Pro
 		return errors.Wrap(err, "close psql statement")
 	}
 
-	return err
+	return nil
+}
+
+/**
+ * Used to re-encrypt profiles which are not encrypted under current master key. Pseudocode:
+ * - lock a batch of rows of another key version, rows locked by another worker are skipped
+ * - decrypt each row with its own key, or take plain text of row not encrypted yet
+ * - encrypt with a new data key wrapped by current master key
+ * - commit, return number of re-encrypted rows
+ */
+func (db *profileSqlxRepository) ReencryptBatch(ctx context.Context, limit uint) (int, error) {
+	if db.keyring == nil {
+		return 0, nil
+	}
+
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "begin transaction")
+	}
+	defer tx.Rollback()
+
+	var rows []*profileRow
+	err = tx.SelectContext(ctx, &rows, `SELECT * FROM profiles WHERE key_version IS DISTINCT FROM $1 ORDER BY uuid LIMIT $2`+db.dialect.lockRows,
+		db.keyring.CurrentVersion(), limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "select profiles to re-encrypt")
+	}
+
+	for _, row := range rows {
+		profile, err := db.decrypt(row)
+		if err != nil {
+			return 0, errors.Wrapf(err, "decrypt profile %s", row.UUID)
+		}
+
+		encrypted, err := db.encrypt(row.UserUUID, profile)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE profiles SET address=NULL, phone=NULL, dob=NULL,
+			address_enc=$1, phone_enc=$2, dob_enc=$3, data_key=$4, key_version=$5, phone_bidx=$6 WHERE uuid=$7`,
+			encrypted.AddressEnc, encrypted.PhoneEnc, encrypted.DobEnc, encrypted.DataKey, encrypted.KeyVersion, encrypted.PhoneBidx, row.UUID)
+		if err != nil {
+			return 0, errors.Wrap(err, "update re-encrypted profile")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "commit transaction")
+	}
+
+	return len(rows), nil
+}
+
+// query rewrites conditions of encrypted columns, phone is looked up by its blind index.
+// Value of encrypted columns can not be compared except phone equality
+func (db *profileSqlxRepository) query(query *domain.Query) (*domain.Query, error) {
+	if db.keyring == nil || query == nil {
+		return query, nil
+	}
+
+	conditions, err := db.conditions(query.Conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	rewritten := *query
+	rewritten.Conditions = conditions
+	return &rewritten, nil
+}
+
+func (db *profileSqlxRepository) conditions(conditions []domain.Condition) ([]domain.Condition, error) {
+	rewritten := make([]domain.Condition, len(conditions))
+	for i, c := range conditions {
+		var err error
+		if rewritten[i], err = db.condition(c); err != nil {
+			return nil, err
+		}
+	}
+	return rewritten, nil
+}
+
+func (db *profileSqlxRepository) condition(c domain.Condition) (domain.Condition, error) {
+	if c.Operator == domain.OpAnd || c.Operator == domain.OpOr {
+		conditions, err := db.conditions(c.Conditions)
+		if err != nil {
+			return c, err
+		}
+		c.Conditions = conditions
+		return c, nil
+	}
+
+	if !encryptedColumns[c.Column] {
+		return c, nil
+	}
+
+	switch {
+	case c.Operator == domain.OpIsNull:
+		return domain.And(c, domain.IsNull(c.Column+"_enc")), nil
+	case c.Operator == domain.OpIsNotNull:
+		return domain.Or(c, domain.IsNotNull(c.Column+"_enc")), nil
+	case c.Column == "phone" && c.Operator == domain.OpEqual:
+		phone, ok := stringCriteria(c.Value)
+		if !ok {
+			return c, ErrEncryptedCriteria
+		}
+		return domain.Eq("phone_bidx", db.keyring.BlindIndex([]byte(normalizePhone(phone)))), nil
+	case c.Column == "phone" && c.Operator == domain.OpIn:
+		values, _ := c.Value.([]interface{})
+		indexes := make([]interface{}, len(values))
+		for i, value := range values {
+			phone, ok := stringCriteria(value)
+			if !ok {
+				return c, ErrEncryptedCriteria
+			}
+			indexes[i] = db.keyring.BlindIndex([]byte(normalizePhone(phone)))
+		}
+		return domain.In("phone_bidx", indexes...), nil
+	}
+
+	return c, ErrEncryptedCriteria
+}
+
+// encrypt returns row holding encrypted fields of profile, or plain text fields when encryption is disabled
+func (db *profileSqlxRepository) encrypt(userUUID string, profile *domain.Profile) (*profileRow, error) {
+	row := new(profileRow)
+
+	if db.keyring == nil {
+		row.Phone = profile.Phone
+		row.Address = profile.Address
+		row.Dob = profile.Dob
+		return row, nil
+	}
+
+	dataKey, wrapped, err := db.keyring.NewDataKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "create data key")
+	}
+	version := db.keyring.CurrentVersion()
+	row.DataKey = wrapped
+	row.KeyVersion = &version
+
+	seal := func(column string, value *string) ([]byte, error) {
+		if value == nil {
+			return nil, nil
+		}
+		return envelope.Seal(dataKey, []byte(*value), additionalData(userUUID, column))
+	}
+
+	if row.PhoneEnc, err = seal("phone", profile.Phone); err != nil {
+		return nil, errors.Wrap(err, "encrypt phone")
+	}
+	if row.AddressEnc, err = seal("address", profile.Address); err != nil {
+		return nil, errors.Wrap(err, "encrypt address")
+	}
+	if profile.Dob != nil {
+		dob := profile.Dob.Format(dobLayout)
+		if row.DobEnc, err = seal("dob", &dob); err != nil {
+			return nil, errors.Wrap(err, "encrypt dob")
+		}
+	}
+
+	if profile.Phone != nil {
+		index := db.keyring.BlindIndex([]byte(normalizePhone(*profile.Phone)))
+		row.PhoneBidx = &index
+	}
+
+	return row, nil
+}
+
+// decrypt returns profile of row, row which is not encrypted yet is returned as is
+func (db *profileSqlxRepository) decrypt(row *profileRow) (*domain.Profile, error) {
+	profile := row.Profile
+	if row.KeyVersion == nil {
+		return &profile, nil
+	}
+	if db.keyring == nil {
+		return nil, errors.New("profile is encrypted but no encryption key is configured")
+	}
+
+	dataKey, err := db.keyring.UnwrapDataKey(row.DataKey, *row.KeyVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "unwrap data key")
+	}
+
+	open := func(column string, value []byte) (*string, error) {
+		if value == nil {
+			return nil, nil
+		}
+		plaintext, err := envelope.Open(dataKey, value, additionalData(row.UserUUID, column))
+		if err != nil {
+			return nil, err
+		}
+		s := string(plaintext)
+		return &s, nil
+	}
+
+	if profile.Phone, err = open("phone", row.PhoneEnc); err != nil {
+		return nil, errors.Wrap(err, "decrypt phone")
+	}
+	if profile.Address, err = open("address", row.AddressEnc); err != nil {
+		return nil, errors.Wrap(err, "decrypt address")
+	}
+	dob, err := open("dob", row.DobEnc)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypt dob")
+	}
+	profile.Dob = nil
+	if dob != nil {
+		t, err := time.Parse(dobLayout, *dob)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse dob")
+		}
+		profile.Dob = &t
+	}
+
+	return &profile, nil
+}
+
+func (db *profileSqlxRepository) decryptAll(rows []*profileRow) ([]*domain.Profile, error) {
+	profiles := make([]*domain.Profile, 0, len(rows))
+	for _, row := range rows {
+		profile, err := db.decrypt(row)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// additionalData binds ciphertext to its owner and column, so it can not be moved to another row or column
+func additionalData(userUUID string, column string) []byte {
+	return []byte(userUUID + "." + column)
+}
+
+// normalizePhone keeps leading + and digits only, so formatting does not change the blind index
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if (r >= '0' && r <= '9') || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func stringCriteria(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case *string:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return "", false
 }
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// queryColumns is whitelist of columns which may be referenced by a query, any other column is rejected
+type queryColumns map[string]bool
+
+// newQueryColumns will create new a whitelist of the given columns
+func newQueryColumns(columns ...string) queryColumns {
+	allowed := make(queryColumns, len(columns))
+	for _, column := range columns {
+		allowed[column] = true
+	}
+	return allowed
+}
+
+// sqlDialect is how the parts of a query which differ between databases are rendered
+type sqlDialect struct {
+	// like is format of case insensitive LIKE of a column and a placeholder
+	like string
+	// nullsLast sorts NULL as the largest value, as postgres does
+	nullsLast bool
+	// utc binds times in UTC, so they compare as text with the stored times
+	utc bool
+	// lockRows locks selected rows skipping the rows locked by another transaction
+	lockRows string
+}
+
+var (
+	postgresDialect = sqlDialect{like: "%s::text ILIKE %s", lockRows: " FOR UPDATE SKIP LOCKED"}
+	// a sqlite transaction which writes locks the whole database, rows are not locked
+	sqliteDialect = sqlDialect{like: `%s LIKE %s ESCAPE '\'`, nullsLast: true, utc: true}
+)
+
+// queryBuilder render a domain.Query into SQL, values are always bound as arguments
+type queryBuilder struct {
+	dialect sqlDialect
+	columns queryColumns
+	args    []interface{}
+}
+
+// buildQuery returns the " AND ..." conditions, ORDER BY and LIMIT/OFFSET of query and their arguments.
+// Records sorted by a query are also sorted by uuid, so records of equal sort key keep a stable order between pages
+func buildQuery(query *domain.Query, columns queryColumns) (string, []interface{}, error) {
+	return postgresDialect.buildQuery(query, columns)
+}
+
+// buildWhere is buildQuery without sort and pagination, it is used to count records
+func buildWhere(query *domain.Query, columns queryColumns) (string, []interface{}, error) {
+	return postgresDialect.buildWhere(query, columns)
+}
+
+// buildQuery renders query as buildQuery does, in dialect d
+func (d sqlDialect) buildQuery(query *domain.Query, columns queryColumns) (string, []interface{}, error) {
+	if query == nil {
+		return "", nil, nil
+	}
+
+	b := &queryBuilder{dialect: d, columns: columns}
+
+	where, err := b.conditions(query.Conditions, " AND ")
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		where = " AND " + where
+	}
+
+	orderBy, err := b.orderBy(query.Orders)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var offsetAndLimit string
+	if nil != query.Limit {
+		offsetAndLimit = offsetAndLimit + fmt.Sprintf(" LIMIT %d", *query.Limit)
+	}
+	if nil != query.Offset {
+		offsetAndLimit = offsetAndLimit + fmt.Sprintf(" OFFSET %d", *query.Offset)
+	}
+
+	return where + orderBy + offsetAndLimit, b.args, nil
+}
+
+// buildWhere renders query as buildWhere does, in dialect d
+func (d sqlDialect) buildWhere(query *domain.Query, columns queryColumns) (string, []interface{}, error) {
+	if query == nil {
+		return "", nil, nil
+	}
+	return d.buildQuery(&domain.Query{Conditions: query.Conditions}, columns)
+}
+
+func (b *queryBuilder) conditions(conditions []domain.Condition, sep string) (string, error) {
+	parts := make([]string, 0, len(conditions))
+	for _, c := range conditions {
+		part, err := b.condition(c)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func (b *queryBuilder) condition(c domain.Condition) (string, error) {
+	switch c.Operator {
+	case domain.OpAnd, domain.OpOr:
+		if len(c.Conditions) == 0 {
+			if c.Operator == domain.OpOr {
+				return "FALSE", nil
+			}
+			return "TRUE", nil
+		}
+		group, err := b.conditions(c.Conditions, " "+string(c.Operator)+" ")
+		if err != nil {
+			return "", err
+		}
+		return "(" + group + ")", nil
+	}
+
+	if !b.columns[c.Column] {
+		return "", domain.ErrInvalidQuery
+	}
+
+	switch c.Operator {
+	case domain.OpEqual, domain.OpNotEqual, domain.OpGreater, domain.OpGreaterEqual, domain.OpLess, domain.OpLessEqual:
+		if c.Value == nil {
+			return "", domain.ErrInvalidQuery
+		}
+		return fmt.Sprintf("%s %s %s", c.Column, c.Operator, b.bind(c.Value)), nil
+	case domain.OpLike:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return "", domain.ErrInvalidQuery
+		}
+		return fmt.Sprintf(b.dialect.like, c.Column, b.bind(pattern)), nil
+	case domain.OpIn:
+		values, ok := c.Value.([]interface{})
+		if !ok {
+			return "", domain.ErrInvalidQuery
+		}
+		if len(values) == 0 {
+			return "FALSE", nil
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			placeholders[i] = b.bind(v)
+		}
+		return fmt.Sprintf("%s IN (%s)", c.Column, strings.Join(placeholders, ", ")), nil
+	case domain.OpBetween:
+		bounds, ok := c.Value.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return "", domain.ErrInvalidQuery
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", c.Column, b.bind(bounds[0]), b.bind(bounds[1])), nil
+	case domain.OpIsNull, domain.OpIsNotNull:
+		return fmt.Sprintf("%s %s", c.Column, c.Operator), nil
+	}
+
+	return "", domain.ErrInvalidQuery
+}
+
+func (b *queryBuilder) orderBy(orders []domain.Order) (string, error) {
+	if len(orders) == 0 {
+		return "", nil
+	}
+
+	var (
+		parts  = make([]string, 0, len(orders)+1)
+		byUUID bool
+	)
+	for _, o := range orders {
+		if !b.columns[o.Column] {
+			return "", domain.ErrInvalidQuery
+		}
+
+		direction := "ASC"
+		if o.Desc {
+			direction = "DESC"
+		}
+		if b.dialect.nullsLast && o.Desc {
+			direction += " NULLS FIRST"
+		} else if b.dialect.nullsLast {
+			direction += " NULLS LAST"
+		}
+		parts = append(parts, o.Column+" "+direction)
+		byUUID = byUUID || o.Column == "uuid"
+	}
+
+	if !byUUID && b.columns["uuid"] {
+		parts = append(parts, "uuid ASC")
+	}
+
+	return " ORDER BY " + strings.Join(parts, ", "), nil
+}
+
+// bind append value to arguments and returns its placeholder
+func (b *queryBuilder) bind(value interface{}) string {
+	if t, ok := value.(time.Time); ok && b.dialect.utc {
+		value = t.UTC()
+	}
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// replicaCheckTimeout bounds the health check of a replica
+const replicaCheckTimeout = 2 * time.Second
+
+// replicaLagQuery returns replay lag of a postgres standby in seconds. It is 0 on a server which is not in recovery,
+// and when every received WAL has been replayed as replay timestamp of an idle primary does not move
+const replicaLagQuery = `SELECT CASE WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`
+
+// ReplicaSet routes reads to read replicas. A replica is available once its health check succeeds with lag
+// of at most maxLag, reads fall back to the primary when no replica is available
+type ReplicaSet struct {
+	replicas []*replica
+	maxLag   time.Duration
+	next     uint32
+}
+
+type replica struct {
+	conn *sqlx.DB
+
+	mu        sync.RWMutex
+	available bool
+}
+
+// NewReplicaSet will create new a ReplicaSet of replicas, they are not available until checked
+func NewReplicaSet(replicas []*sqlx.DB, maxLag time.Duration) *ReplicaSet {
+	s := &ReplicaSet{maxLag: maxLag}
+	for _, conn := range replicas {
+		s.replicas = append(s.replicas, &replica{conn: conn})
+	}
+	return s
+}
+
+// Run checks replicas every interval until ctx is done
+func (s *ReplicaSet) Run(ctx context.Context, interval time.Duration) {
+	for {
+		s.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Check pings every replica and measures its lag, replicas which can not be reached or lag behind are not read
+func (s *ReplicaSet) Check(ctx context.Context) {
+	for i, r := range s.replicas {
+		lag, err := r.check(ctx)
+		if err == nil && lag > s.maxLag {
+			err = fmt.Errorf("lags %s behind primary", lag)
+		}
+		r.set(i, err)
+	}
+}
+
+// pick returns the next available replica in round robin, nil when there is none
+func (s *ReplicaSet) pick() *replica {
+	if s == nil || len(s.replicas) == 0 {
+		return nil
+	}
+
+	start := int(atomic.AddUint32(&s.next, 1))
+	for i := 0; i < len(s.replicas); i++ {
+		r := s.replicas[(start+i)%len(s.replicas)]
+		if r.isAvailable() {
+			return r
+		}
+	}
+	return nil
+}
+
+// check returns lag of the replica, lag is only measured on postgres
+func (r *replica) check(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	if err := r.conn.PingContext(ctx); err != nil {
+		return 0, err
+	}
+	if r.conn.DriverName() != "postgres" {
+		return 0, nil
+	}
+
+	var seconds float64
+	if err := r.conn.GetContext(ctx, &seconds, replicaLagQuery); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// set records result of a health check, changes of availability are logged
+func (r *replica) set(index int, err error) {
+	r.mu.Lock()
+	changed := r.available != (err == nil)
+	r.available = err == nil
+	r.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Warnf("replica %d is not available: %s", index, err)
+	} else {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Printf("replica %d is available", index)
+	}
+}
+
+// fail marks the replica as not available until its next successful check
+func (r *replica) fail(err error) {
+	r.mu.Lock()
+	changed := r.available
+	r.available = false
+	r.mu.Unlock()
+
+	if changed {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Warnln("replica read failed, reading primary: ", err)
+	}
+}
+
+func (r *replica) isAvailable() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.available
+}
+
+// replicaFailed reports whether a read failed because of the replica rather than the query, it is run again on the primary.
+// Postgres errors are errors of the query except connection exceptions, operator intervention and conflicts with recovery
+func replicaFailed(ctx context.Context, err error) bool {
+	if err == nil || err == sql.ErrNoRows || ctx.Err() != nil {
+		return false
+	}
+
+	if pqErr, ok := err.(*pq.Error); ok {
+		class := pqErr.Code.Class()
+		return class == "08" || class == "57" || pqErr.Code == "40001"
+	}
+	return true
+}
@@ -1,36 +1,57 @@
 package repository
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
 )
 
-func filterRecordsQuery(criterias map[string]interface{}, orderBy *map[string]string) (query string, args []interface{}) {
-	var (
-		orderByCommand string
-		orderByKeyword string
-		argsLen        uint16 = 1
-		whereCondition string
-	)
-
-	for i, criteria := range criterias {
-		if criteria != nil {
-			args = append(args, criteria)
-			whereCondition = whereCondition + fmt.Sprintf(" AND %s=$%d", i, argsLen)
-			argsLen++
-		} else {
-			whereCondition = whereCondition + fmt.Sprintf(" AND %s is NULL", i)
-		}
+// nullTime returns nil for zero time, so the column keeps its value
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
 	}
+	return &t
+}
 
-	if orderBy != nil {
-		orderByCommand = " ORDER BY"
-		for i, ob := range *orderBy {
-			orderByKeyword = orderByKeyword + fmt.Sprintf(" %s %s,", i, ob)
-		}
-		orderByKeyword = strings.TrimSuffix(orderByKeyword, ",")
+// updateConflict explains an optimistic update of table which matched no row, notFound is returned when the row does not exist
+// and domain.ErrConflict when it has been updated since it was read
+func updateConflict(ctx context.Context, conn txConn, table string, uuid string, notFound error) error {
+	var exists bool
+
+	err := conn.GetContext(domain.WithReadYourWrites(ctx), &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE uuid=$1)`, table), uuid)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return notFound
 	}
+	return domain.ErrConflict
+}
 
-	query = query + whereCondition + orderByCommand + orderByKeyword
-	return query, args
+// timestampNow returns current time in UTC of the microsecond precision of a timestamptz column,
+// it is the timestamp of records whose timestamps are not generated by the database
+func timestampNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// newUUID returns a random uuid, as uuid_generate_v4 does
+func newUUID() string {
+	return uuid.New().String()
+}
+
+// newSecurityStamp returns a random security stamp of a new user, as the security_stamp default of users does
+func newSecurityStamp() (string, error) {
+	stamp := make([]byte, 20)
+	if _, err := rand.Read(stamp); err != nil {
+		return "", errors.Wrap(err, "generate security stamp")
+	}
+	return hex.EncodeToString(stamp), nil
 }
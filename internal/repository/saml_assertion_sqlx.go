@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type samlAssertionSqlxRepository struct {
+	conn txConn
+}
+
+// NewSamlAssertionSqlxRepository will create new an samlAssertionSqlxRepository object representation of domain.SamlAssertionRepository interface
+func NewSamlAssertionSqlxRepository(conn *sqlx.DB) domain.SamlAssertionRepository {
+	return &samlAssertionSqlxRepository{txConn{DB: conn}}
+}
+
+// Store records the assertion once, expired assertions are removed meanwhile as they are rejected anyway
+func (db *samlAssertionSqlxRepository) Store(ctx context.Context, providerUUID string, assertionID string, expiresAt time.Time) (bool, error) {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM saml_assertions WHERE expires_at < $1`, timestampNow())
+	if err != nil {
+		return false, errors.Wrap(err, "delete saml_assertions")
+	}
+
+	result, err := db.conn.ExecContext(ctx, `INSERT INTO saml_assertions (provider_uuid, assertion_id, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, providerUUID, assertionID, expiresAt.UTC())
+	if err != nil {
+		return false, errors.Wrap(err, "insert saml_assertions")
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
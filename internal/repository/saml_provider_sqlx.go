@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// samlProviderColumns are columns of saml_providers which may be queried
+var samlProviderColumns = newQueryColumns("uuid", "tenant_uuid", "email_domain", "created_at", "updated_at")
+
+type samlProviderSqlxRepository struct {
+	conn txConn
+}
+
+// NewSamlProviderSqlxRepository will create new an samlProviderSqlxRepository object representation of domain.SamlProviderRepository interface
+func NewSamlProviderSqlxRepository(conn *sqlx.DB) domain.SamlProviderRepository {
+	return &samlProviderSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *samlProviderSqlxRepository) Find(ctx context.Context, uuid string) (*domain.SamlProvider, error) {
+	provider := new(domain.SamlProvider)
+	err := db.conn.GetContext(ctx, provider, `SELECT * FROM saml_providers WHERE uuid=$1`, uuid)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+func (db *samlProviderSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.SamlProvider, error) {
+	filterQuery, args, err := buildQuery(query, samlProviderColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := new(domain.SamlProvider)
+	err = db.conn.GetContext(ctx, provider, `SELECT * FROM saml_providers WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// tenantColumns are columns of tenants which may be queried
+var tenantColumns = newQueryColumns("uuid", "name", "scim_token", "password_history_size", "password_max_age_days", "created_at", "updated_at")
+
+type tenantSqlxRepository struct {
+	conn txConn
+}
+
+// NewTenantSqlxRepository will create new an tenantSqlxRepository object representation of domain.TenantRepository interface
+func NewTenantSqlxRepository(conn *sqlx.DB) domain.TenantRepository {
+	return &tenantSqlxRepository{txConn{DB: conn}}
+}
+
+func (db *tenantSqlxRepository) Find(ctx context.Context, uuid string) (*domain.Tenant, error) {
+	tenant := new(domain.Tenant)
+	err := db.conn.GetContext(ctx, tenant, `SELECT * FROM tenants WHERE uuid=$1`, uuid)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+func (db *tenantSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.Tenant, error) {
+	filterQuery, args, err := buildQuery(query, tenantColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := new(domain.Tenant)
+	err = db.conn.GetContext(ctx, tenant, `SELECT * FROM tenants WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+func (db *tenantSqlxRepository) Store(ctx context.Context, tenant *domain.Tenant) (*domain.Tenant, error) {
+	stmt, err := db.conn.PrepareContext(ctx, "INSERT INTO tenants (name, scim_token) VALUES ($1, $2) RETURNING uuid, created_at, updated_at")
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare tenants insertion")
+	}
+
+	row := stmt.QueryRowContext(ctx, tenant.Name, tenant.ScimToken)
+
+	if err = row.Scan(&tenant.UUID, &tenant.CreatedAt, &tenant.UpdatedAt); err != nil {
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	if err := stmt.Close(); err != nil {
+		return nil, errors.Wrap(err, "close psql statement")
+	}
+
+	return tenant, err
+}
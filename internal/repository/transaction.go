@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// txRetries is number of times a transaction aborted by a serialization failure or deadlock is run again
+const txRetries = 3
+
+// txKey is context key of the transaction of a unit of work
+type txKey struct{}
+
+// afterCommitKey is context key of functions run once the transaction of a unit of work is committed
+type afterCommitKey struct{}
+
+type sqlxTransactor struct {
+	conn      *sqlx.DB
+	isolation sql.IsolationLevel
+}
+
+// NewSqlxTransactor will create new an sqlxTransactor object representation of domain.Transactor interface.
+// Transactions are run at isolation level, a transaction aborted by a concurrent one is retried
+func NewSqlxTransactor(conn *sqlx.DB, isolation sql.IsolationLevel) domain.Transactor {
+	return &sqlxTransactor{conn: conn, isolation: isolation}
+}
+
+func (t *sqlxTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := t.run(ctx, fn)
+		if attempt < txRetries && retryable(err) {
+			continue
+		}
+		return err
+	}
+}
+
+// run fn in a new transaction, error of fn is returned as is so domain errors are kept
+func (t *sqlxTransactor) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := t.conn.BeginTxx(ctx, &sql.TxOptions{Isolation: t.isolation})
+	if err != nil {
+		return errors.Wrap(err, "begin transaction")
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	hooks := new([]func())
+	if err = fn(context.WithValue(context.WithValue(ctx, txKey{}, tx), afterCommitKey{}, hooks)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "commit transaction")
+	}
+	for _, hook := range *hooks {
+		hook()
+	}
+	return nil
+}
+
+// afterCommit runs fn once the transaction of ctx is committed, or right away when ctx has no transaction.
+// fn is not run when the transaction is rolled back
+func afterCommit(ctx context.Context, fn func()) {
+	if hooks, ok := ctx.Value(afterCommitKey{}).(*[]func()); ok {
+		*hooks = append(*hooks, fn)
+		return
+	}
+	fn()
+}
+
+// retryable reports whether err is a serialization failure or deadlock, the transaction may succeed when run again
+func retryable(err error) bool {
+	pqErr, ok := errors.Cause(err).(*pq.Error)
+	if !ok {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// executor is the part of sqlx.DB and sqlx.Tx used by repositories
+type executor interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// txConn runs statements on the transaction carried by context, or on the database when context has none.
+// Reads outside a transaction are routed to replicas, unless ctx requires read-your-writes or there is no available replica.
+// Methods not defined here, like BeginTxx, always use the database
+type txConn struct {
+	*sqlx.DB
+	replicas *ReplicaSet
+}
+
+func (c txConn) executor(ctx context.Context) executor {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return c.DB
+}
+
+// reader returns replica reads of ctx are routed to, it is nil when they run on executor
+func (c txConn) reader(ctx context.Context) *replica {
+	if ctx.Value(txKey{}) != nil || domain.ReadYourWritesFromContext(ctx) {
+		return nil
+	}
+	return c.replicas.pick()
+}
+
+func (c txConn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if r := c.reader(ctx); r != nil {
+		err := r.conn.GetContext(ctx, dest, query, args...)
+		if !replicaFailed(ctx, err) {
+			return err
+		}
+		r.fail(err)
+	}
+	return c.executor(ctx).GetContext(ctx, dest, query, args...)
+}
+
+func (c txConn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if r := c.reader(ctx); r != nil {
+		err := r.conn.SelectContext(ctx, dest, query, args...)
+		if !replicaFailed(ctx, err) {
+			return err
+		}
+		r.fail(err)
+
+		// rows scanned before the failure are dropped, the primary reads them again
+		v := reflect.ValueOf(dest).Elem()
+		v.Set(reflect.Zero(v.Type()))
+	}
+	return c.executor(ctx).SelectContext(ctx, dest, query, args...)
+}
+
+func (c txConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.executor(ctx).ExecContext(ctx, query, args...)
+}
+
+func (c txConn) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return c.executor(ctx).QueryRowxContext(ctx, query, args...)
+}
+
+func (c txConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.executor(ctx).PrepareContext(ctx, query)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// userCacheRepository caches users found by Find and FindOneBy, other reads go to the decorated repository
+type userCacheRepository struct {
+	domain.UserRepository
+	layer *cacheLayer
+}
+
+// NewUserCacheRepository will create new an userCacheRepository object representation of domain.UserRepository interface.
+// Users read from next are kept in cache, changes are published by publisher as origin
+func NewUserCacheRepository(next domain.UserRepository, cache domain.Cache, publisher domain.Publisher, origin string) domain.UserRepository {
+	return &userCacheRepository{next, newCacheLayer(cache, publisher, origin)}
+}
+
+func (r *userCacheRepository) Find(ctx context.Context, uuid string) (*domain.User, error) {
+	return r.load(ctx, "user:"+uuid, func(ctx context.Context) (*domain.User, error) {
+		return r.UserRepository.Find(ctx, uuid)
+	})
+}
+
+func (r *userCacheRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.User, error) {
+	key, ok := cacheKey("user:query:", query, userColumns)
+	if !ok {
+		return r.UserRepository.FindOneBy(ctx, query)
+	}
+
+	return r.load(ctx, key, func(ctx context.Context) (*domain.User, error) {
+		return r.UserRepository.FindOneBy(ctx, query)
+	})
+}
+
+func (r *userCacheRepository) Store(ctx context.Context, user *domain.User) (*domain.User, error) {
+	stored, err := r.UserRepository.Store(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	r.layer.invalidate(ctx, userTag(stored.UUID))
+	return stored, nil
+}
+
+func (r *userCacheRepository) Update(ctx context.Context, user *domain.User) (*domain.User, error) {
+	updated, err := r.UserRepository.Update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	r.layer.invalidate(ctx, userTag(updated.UUID))
+	return updated, nil
+}
+
+// load returns copy of the cached user, usecases change users they find
+func (r *userCacheRepository) load(ctx context.Context, key string, fn func(ctx context.Context) (*domain.User, error)) (*domain.User, error) {
+	value, err := r.layer.load(ctx, key, func(value interface{}) []string {
+		return []string{userTag(value.(*domain.User).UUID)}
+	}, func(ctx context.Context) (interface{}, error) {
+		user, err := fn(ctx)
+		if err != nil || user == nil {
+			return nil, err
+		}
+		return copyUser(user), nil
+	})
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return copyUser(value.(*domain.User)), nil
+}
+
+// userTag is cache tag of entries holding user of uuid
+func userTag(uuid string) string {
+	return "user:" + uuid
+}
@@ -0,0 +1,168 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/cache"
+	"github.com/wicaker/user/internal/repository"
+)
+
+// countingUserRepository counts lookups reaching the decorated repository
+type countingUserRepository struct {
+	domain.UserRepository
+	finds int32
+}
+
+func (r *countingUserRepository) Find(ctx context.Context, uuid string) (*domain.User, error) {
+	atomic.AddInt32(&r.finds, 1)
+	time.Sleep(time.Millisecond * 10)
+	return r.UserRepository.Find(ctx, uuid)
+}
+
+// recordingPublisher keeps published cache invalidation events
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []domain.CacheInvalidation
+}
+
+func (p *recordingPublisher) Publish(message string, routingKey string, header map[string]interface{}) error {
+	var event domain.CacheInvalidation
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func storeUsers(t *testing.T, userRepo domain.UserRepository, emails ...string) []*domain.User {
+	users := make([]*domain.User, len(emails))
+	for i, email := range emails {
+		user, err := userRepo.Store(context.TODO(), &domain.User{Email: email, Password: "hashed"})
+		require.NoError(t, err)
+		users[i] = user
+	}
+	return users
+}
+
+func TestUserCacheRepository(t *testing.T) {
+	var (
+		next      = &countingUserRepository{UserRepository: repository.NewUserMemoryRepository()}
+		lru       = cache.NewLRU(100, time.Minute)
+		publisher = new(recordingPublisher)
+		userRepo  = repository.NewUserCacheRepository(next, lru, publisher, "replica-a")
+	)
+
+	users := storeUsers(t, userRepo, "alice@mail.com")
+	require.Len(t, publisher.events, 1)
+
+	t.Run("success concurrent misses load once", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				user, err := userRepo.Find(context.TODO(), users[0].UUID)
+				assert.NoError(t, err)
+				assert.NotNil(t, user)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&next.finds))
+	})
+
+	t.Run("success cached user is copied", func(t *testing.T) {
+		user, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		user.Email = "changed@mail.com"
+
+		found, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@mail.com", found.Email)
+	})
+
+	t.Run("success update invalidates and publishes", func(t *testing.T) {
+		byEmail := domain.Where(domain.Eq("email", "alice@mail.com"))
+		user, err := userRepo.FindOneBy(context.TODO(), byEmail)
+		require.NoError(t, err)
+
+		user.IsActive = true
+		_, err = userRepo.Update(context.TODO(), user)
+		require.NoError(t, err)
+
+		found, err := userRepo.FindOneBy(context.TODO(), byEmail)
+		require.NoError(t, err)
+		assert.True(t, found.IsActive)
+		assert.Equal(t, 2, found.Version)
+
+		require.Len(t, publisher.events, 2)
+		assert.Equal(t, domain.CacheInvalidation{Origin: "replica-a", Tags: []string{"user:" + users[0].UUID}}, publisher.events[1])
+	})
+
+	t.Run("success invalidation of another replica", func(t *testing.T) {
+		_, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+
+		user, err := next.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		user.Role = domain.RoleAdmin
+		_, err = next.Update(context.TODO(), user)
+		require.NoError(t, err)
+
+		found, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleUser, found.Role)
+
+		lru.Invalidate("user:" + users[0].UUID)
+
+		found, err = userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleAdmin, found.Role)
+	})
+
+	t.Run("success read-your-writes bypasses the cache", func(t *testing.T) {
+		// another replica changes the user and its invalidation is lost
+		other := repository.NewUserCacheRepository(next, cache.NewLRU(100, time.Minute), nil, "replica-b")
+		_, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+
+		user, err := other.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		user.SecurityStamp = "rotated"
+		_, err = other.Update(context.TODO(), user)
+		require.NoError(t, err)
+
+		found, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		assert.NotEqual(t, "rotated", found.SecurityStamp)
+
+		found, err = userRepo.Find(domain.WithReadYourWrites(context.TODO()), users[0].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", found.SecurityStamp)
+	})
+
+	t.Run("success missing user is not cached", func(t *testing.T) {
+		byEmail := domain.Where(domain.Eq("email", "bob@mail.com"))
+		found, err := userRepo.FindOneBy(context.TODO(), byEmail)
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		storeUsers(t, userRepo, "bob@mail.com")
+
+		found, err = userRepo.FindOneBy(context.TODO(), byEmail)
+		require.NoError(t, err)
+		assert.NotNil(t, found)
+	})
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// userMemoryRepository keeps users in memory, it does not join the unit of work of context
+type userMemoryRepository struct {
+	mu    sync.RWMutex
+	users []*domain.User
+}
+
+// NewUserMemoryRepository will create new an userMemoryRepository object representation of domain.UserRepository interface.
+// It behaves as the users table: uuid, security stamp, timestamps and version are generated and email is unique
+func NewUserMemoryRepository() domain.UserRepository {
+	return &userMemoryRepository{}
+}
+
+func (m *userMemoryRepository) Find(ctx context.Context, uuid string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if i := m.index(uuid); i >= 0 {
+		return copyUser(m.users[i]), nil
+	}
+	return nil, nil
+}
+
+func (m *userMemoryRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.User, error) {
+	users, err := m.FindBy(ctx, query)
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+	return users[0], nil
+}
+
+func (m *userMemoryRepository) FindAll(ctx context.Context) ([]*domain.User, error) {
+	return m.FindBy(ctx, nil)
+}
+
+func (m *userMemoryRepository) FindBy(ctx context.Context, query *domain.Query) ([]*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched, err := selectRows(len(m.users), m.row, query, userColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []*domain.User
+	for _, i := range matched {
+		users = append(users, copyUser(m.users[i]))
+	}
+	return users, nil
+}
+
+func (m *userMemoryRepository) Count(ctx context.Context, query *domain.Query) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return countRows(len(m.users), m.row, query, userColumns)
+}
+
+// FindPage returns a page of users matching query in (created_at, uuid) order
+func (m *userMemoryRepository) FindPage(ctx context.Context, query *domain.Query, page domain.PageRequest) ([]*domain.User, *domain.Page, error) {
+	users, err := m.FindBy(ctx, keysetQuery(query, page, "created_at"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&users, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: users[i].CreatedAt, UUID: users[i].UUID}
+	})
+
+	if page.WithTotal {
+		total, err := m.Count(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return users, result, nil
+}
+
+func (m *userMemoryRepository) Store(ctx context.Context, user *domain.User) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if user.Email == "" || user.Password == "" {
+		return nil, errors.Wrap(errCheckViolation, "row scan")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.emailTaken(user.Email, "") {
+		return nil, errors.Wrap(errUniqueViolation, "row scan")
+	}
+
+	securityStamp, err := newSecurityStamp()
+	if err != nil {
+		return nil, err
+	}
+	now := timestampNow()
+	stored := &domain.User{
+		UUID:              newUUID(),
+		Email:             user.Email,
+		Password:          user.Password,
+		Role:              domain.RoleUser,
+		TenantUUID:        copyString(user.TenantUUID),
+		ExternalID:        copyString(user.ExternalID),
+		SecurityStamp:     securityStamp,
+		PasswordChangedAt: now,
+		Version:           1,
+		UpdatedAt:         now,
+		CreatedAt:         now,
+	}
+	m.users = append(m.users, stored)
+
+	user.UUID = stored.UUID
+	user.SecurityStamp = stored.SecurityStamp
+	user.Role = stored.Role
+	user.PasswordChangedAt = stored.PasswordChangedAt
+	user.Version = stored.Version
+	user.CreatedAt = stored.CreatedAt
+	user.UpdatedAt = stored.UpdatedAt
+	return user, nil
+}
+
+// Update replaces the stored user, security stamp is kept unless user has one
+func (m *userMemoryRepository) Update(ctx context.Context, user *domain.User) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if user.Email == "" || user.Password == "" {
+		return nil, errors.Wrap(errCheckViolation, "row scan")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(user.UUID)
+	if i < 0 {
+		return nil, domain.ErrUserNotFound
+	}
+	stored := m.users[i]
+	if stored.Version != user.Version {
+		return nil, domain.ErrConflict
+	}
+	if m.emailTaken(user.Email, user.UUID) {
+		return nil, errors.Wrap(errUniqueViolation, "row scan")
+	}
+
+	updated := copyUser(stored)
+	updated.Email = user.Email
+	updated.Password = user.Password
+	updated.IsActive = user.IsActive
+	updated.NewPassword = copyString(user.NewPassword)
+	if user.Role != "" {
+		updated.Role = user.Role
+	}
+	updated.TenantUUID = copyString(user.TenantUUID)
+	updated.ExternalID = copyString(user.ExternalID)
+	if !user.PasswordChangedAt.IsZero() {
+		updated.PasswordChangedAt = user.PasswordChangedAt.UTC().Truncate(time.Microsecond)
+	}
+	if user.SecurityStamp != "" {
+		updated.SecurityStamp = user.SecurityStamp
+	}
+	updated.Version++
+	updated.UpdatedAt = timestampNow()
+	m.users[i] = updated
+
+	user.SecurityStamp = updated.SecurityStamp
+	user.Role = updated.Role
+	user.PasswordChangedAt = updated.PasswordChangedAt
+	user.Version = updated.Version
+	user.CreatedAt = updated.CreatedAt
+	user.UpdatedAt = updated.UpdatedAt
+	return user, nil
+}
+
+func (m *userMemoryRepository) index(uuid string) int {
+	for i, user := range m.users {
+		if user.UUID == uuid {
+			return i
+		}
+	}
+	return -1
+}
+
+// emailTaken reports whether email belongs to a user other than uuid
+func (m *userMemoryRepository) emailTaken(email string, uuid string) bool {
+	for _, user := range m.users {
+		if user.Email == email && user.UUID != uuid {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *userMemoryRepository) row(i int) memoryRow {
+	user := m.users[i]
+	return memoryRow{
+		"uuid":                user.UUID,
+		"email":               user.Email,
+		"password":            user.Password,
+		"new_password":        user.NewPassword,
+		"is_active":           user.IsActive,
+		"role":                user.Role,
+		"tenant_uuid":         user.TenantUUID,
+		"external_id":         user.ExternalID,
+		"security_stamp":      user.SecurityStamp,
+		"password_changed_at": user.PasswordChangedAt,
+		"created_at":          user.CreatedAt,
+		"updated_at":          user.UpdatedAt,
+	}
+}
+
+func copyUser(user *domain.User) *domain.User {
+	c := *user
+	c.NewPassword = copyString(user.NewPassword)
+	c.TenantUUID = copyString(user.TenantUUID)
+	c.ExternalID = copyString(user.ExternalID)
+	c.Consents = nil
+	return &c
+}
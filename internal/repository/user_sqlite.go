@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// userSqliteRepository reads users as userSqlxRepository does, uuid, security stamp and timestamps
+// generated by defaults and triggers of postgres are generated on write
+type userSqliteRepository struct {
+	*userSqlxRepository
+}
+
+// NewUserSqliteRepository will create new an userSqliteRepository object representation of domain.UserRepository interface
+func NewUserSqliteRepository(conn *sqlx.DB) domain.UserRepository {
+	return &userSqliteRepository{&userSqlxRepository{txConn{DB: conn}, sqliteDialect}}
+}
+
+func (db *userSqliteRepository) Store(ctx context.Context, user *domain.User) (*domain.User, error) {
+	securityStamp, err := newSecurityStamp()
+	if err != nil {
+		return nil, err
+	}
+	now := timestampNow()
+
+	row := db.conn.QueryRowxContext(ctx, `INSERT INTO users (uuid, email, password, tenant_uuid, external_id, security_stamp, password_changed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING uuid, security_stamp, role, password_changed_at, version, created_at, updated_at`,
+		newUUID(), user.Email, user.Password, user.TenantUUID, user.ExternalID, securityStamp, now, now, now)
+
+	if err := row.Scan(&user.UUID, &user.SecurityStamp, &user.Role, &user.PasswordChangedAt, &user.Version, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	return user, nil
+}
+
+func (db *userSqliteRepository) Update(ctx context.Context, user *domain.User) (*domain.User, error) {
+	var passwordChangedAt *time.Time
+	if !user.PasswordChangedAt.IsZero() {
+		t := user.PasswordChangedAt.UTC().Truncate(time.Microsecond)
+		passwordChangedAt = &t
+	}
+
+	row := db.conn.QueryRowxContext(ctx, `UPDATE users SET email=$1, password=$2, is_active=$3, new_password=$4, role=COALESCE(NULLIF($5, ''), role), tenant_uuid=$6,
+		external_id=$7, password_changed_at=COALESCE($8, password_changed_at), security_stamp=COALESCE(NULLIF($9, ''), security_stamp), updated_at=$10, version=version+1 WHERE uuid=$11 AND version=$12
+		RETURNING uuid, security_stamp, role, password_changed_at, version, created_at, updated_at`,
+		user.Email,
+		user.Password,
+		user.IsActive,
+		user.NewPassword,
+		user.Role,
+		user.TenantUUID,
+		user.ExternalID,
+		passwordChangedAt,
+		user.SecurityStamp,
+		timestampNow(),
+		user.UUID,
+		user.Version,
+	)
+
+	if err := row.Scan(&user.UUID, &user.SecurityStamp, &user.Role, &user.PasswordChangedAt, &user.Version, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, updateConflict(ctx, db.conn, "users", user.UUID, domain.ErrUserNotFound)
+		}
+		return nil, errors.Wrap(err, "row scan")
+	}
+
+	return user, nil
+}
@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
@@ -11,13 +10,24 @@ import (
 	"github.com/wicaker/user/internal/domain"
 )
 
+// userColumns are columns of users which may be queried
+var userColumns = newQueryColumns("uuid", "email", "password", "new_password", "is_active", "role", "tenant_uuid", "external_id", "security_stamp",
+	"password_changed_at", "created_at", "updated_at")
+
 type userSqlxRepository struct {
-	conn *sqlx.DB
+	conn    txConn
+	dialect sqlDialect
 }
 
 // NewUserSqlxRepository will create new an userSqlxRepository object representation of domain.UserRepository interface
 func NewUserSqlxRepository(conn *sqlx.DB) domain.UserRepository {
-	return &userSqlxRepository{conn}
+	return &userSqlxRepository{txConn{DB: conn}, postgresDialect}
+}
+
+// NewUserSqlxReplicaRepository will create new an userSqlxRepository object representation of domain.UserRepository interface
+// whose reads are routed to replicas, writes and reads requiring read-your-writes use conn
+func NewUserSqlxReplicaRepository(conn *sqlx.DB, replicas *ReplicaSet) domain.UserRepository {
+	return &userSqlxRepository{txConn{conn, replicas}, postgresDialect}
 }
 
 func (db *userSqlxRepository) Find(ctx context.Context, uuid string) (*domain.User, error) {
@@ -34,13 +44,14 @@ func (db *userSqlxRepository) Find(ctx context.Context, uuid string) (*domain.Us
 	return user, nil
 }
 
-func (db *userSqlxRepository) FindOneBy(ctx context.Context, criteria map[string]interface{}, orderBy *map[string]string) (*domain.User, error) {
-	var (
-		user              = new(domain.User)
-		filterQuery, args = filterRecordsQuery(criteria, orderBy)
-	)
+func (db *userSqlxRepository) FindOneBy(ctx context.Context, query *domain.Query) (*domain.User, error) {
+	filterQuery, args, err := db.dialect.buildQuery(query, userColumns)
+	if err != nil {
+		return nil, err
+	}
 
-	err := db.conn.GetContext(ctx, user, `SELECT * FROM users WHERE 1=1`+filterQuery, args...)
+	user := new(domain.User)
+	err = db.conn.GetContext(ctx, user, `SELECT * FROM users WHERE 1=1`+filterQuery, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -61,37 +72,67 @@ func (db *userSqlxRepository) FindAll(ctx context.Context) ([]*domain.User, erro
 	return users, nil
 }
 
-func (db *userSqlxRepository) FindBy(ctx context.Context, criterias map[string]interface{}, orderBy *map[string]string, limit *uint, offset *uint) ([]*domain.User, error) {
-	var (
-		users             []*domain.User
-		filterQuery, args = filterRecordsQuery(criterias, orderBy)
-		offsetAndLimit    string
-	)
-
-	if nil != limit {
-		offsetAndLimit = offsetAndLimit + fmt.Sprintf(" LIMIT %d", *limit)
-	}
+func (db *userSqlxRepository) FindBy(ctx context.Context, query *domain.Query) ([]*domain.User, error) {
+	var users []*domain.User
 
-	if nil != offset {
-		offsetAndLimit = offsetAndLimit + fmt.Sprintf(" OFFSET %d", *offset)
+	filterQuery, args, err := db.dialect.buildQuery(query, userColumns)
+	if err != nil {
+		return users, err
 	}
 
-	err := db.conn.SelectContext(ctx, &users, `SELECT * FROM users WHERE 1=1`+filterQuery+offsetAndLimit, args...)
+	err = db.conn.SelectContext(ctx, &users, `SELECT * FROM users WHERE 1=1`+filterQuery, args...)
 	if err != nil {
 		return users, err
 	}
 	return users, nil
 }
 
+func (db *userSqlxRepository) Count(ctx context.Context, query *domain.Query) (int, error) {
+	var count int
+
+	filterQuery, args, err := db.dialect.buildWhere(query, userColumns)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.conn.GetContext(ctx, &count, `SELECT COUNT(*) FROM users WHERE 1=1`+filterQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindPage returns a page of users matching query in (created_at, uuid) order
+func (db *userSqlxRepository) FindPage(ctx context.Context, query *domain.Query, page domain.PageRequest) ([]*domain.User, *domain.Page, error) {
+	users, err := db.FindBy(ctx, keysetQuery(query, page, "created_at"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := pageOf(&users, page, func(i int) domain.Cursor {
+		return domain.Cursor{Time: users[i].CreatedAt, UUID: users[i].UUID}
+	})
+
+	if page.WithTotal {
+		total, err := db.Count(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Total = &total
+	}
+
+	return users, result, nil
+}
+
 func (db *userSqlxRepository) Store(ctx context.Context, user *domain.User) (*domain.User, error) {
-	stmt, err := db.conn.PrepareContext(ctx, "INSERT INTO users (email, password) VALUES ($1, $2) RETURNING uuid, salt, created_at, updated_at")
+	stmt, err := db.conn.PrepareContext(ctx, "INSERT INTO users (email, password, tenant_uuid, external_id) VALUES ($1, $2, $3, $4) RETURNING uuid, security_stamp, role, password_changed_at, version, created_at, updated_at")
 	if err != nil {
 		return nil, errors.Wrap(err, "prepare users insertion")
 	}
 
-	row := stmt.QueryRow(user.Email, user.Password)
+	row := stmt.QueryRow(user.Email, user.Password, user.TenantUUID, user.ExternalID)
 
-	if err = row.Scan(&user.UUID, &user.Salt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err = row.Scan(&user.UUID, &user.SecurityStamp, &user.Role, &user.PasswordChangedAt, &user.Version, &user.CreatedAt, &user.UpdatedAt); err != nil {
 		if err := stmt.Close(); err != nil {
 			return nil, errors.Wrap(err, "close psql statement")
 		}
@@ -107,7 +148,9 @@ func (db *userSqlxRepository) Store(ctx context.Context, user *domain.User) (*do
 }
 
 func (db *userSqlxRepository) Update(ctx context.Context, user *domain.User) (*domain.User, error) {
-	stmt, err := db.conn.PrepareContext(ctx, `UPDATE users SET email=$1 , password=$2, is_active=$3, new_password=$4 WHERE uuid=$5 RETURNING uuid, salt, created_at, updated_at`)
+	stmt, err := db.conn.PrepareContext(ctx, `UPDATE users SET email=$1 , password=$2, is_active=$3, new_password=$4, role=COALESCE(NULLIF($5, ''), role), tenant_uuid=$6, external_id=$7,
+		password_changed_at=COALESCE($8, password_changed_at), security_stamp=COALESCE(NULLIF($9, ''), security_stamp), version=version+1 WHERE uuid=$10 AND version=$11
+		RETURNING uuid, security_stamp, role, password_changed_at, version, created_at, updated_at`)
 	if err != nil {
 		return nil, errors.Wrap(err, "prepare users update")
 	}
@@ -117,14 +160,23 @@ func (db *userSqlxRepository) Update(ctx context.Context, user *domain.User) (*d
 		user.Password,
 		user.IsActive,
 		user.NewPassword,
+		user.Role,
+		user.TenantUUID,
+		user.ExternalID,
+		nullTime(user.PasswordChangedAt),
+		user.SecurityStamp,
 		user.UUID,
+		user.Version,
 	)
 
-	if err = row.Scan(&user.UUID, &user.Salt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err = row.Scan(&user.UUID, &user.SecurityStamp, &user.Role, &user.PasswordChangedAt, &user.Version, &user.CreatedAt, &user.UpdatedAt); err != nil {
 		if err := stmt.Close(); err != nil {
 			return nil, errors.Wrap(err, "close psql statement")
 		}
 
+		if err == sql.ErrNoRows {
+			return nil, updateConflict(ctx, db.conn, "users", user.UUID, domain.ErrUserNotFound)
+		}
 		return nil, errors.Wrap(err, "row scan")
 	}
 
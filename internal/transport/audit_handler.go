@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+)
+
+// AuditHandler represent the httphandler for audit events
+type AuditHandler struct {
+	AuditUsecase domain.AuditUsecase
+	paginator    *paginator
+}
+
+// NewAuditHandler will initialize the audit events endpoint
+func NewAuditHandler(e *echo.Echo, u domain.AuditUsecase, p *paginator) {
+	handler := &AuditHandler{
+		AuditUsecase: u,
+		paginator:    p,
+	}
+
+	e.GET("/audit-events", handler.Fetch)
+	e.GET("/audit-events/verify", handler.Verify)
+}
+
+// Fetch will handle admin query of audit events.
+// Query params: from and to (RFC3339), action (comma separated or repeated), actor, target, limit, after, before and total
+func (ah *AuditHandler) Fetch(c echo.Context) error {
+	filter, err := auditEventFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	page, err := ah.paginator.pageRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	events, result, err := ah.AuditUsecase.Fetch(ctx, filter, page, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData, err := ah.paginator.pageData("audit_events", events, result)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, domain.Response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Audit events", Data: respData})
+}
+
+// Verify will handle admin request to check the hash chain of audit events
+func (ah *AuditHandler) Verify(c echo.Context) error {
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	result, err := ah.AuditUsecase.Verify(ctx, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"verification": result,
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Audit chain verification", Data: respData})
+}
+
+func auditEventFilter(c echo.Context) (domain.AuditEventFilter, error) {
+	var filter domain.AuditEventFilter
+
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := c.QueryParam(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, errors.New("Invalid " + param.name + ", expected RFC3339 time")
+		}
+		*param.dest = &t
+	}
+
+	for _, action := range c.QueryParams()["action"] {
+		filter.Actions = append(filter.Actions, splitQueryList(action)...)
+	}
+
+	for _, param := range []struct {
+		name string
+		dest *string
+	}{{"actor", &filter.ActorUUID}, {"target", &filter.TargetUUID}} {
+		value := c.QueryParam(param.name)
+		if value == "" {
+			continue
+		}
+		if _, err := uuid.Parse(value); err != nil {
+			return filter, errors.New("Invalid " + param.name + ", expected uuid")
+		}
+		*param.dest = value
+	}
+
+	return filter, nil
+}
+
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/cache"
+	"github.com/wicaker/user/internal/pkg/rmq"
+	"github.com/wicaker/user/internal/repository"
+)
+
+// newCacheRepositories decorates user and profile repositories with a cache when it is enabled.
+// Changes are published to and consumed from the user-cache-invalidation queue, so every replica drops changed records
+func newCacheRepositories(userRepo domain.UserRepository, profileRepo domain.ProfileRepository, rmqQ []rmq.Queue) (domain.UserRepository, domain.ProfileRepository) {
+	cacheConf := config.NewCache()
+	if !cacheConf.Enabled() {
+		return userRepo, profileRepo
+	}
+
+	lru := cache.NewLRU(cacheConf.Size, cacheConf.TTL)
+	origin := uuid.New().String()
+
+	var publisher domain.Publisher
+	for _, q := range rmqQ {
+		if q.GetQueueName() == "user-cache-invalidation" {
+			q.Consume(cacheInvalidationConsumer(lru, origin))
+			publisher = q
+		}
+	}
+	if publisher == nil {
+		logrus.Warnln("user-cache-invalidation queue is not registered, cache of other replicas is not invalidated")
+	}
+
+	return repository.NewUserCacheRepository(userRepo, lru, publisher, origin), repository.NewProfileCacheRepository(profileRepo, lru, publisher, origin)
+}
+
+// cacheInvalidationConsumer drops entries changed by other replicas, events of origin have already been applied
+func cacheInvalidationConsumer(c domain.Cache, origin string) rmq.MsgCons {
+	return func(delivery amqp.Delivery) {
+		var event domain.CacheInvalidation
+		if err := json.Unmarshal(delivery.Body, &event); err != nil {
+			logrus.Error(err)
+			delivery.Reject(false)
+			return
+		}
+
+		if event.Origin != origin {
+			for _, tag := range event.Tags {
+				c.Invalidate(tag)
+			}
+		}
+		delivery.Ack(false)
+	}
+}
@@ -0,0 +1,194 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+)
+
+// ConsentHandler represent the httphandler for policy documents and consents
+type ConsentHandler struct {
+	ConsentUsecase domain.ConsentUsecase
+	paginator      *paginator
+}
+
+// consentRequest represent request body of accepting policy documents
+type consentRequest struct {
+	Consents []domain.Consent `json:"consents" validate:"required,min=1,dive"`
+}
+
+// consentGateExempt are path prefixes reachable without accepting current policy documents.
+// Consent endpoints are needed to accept them and data export is a right of the user regardless
+var consentGateExempt = []string{"/user/consents", "/policies", "/user/exports"}
+
+// NewConsentHandler will initialize the consent endpoint and the re-consent gate of authenticated endpoints
+func NewConsentHandler(e *echo.Echo, u domain.ConsentUsecase, p *paginator) {
+	handler := &ConsentHandler{
+		ConsentUsecase: u,
+		paginator:      p,
+	}
+
+	e.Use(handler.RequireConsent)
+
+	e.GET("/policies", handler.Current)
+	e.POST("/policies", handler.Publish)
+	e.GET("/user/consents", handler.History)
+	e.POST("/user/consents", handler.Accept)
+}
+
+// RequireConsent will reject request of user who has not accepted the current policy documents yet.
+// Only request with x-access-token header is checked, endpoints of consentGateExempt are always reachable
+func (ch *ConsentHandler) RequireConsent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		for _, prefix := range consentGateExempt {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return next(c)
+			}
+		}
+
+		token := c.Request().Header.Get("x-access-token")
+		if token == "" {
+			return next(c)
+		}
+
+		// an invalid token is answered by the handler itself
+		parsedToken, err := middleware.JwtVerify(token)
+		if err != nil {
+			return next(c)
+		}
+
+		pending, err := ch.ConsentUsecase.Pending(requestContext(c), *parsedToken)
+		if err != nil {
+			return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+		}
+		if len(pending) > 0 {
+			respData := map[string]interface{}{
+				"documents": pending,
+			}
+			return c.JSON(domain.GetStatusCode(domain.ErrConsentRequired), domain.Response{Message: domain.ErrConsentRequired.Error(), Data: respData})
+		}
+
+		return next(c)
+	}
+}
+
+// Current will handle request of current policy documents
+func (ch *ConsentHandler) Current(c echo.Context) error {
+	documents, err := ch.ConsentUsecase.Current(requestContext(c))
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"documents": documents,
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Current policy documents", Data: respData})
+}
+
+// Publish will handle admin request to publish a new version of policy document
+func (ch *ConsentHandler) Publish(c echo.Context) error {
+	var document domain.PolicyDocument
+
+	err := c.Bind(&document)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	if ok, err := middleware.Validate(&document); !ok {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: "Validation error", Errors: err})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	err = ch.ConsentUsecase.Publish(ctx, &document, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"document": document,
+	}
+
+	return c.JSON(http.StatusCreated, domain.Response{Message: "Successfully publish policy document", Data: respData})
+}
+
+// Accept will handle request of accepting policy documents
+func (ch *ConsentHandler) Accept(c echo.Context) error {
+	var request consentRequest
+
+	err := c.Bind(&request)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	if ok, err := middleware.Validate(&request); !ok {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: "Validation error", Errors: err})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	err = ch.ConsentUsecase.Accept(ctx, request.Consents, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusNoContent, domain.Response{Message: "Successfully accept policy documents"})
+}
+
+// History will handle request of consent history of user, query params limit, after, before and total are optional
+func (ch *ConsentHandler) History(c echo.Context) error {
+	page, err := ch.paginator.pageRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	consents, result, err := ch.ConsentUsecase.History(ctx, *parsedToken, page)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData, err := ch.paginator.pageData("consents", consents, result)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, domain.Response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Consent history", Data: respData})
+}
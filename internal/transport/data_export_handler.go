@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+)
+
+// DataExportHandler represent the httphandler for personal data export
+type DataExportHandler struct {
+	DataExportUsecase domain.DataExportUsecase
+}
+
+// NewDataExportHandler will initialize the data export endpoint
+func NewDataExportHandler(e *echo.Echo, u domain.DataExportUsecase) {
+	handler := &DataExportHandler{
+		DataExportUsecase: u,
+	}
+
+	e.POST("/user/exports", handler.Request)
+	e.GET("/user/exports/:uuid", handler.Get)
+	e.GET("/user/exports/:uuid/download", handler.Download)
+}
+
+// Request will handle request of exporting personal data, the archive is built asynchronously
+func (dh *DataExportHandler) Request(c echo.Context) error {
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	export, err := dh.DataExportUsecase.Request(ctx, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"export": export,
+	}
+
+	return c.JSON(http.StatusAccepted, domain.Response{Message: "Data export requested. You will be notified when it is ready!", Data: respData})
+}
+
+// Get will handle request of data export status
+func (dh *DataExportHandler) Get(c echo.Context) error {
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	export, err := dh.DataExportUsecase.Get(ctx, c.Param("uuid"), *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"export": export,
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Data export", Data: respData})
+}
+
+// Download will handle download of data export through signed link
+func (dh *DataExportHandler) Download(c echo.Context) error {
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	export, err := dh.DataExportUsecase.Download(ctx, c.Param("uuid"), c.QueryParam("expires"), c.QueryParam("signature"))
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	return c.Attachment(*export.FilePath, "user-data-"+export.UUID+".zip")
+}
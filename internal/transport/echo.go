@@ -1,9 +1,15 @@
 package transport
 
 import (
+	"log"
 	"time"
 
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/authenticator"
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/geolocator"
 	"github.com/wicaker/user/internal/middleware"
+	"github.com/wicaker/user/internal/pkg/envelope"
 	"github.com/wicaker/user/internal/pkg/rmq"
 	"github.com/wicaker/user/internal/repository"
 	"github.com/wicaker/user/internal/usecase"
@@ -12,18 +18,153 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// Echo server
-func Echo(db *sqlx.DB, rmqQ []rmq.Queue) *echo.Echo {
+// Echo server, user and profile reads are routed to replicas when it is not nil
+func Echo(db *sqlx.DB, replicas *repository.ReplicaSet, rmqQ []rmq.Queue) *echo.Echo {
 	e := echo.New()
 	middL := middleware.InitEchoMiddleware()
 	e.Use(middL.MiddlewareLogging)
 	e.Use(middL.CORS)
+	e.Use(middL.ClientInfo)
+	e.Use(middL.CorrelationID)
 
 	timeoutContext := time.Duration(2) * time.Second
 
-	userRepo := repository.NewUserSqlxRepository(db)
-	userUcase := usecase.NewUserUsecase(timeoutContext, userRepo)
-	NewUserHandler(e, rmqQ, userUcase)
+	keyring, err := config.NewEncryption().Keyring()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	transactor := repository.NewSqlxTransactor(db, config.NewSqlx().TxIsolation())
+	userRepo, profileRepo := newUserRepositories(db, replicas, keyring)
+	userRepo, profileRepo = newCacheRepositories(userRepo, profileRepo, rmqQ)
+	tenantRepo := repository.NewTenantSqlxRepository(db)
+	invitationRepo := repository.NewInvitationSqlxRepository(db)
+	policyRepo := repository.NewPolicyDocumentSqlxRepository(db)
+	consentRepo := repository.NewConsentSqlxRepository(db)
+	auditRepo := repository.NewAuditEventSqlxRepository(db)
+	loginRepo := repository.NewLoginAttemptSqlxRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistorySqlxRepository(db)
+	outboxRepo := repository.NewOutboxSqlxRepository(db)
+
+	pages := newPaginator(config.NewPagination())
+
+	loginHistoryUcase := usecase.NewLoginHistoryUsecase(timeoutContext, loginRepo, userRepo, newGeoLocator(), outboxRepo)
+	NewLoginHistoryHandler(e, loginHistoryUcase, pages)
+
+	userUcase := usecase.NewUserUsecase(timeoutContext, transactor, userRepo, invitationRepo, policyRepo, consentRepo, auditRepo, outboxRepo, loginHistoryUcase, tenantRepo, passwordHistoryRepo, newAuthenticator(userRepo), newRegistrationPolicy(), newPasswordPolicy())
+	NewUserHandler(e, userUcase, middleware.RequireRecentAuth(config.NewStepUp().MaxAge))
+
+	auditUcase := usecase.NewAuditUsecase(timeoutContext, auditRepo, userRepo)
+	NewAuditHandler(e, auditUcase, pages)
+
+	profileUcase := usecase.NewProfileUsecase(timeoutContext, transactor, userRepo, profileRepo)
+	NewProfileHandler(e, profileUcase)
+
+	consentUcase := usecase.NewConsentUsecase(timeoutContext, userRepo, policyRepo, consentRepo)
+	NewConsentHandler(e, consentUcase, pages)
+
+	dataExportRepo := repository.NewDataExportSqlxRepository(db)
+	dataExportUcase := usecase.NewDataExportUsecase(timeoutContext, config.NewExport(), transactor, dataExportRepo, userRepo, profileRepo, consentRepo, auditRepo, loginRepo, outboxRepo)
+	NewDataExportHandler(e, dataExportUcase)
+
+	invitationUcase := usecase.NewInvitationUsecase(timeoutContext, transactor, userRepo, invitationRepo, outboxRepo)
+	NewInvitationHandler(e, invitationUcase)
+
+	groupRepo := repository.NewGroupSqlxRepository(db)
+	provisioningUcase := usecase.NewProvisioningUsecase(timeoutContext, transactor, tenantRepo, userRepo, profileRepo, groupRepo)
+	NewScimHandler(e, provisioningUcase)
+
+	samlConf := config.NewSaml()
+	samlKey, samlCert, err := samlConf.KeyPair()
+	if err != nil {
+		log.Println(err)
+	}
+	samlProviderRepo := repository.NewSamlProviderSqlxRepository(db)
+	samlAssertionRepo := repository.NewSamlAssertionSqlxRepository(db)
+	samlUcase := usecase.NewSamlUsecase(timeoutContext, samlConf.BaseURL, samlKey, samlCert, transactor, samlProviderRepo, samlAssertionRepo, userRepo, profileRepo, loginHistoryUcase)
+	NewSamlHandler(e, samlUcase)
 
 	return e
 }
+
+// newUserRepositories returns user and profile repositories of the database driver,
+// uuid, security stamp and timestamps are generated by the repositories on sqlite as it has no postgres triggers
+func newUserRepositories(db *sqlx.DB, replicas *repository.ReplicaSet, keyring *envelope.Keyring) (domain.UserRepository, domain.ProfileRepository) {
+	if db.DriverName() == config.DriverSqlite {
+		return repository.NewUserSqliteRepository(db), repository.NewProfileSqliteRepository(db, keyring)
+	}
+	if replicas != nil {
+		return repository.NewUserSqlxReplicaRepository(db, replicas), repository.NewProfileSqlxReplicaRepository(db, replicas, keyring)
+	}
+	return repository.NewUserSqlxRepository(db), repository.NewProfileSqlxRepository(db, keyring)
+}
+
+// newAuthenticator route email domains configured for ldap to the directory, the others use local password
+func newAuthenticator(userRepo domain.UserRepository) domain.Authenticator {
+	localAuth := authenticator.NewLocalAuthenticator(userRepo)
+
+	ldapConf := config.NewLdap()
+	if !ldapConf.Enabled() {
+		return localAuth
+	}
+
+	ldapAuth := authenticator.NewLdapAuthenticator(ldapConf, userRepo)
+	routes := make(map[string]domain.Authenticator)
+	for _, d := range ldapConf.EmailDomains {
+		routes[d] = ldapAuth
+	}
+
+	return authenticator.NewDomainAuthenticator(localAuth, routes)
+}
+
+// newRegistrationPolicy read registration mode, a denylist which can not be loaded is logged and left empty
+func newRegistrationPolicy() domain.RegistrationPolicy {
+	registrationConf := config.NewRegistration()
+
+	disposableDomains, err := registrationConf.DisposableDomains()
+	if err != nil {
+		log.Println(err)
+	}
+
+	return domain.RegistrationPolicy{
+		Mode:              registrationConf.Mode,
+		AllowedDomains:    registrationConf.AllowedDomains,
+		DisposableDomains: disposableDomains,
+	}
+}
+
+// newPasswordPolicy read the global password policy, tenants may override it
+func newPasswordPolicy() domain.PasswordPolicy {
+	passwordConf := config.NewPassword()
+
+	return domain.PasswordPolicy{
+		HistorySize: passwordConf.HistorySize,
+		MaxAge:      passwordConf.MaxAge,
+	}
+}
+
+// findQueue returns queue of the given name, it is nil when the queue is not registered
+func findQueue(rmqQ []rmq.Queue, name string) rmq.Queue {
+	for _, q := range rmqQ {
+		if q.GetQueueName() == name {
+			return q
+		}
+	}
+	return nil
+}
+
+// newGeoLocator open the local GeoIP database, logins are not located when it is not configured
+func newGeoLocator() domain.GeoLocator {
+	geoConf := config.NewGeoIP()
+	if !geoConf.Enabled() {
+		return geolocator.NewNoopLocator()
+	}
+
+	locator, err := geolocator.NewMaxmindLocator(geoConf.DatabaseFile)
+	if err != nil {
+		log.Println(err)
+		return geolocator.NewNoopLocator()
+	}
+
+	return locator
+}
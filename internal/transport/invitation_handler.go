@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+)
+
+// InvitationHandler represent the httphandler for invitation
+type InvitationHandler struct {
+	InvitationUsecase domain.InvitationUsecase
+}
+
+// NewInvitationHandler will initialize the invitation endpoint
+func NewInvitationHandler(e *echo.Echo, u domain.InvitationUsecase) {
+	handler := &InvitationHandler{
+		InvitationUsecase: u,
+	}
+
+	e.POST("/user/invitations", handler.Invite)
+}
+
+// Invite will handle admin request to invite an email address
+func (ih *InvitationHandler) Invite(c echo.Context) error {
+	var invitation domain.Invitation
+
+	err := c.Bind(&invitation)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	if ok, err := middleware.Validate(&invitation); !ok {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: "Validation error", Errors: err})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, err = ih.InvitationUsecase.Invite(ctx, &invitation, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"uuid":       invitation.UUID,
+		"email":      invitation.Email,
+		"expires_at": invitation.ExpiresAt,
+	}
+
+	return c.JSON(http.StatusCreated, domain.Response{Message: "Successfully invite user", Data: respData})
+}
@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+)
+
+// LoginHistoryHandler represent the httphandler for login history
+type LoginHistoryHandler struct {
+	LoginHistoryUsecase domain.LoginHistoryUsecase
+	paginator           *paginator
+}
+
+// NewLoginHistoryHandler will initialize the login history endpoint
+func NewLoginHistoryHandler(e *echo.Echo, u domain.LoginHistoryUsecase, p *paginator) {
+	handler := &LoginHistoryHandler{
+		LoginHistoryUsecase: u,
+		paginator:           p,
+	}
+
+	e.GET("/user/logins", handler.Fetch)
+}
+
+// Fetch will handle request of login history of user, query params limit, after, before and total are optional
+func (lh *LoginHistoryHandler) Fetch(c echo.Context) error {
+	page, err := lh.paginator.pageRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	logins, result, err := lh.LoginHistoryUsecase.Fetch(ctx, *parsedToken, page)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData, err := lh.paginator.pageData("logins", logins, result)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, domain.Response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Login history", Data: respData})
+}
@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/rmq"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/internal/worker"
+)
+
+// outboxQueues are names of the queues outbox messages are published to by routing key
+var outboxQueues = map[string]string{
+	"user.register":        "publish-user-register",
+	"user.change_password": "publish-user-change-password",
+	"user.forgot_password": "publish-user-forgot-password",
+	"user.invite":          "publish-user-invite",
+	"user.export_ready":    "publish-user-export",
+	"user.new_login":       "publish-user-new-login",
+}
+
+const (
+	// eventVersionExtension is the CloudEvents extension of the event schema version
+	eventVersionExtension = "eventversion"
+	// correlationIDExtension is the CloudEvents extension of the correlation id
+	correlationIDExtension = "correlationid"
+)
+
+// NewOutboxRelay will create new the relay of outbox messages, messages of a routing key whose queue is not registered
+// stay in the outbox until it is. Queues of a CloudEvents format receive the envelope as event attributes
+func NewOutboxRelay(db *sqlx.DB, rmqQ []rmq.Queue) *worker.OutboxRelay {
+	outboxConf := config.NewOutbox()
+
+	publishers := make(map[string]domain.Publisher)
+	for routingKey, name := range outboxQueues {
+		queue := findQueue(rmqQ, name)
+		switch {
+		case queue == nil:
+		case queue.Format() == rmq.FormatPlain:
+			publishers[routingKey] = queue
+		default:
+			publishers[routingKey] = cloudEventPublisher{queue: queue, source: outboxConf.EventSource}
+		}
+	}
+
+	return worker.NewOutboxRelay(
+		repository.NewSqlxTransactor(db, config.NewSqlx().TxIsolation()),
+		repository.NewOutboxSqlxRepository(db),
+		publishers,
+		outboxConf.RelayInterval,
+		outboxConf.BatchSize,
+		outboxConf.Lease,
+		outboxConf.Retention,
+	)
+}
+
+// cloudEventPublisher publishes event envelopes of the relay to a queue of a CloudEvents format.
+// The envelope is mapped onto ce- headers and data of the envelope is the message
+type cloudEventPublisher struct {
+	queue  rmq.Queue
+	source string
+}
+
+func (p cloudEventPublisher) Publish(message string, routingKey string, headers map[string]interface{}) error {
+	var envelope domain.EventEnvelope
+	if err := json.Unmarshal([]byte(message), &envelope); err != nil {
+		return err
+	}
+
+	attributes := make(map[string]interface{}, len(headers)+7)
+	for name, value := range headers {
+		attributes[name] = value
+	}
+	attributes[rmq.CloudEventsHeaderPrefix+"id"] = envelope.ID
+	attributes[rmq.CloudEventsHeaderPrefix+"source"] = p.source
+	attributes[rmq.CloudEventsHeaderPrefix+"type"] = envelope.Type
+	attributes[rmq.CloudEventsHeaderPrefix+"time"] = envelope.OccurredAt.UTC().Format(time.RFC3339Nano)
+	attributes[rmq.CloudEventsHeaderPrefix+"datacontenttype"] = "application/json"
+	attributes[rmq.CloudEventsHeaderPrefix+eventVersionExtension] = strconv.Itoa(envelope.Version)
+	if envelope.CorrelationID != "" {
+		attributes[rmq.CloudEventsHeaderPrefix+correlationIDExtension] = envelope.CorrelationID
+	}
+
+	return p.queue.Publish(string(envelope.Data), routingKey, attributes)
+}
@@ -0,0 +1,96 @@
+package transport
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/cursor"
+)
+
+// paginator parse page requests of list endpoints and encode cursors of the returned pages
+type paginator struct {
+	codec        *cursor.Codec
+	defaultLimit uint
+	maxLimit     uint
+}
+
+// newPaginator will create new a paginator of the pagination config
+func newPaginator(conf *config.PaginationConfig) *paginator {
+	return &paginator{
+		codec:        cursor.NewCodec(conf.CursorKey),
+		defaultLimit: conf.DefaultLimit,
+		maxLimit:     conf.MaxLimit,
+	}
+}
+
+// pageRequest parse query params limit, after, before and total of list request.
+// Limit above the maximum is lowered to it, after and before are exclusive
+func (p *paginator) pageRequest(c echo.Context) (domain.PageRequest, error) {
+	page := domain.PageRequest{Limit: p.defaultLimit}
+
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || limit == 0 {
+			return page, errors.New("Invalid limit")
+		}
+		page.Limit = uint(limit)
+	}
+	if page.Limit > p.maxLimit {
+		page.Limit = p.maxLimit
+	}
+
+	if c.QueryParam("after") != "" && c.QueryParam("before") != "" {
+		return page, errors.New("Only one of after and before can be given")
+	}
+	for _, param := range []struct {
+		name string
+		dest **domain.Cursor
+	}{{"after", &page.After}, {"before", &page.Before}} {
+		value := c.QueryParam(param.name)
+		if value == "" {
+			continue
+		}
+		position := new(domain.Cursor)
+		if err := p.codec.Decode(value, position); err != nil {
+			return page, domain.ErrInvalidCursor
+		}
+		*param.dest = position
+	}
+
+	page.WithTotal = c.QueryParam("total") == "true"
+
+	return page, nil
+}
+
+// pageData returns response data of a page, items are kept under key and cursors of the neighbouring pages under "page"
+func (p *paginator) pageData(key string, items interface{}, page *domain.Page) (map[string]interface{}, error) {
+	meta := map[string]interface{}{
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	for _, position := range []struct {
+		name   string
+		cursor *domain.Cursor
+	}{{"next_cursor", page.Next}, {"prev_cursor", page.Prev}} {
+		if position.cursor == nil {
+			continue
+		}
+		value, err := p.codec.Encode(position.cursor)
+		if err != nil {
+			return nil, err
+		}
+		meta[position.name] = value
+	}
+	if page.Total != nil {
+		meta["total"] = *page.Total
+	}
+
+	return map[string]interface{}{
+		key:    items,
+		"page": meta,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+)
+
+// ProfileHandler represent the httphandler for profile of user
+type ProfileHandler struct {
+	ProfileUsecase domain.ProfileUsecase
+}
+
+// profileRequest represent request body of replacing profile
+type profileRequest struct {
+	FirstName *string    `json:"first_name" validate:"omitempty,max=255"`
+	LastName  *string    `json:"last_name" validate:"omitempty,max=255"`
+	Phone     *string    `json:"phone" validate:"omitempty,max=21"`
+	Address   *string    `json:"address" validate:"omitempty,max=255"`
+	Gender    *string    `json:"gender" validate:"omitempty,oneof=m f"`
+	Dob       *time.Time `json:"dob"`
+}
+
+// profileResponse represent profile in response body, user of profile is not exposed
+type profileResponse struct {
+	UUID      string     `json:"uuid"`
+	FirstName *string    `json:"first_name"`
+	LastName  *string    `json:"last_name"`
+	Phone     *string    `json:"phone"`
+	Address   *string    `json:"address"`
+	Gender    *string    `json:"gender"`
+	Dob       *time.Time `json:"dob"`
+	Version   int        `json:"version"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewProfileHandler will initialize the profile endpoint
+func NewProfileHandler(e *echo.Echo, u domain.ProfileUsecase) {
+	handler := &ProfileHandler{
+		ProfileUsecase: u,
+	}
+
+	e.GET("/user/profile", handler.Get)
+	e.PUT("/user/profile", handler.Update)
+}
+
+// Get will handle request of profile of user, version of profile is returned as ETag
+func (ph *ProfileHandler) Get(c echo.Context) error {
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	profile, err := ph.ProfileUsecase.Get(ctx, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	return respondProfile(c, http.StatusOK, "Profile", profile)
+}
+
+// Update will handle request of replacing profile of user.
+// If-Match header is optional, when given the profile is only replaced if it still has that ETag
+func (ph *ProfileHandler) Update(c echo.Context) error {
+	var request profileRequest
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	err = c.Bind(&request)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: err.Error()})
+	}
+
+	if ok, err := middleware.Validate(&request); !ok {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: "Validation error", Errors: err})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	profile, err := ph.ProfileUsecase.Update(ctx, &domain.Profile{
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Phone:     request.Phone,
+		Address:   request.Address,
+		Gender:    request.Gender,
+		Dob:       request.Dob,
+		Version:   version,
+	}, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	return respondProfile(c, http.StatusOK, "Successfully update profile", profile)
+}
+
+func respondProfile(c echo.Context, code int, message string, profile *domain.Profile) error {
+	c.Response().Header().Set("ETag", entityTag(profile.Version))
+
+	respData := map[string]interface{}{
+		"profile": profileResponse{
+			UUID:      profile.UUID,
+			FirstName: profile.FirstName,
+			LastName:  profile.LastName,
+			Phone:     profile.Phone,
+			Address:   profile.Address,
+			Gender:    profile.Gender,
+			Dob:       profile.Dob,
+			Version:   profile.Version,
+			UpdatedAt: profile.UpdatedAt,
+			CreatedAt: profile.CreatedAt,
+		},
+	}
+
+	return c.JSON(code, domain.Response{Message: message, Data: respData})
+}
+
+// entityTag returns strong entity tag of a record version
+func entityTag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatchVersion returns record version of If-Match header, zero when the header is not given or is "*".
+// A header which is not an entity tag of this service can never match
+func ifMatchVersion(c echo.Context) (int, error) {
+	match := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if match == "" || match == "*" {
+		return 0, nil
+	}
+
+	tag, err := strconv.Unquote(match)
+	if err != nil {
+		return 0, domain.ErrPreconditionFailed
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return 0, domain.ErrPreconditionFailed
+	}
+
+	return version, nil
+}
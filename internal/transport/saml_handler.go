@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// SamlHandler represent the httphandler for saml single sign-on
+type SamlHandler struct {
+	SamlUsecase domain.SamlUsecase
+}
+
+// NewSamlHandler will initialize the saml endpoint
+func NewSamlHandler(e *echo.Echo, u domain.SamlUsecase) {
+	handler := &SamlHandler{
+		SamlUsecase: u,
+	}
+
+	e.GET("/saml/login", handler.Login)
+	e.GET("/saml/:provider/metadata", handler.Metadata)
+	e.POST("/saml/:provider/acs", handler.AssertionConsumerService)
+}
+
+// Login will redirect user to identity provider of the email domain or tenant
+func (sh *SamlHandler) Login(c echo.Context) error {
+	email := c.QueryParam("email")
+	tenant := c.QueryParam("tenant")
+	if email == "" && tenant == "" {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: "email or tenant is required"})
+	}
+
+	redirectURL, err := sh.SamlUsecase.AuthnRequest(requestContext(c), email, tenant)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	return c.Redirect(http.StatusFound, redirectURL)
+}
+
+// Metadata will handle service provider metadata request
+func (sh *SamlHandler) Metadata(c echo.Context) error {
+	metadata, err := sh.SamlUsecase.Metadata(requestContext(c), c.Param("provider"))
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	return c.Blob(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// AssertionConsumerService will handle saml response posted by identity provider
+func (sh *SamlHandler) AssertionConsumerService(c echo.Context) error {
+	samlResponse := c.FormValue("SAMLResponse")
+	if samlResponse == "" {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: "SAMLResponse is required"})
+	}
+
+	token, err := sh.SamlUsecase.Login(requestContext(c), c.Param("provider"), samlResponse, c.FormValue("RelayState"))
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"token": token,
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Login successfully", Data: respData})
+}
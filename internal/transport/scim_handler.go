@@ -0,0 +1,449 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/scim"
+)
+
+const scimTenantKey = "scim_tenant"
+
+// ScimHandler represent the httphandler for SCIM 2.0 provisioning
+type ScimHandler struct {
+	ProvisioningUsecase domain.ProvisioningUsecase
+}
+
+// NewScimHandler will initialize the scim endpoint
+func NewScimHandler(e *echo.Echo, u domain.ProvisioningUsecase) {
+	handler := &ScimHandler{
+		ProvisioningUsecase: u,
+	}
+
+	g := e.Group(scimBasePath, handler.Authenticate)
+	g.GET("/ServiceProviderConfig", handler.ServiceProviderConfig)
+	g.GET("/Users", handler.FetchUsers)
+	g.POST("/Users", handler.StoreUser)
+	g.GET("/Users/:id", handler.GetUser)
+	g.PUT("/Users/:id", handler.UpdateUser)
+	g.PATCH("/Users/:id", handler.PatchUser)
+	g.DELETE("/Users/:id", handler.DeleteUser)
+	g.GET("/Groups", handler.FetchGroups)
+	g.POST("/Groups", handler.StoreGroup)
+	g.GET("/Groups/:id", handler.GetGroup)
+	g.PUT("/Groups/:id", handler.UpdateGroup)
+	g.PATCH("/Groups/:id", handler.PatchGroup)
+	g.DELETE("/Groups/:id", handler.DeleteGroup)
+}
+
+// Authenticate will resolve tenant of the bearer token in Authorization header
+func (sh *ScimHandler) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var (
+			ctx   = requestContext(c)
+			auth  = c.Request().Header.Get(echo.HeaderAuthorization)
+			token string
+		)
+
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			token = strings.TrimSpace(auth[7:])
+		}
+
+		tenant, err := sh.ProvisioningUsecase.Authenticate(ctx, token)
+		if err != nil {
+			return scimError(c, err)
+		}
+
+		c.Set(scimTenantKey, tenant)
+		return next(c)
+	}
+}
+
+// ServiceProviderConfig will handle service provider configuration request
+func (sh *ScimHandler) ServiceProviderConfig(c echo.Context) error {
+	return scimJSON(c, http.StatusOK, scim.ServiceProviderConfig())
+}
+
+// FetchUsers will handle query users request
+func (sh *ScimHandler) FetchUsers(c echo.Context) error {
+	query, err := scimQuery(c.QueryParam("filter"), scim.SchemaUser, scimUserColumns)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	startIndex, count := scimPagination(c)
+	users, profiles, total, err := sh.ProvisioningUsecase.FetchUsers(requestContext(c), scimTenant(c), query, uint(count), uint(startIndex-1))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	resources := make([]interface{}, len(users))
+	for i := range users {
+		resources[i] = toScimUser(users[i], profiles[i])
+	}
+
+	return scimJSON(c, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+}
+
+// GetUser will handle get user request
+func (sh *ScimHandler) GetUser(c echo.Context) error {
+	user, profile, err := sh.ProvisioningUsecase.GetUser(requestContext(c), scimTenant(c), c.Param("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	su := toScimUser(user, profile)
+	if match := c.Request().Header.Get("If-None-Match"); match != "" && match == su.Meta.Version {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return scimResource(c, http.StatusOK, su, su.Meta)
+}
+
+// StoreUser will handle create user request
+func (sh *ScimHandler) StoreUser(c echo.Context) error {
+	var su scim.User
+	if err := json.NewDecoder(c.Request().Body).Decode(&su); err != nil {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+	}
+	if su.UserName == "" {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidValue", "userName is required"))
+	}
+
+	ctx := requestContext(c)
+	user, profile := fromScimUser(su)
+
+	err := sh.ProvisioningUsecase.StoreUser(ctx, scimTenant(c), user, profile)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	return sh.respondUser(c, http.StatusCreated, user.UUID)
+}
+
+// UpdateUser will handle replace user request
+func (sh *ScimHandler) UpdateUser(c echo.Context) error {
+	var su scim.User
+	if err := json.NewDecoder(c.Request().Body).Decode(&su); err != nil {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+	}
+	if su.UserName == "" {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidValue", "userName is required"))
+	}
+
+	if err := sh.checkUserVersion(c); err != nil {
+		return scimError(c, err)
+	}
+
+	su.ID = c.Param("id")
+	user, profile := fromScimUser(su)
+
+	err := sh.ProvisioningUsecase.UpdateUser(requestContext(c), scimTenant(c), user, profile)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	return sh.respondUser(c, http.StatusOK, user.UUID)
+}
+
+// PatchUser will handle modify user request
+func (sh *ScimHandler) PatchUser(c echo.Context) error {
+	var req scim.PatchRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+	}
+
+	ctx := requestContext(c)
+	user, profile, err := sh.ProvisioningUsecase.GetUser(ctx, scimTenant(c), c.Param("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	su := toScimUser(user, profile)
+	if err := checkVersion(c, su.Meta); err != nil {
+		return scimError(c, err)
+	}
+
+	if err := su.Patch(req.Operations); err != nil {
+		return scimError(c, err)
+	}
+	if su.UserName == "" {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidValue", "userName is required"))
+	}
+
+	user, profile = fromScimUser(su)
+	err = sh.ProvisioningUsecase.UpdateUser(ctx, scimTenant(c), user, profile)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	return sh.respondUser(c, http.StatusOK, user.UUID)
+}
+
+// DeleteUser will handle deprovision user request
+func (sh *ScimHandler) DeleteUser(c echo.Context) error {
+	if err := sh.checkUserVersion(c); err != nil {
+		return scimError(c, err)
+	}
+
+	err := sh.ProvisioningUsecase.DeleteUser(requestContext(c), scimTenant(c), c.Param("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// FetchGroups will handle query groups request
+func (sh *ScimHandler) FetchGroups(c echo.Context) error {
+	query, err := scimQuery(c.QueryParam("filter"), scim.SchemaGroup, scimGroupColumns)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	startIndex, count := scimPagination(c)
+	groups, total, err := sh.ProvisioningUsecase.FetchGroups(requestContext(c), scimTenant(c), query, uint(count), uint(startIndex-1))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	resources := make([]interface{}, len(groups))
+	for i := range groups {
+		resources[i] = toScimGroup(groups[i])
+	}
+
+	return scimJSON(c, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+}
+
+// GetGroup will handle get group request
+func (sh *ScimHandler) GetGroup(c echo.Context) error {
+	group, err := sh.ProvisioningUsecase.GetGroup(requestContext(c), scimTenant(c), c.Param("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	sg := toScimGroup(group)
+	if match := c.Request().Header.Get("If-None-Match"); match != "" && match == sg.Meta.Version {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return scimResource(c, http.StatusOK, sg, sg.Meta)
+}
+
+// StoreGroup will handle create group request
+func (sh *ScimHandler) StoreGroup(c echo.Context) error {
+	var sg scim.Group
+	if err := json.NewDecoder(c.Request().Body).Decode(&sg); err != nil {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+	}
+	if sg.DisplayName == "" {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidValue", "displayName is required"))
+	}
+
+	group := fromScimGroup(sg)
+	err := sh.ProvisioningUsecase.StoreGroup(requestContext(c), scimTenant(c), group)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	sg = toScimGroup(group)
+	return scimResource(c, http.StatusCreated, sg, sg.Meta)
+}
+
+// UpdateGroup will handle replace group request
+func (sh *ScimHandler) UpdateGroup(c echo.Context) error {
+	var sg scim.Group
+	if err := json.NewDecoder(c.Request().Body).Decode(&sg); err != nil {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+	}
+	if sg.DisplayName == "" {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidValue", "displayName is required"))
+	}
+
+	ctx := requestContext(c)
+	current, err := sh.ProvisioningUsecase.GetGroup(ctx, scimTenant(c), c.Param("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	if err := checkVersion(c, toScimGroup(current).Meta); err != nil {
+		return scimError(c, err)
+	}
+
+	sg.ID = current.UUID
+	group := fromScimGroup(sg)
+	err = sh.ProvisioningUsecase.UpdateGroup(ctx, scimTenant(c), group)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	sg = toScimGroup(group)
+	return scimResource(c, http.StatusOK, sg, sg.Meta)
+}
+
+// PatchGroup will handle modify group request
+func (sh *ScimHandler) PatchGroup(c echo.Context) error {
+	var req scim.PatchRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+	}
+
+	ctx := requestContext(c)
+	current, err := sh.ProvisioningUsecase.GetGroup(ctx, scimTenant(c), c.Param("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	sg := toScimGroup(current)
+	if err := checkVersion(c, sg.Meta); err != nil {
+		return scimError(c, err)
+	}
+
+	if err := sg.Patch(req.Operations); err != nil {
+		return scimError(c, err)
+	}
+	if sg.DisplayName == "" {
+		return scimError(c, scim.NewError(http.StatusBadRequest, "invalidValue", "displayName is required"))
+	}
+
+	group := fromScimGroup(sg)
+	err = sh.ProvisioningUsecase.UpdateGroup(ctx, scimTenant(c), group)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	sg = toScimGroup(group)
+	return scimResource(c, http.StatusOK, sg, sg.Meta)
+}
+
+// DeleteGroup will handle delete group request
+func (sh *ScimHandler) DeleteGroup(c echo.Context) error {
+	ctx := requestContext(c)
+	current, err := sh.ProvisioningUsecase.GetGroup(ctx, scimTenant(c), c.Param("id"))
+	if err != nil {
+		return scimError(c, err)
+	}
+	if err := checkVersion(c, toScimGroup(current).Meta); err != nil {
+		return scimError(c, err)
+	}
+
+	err = sh.ProvisioningUsecase.DeleteGroup(ctx, scimTenant(c), current.UUID)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (sh *ScimHandler) respondUser(c echo.Context, code int, uuid string) error {
+	user, profile, err := sh.ProvisioningUsecase.GetUser(requestContext(c), scimTenant(c), uuid)
+	if err != nil {
+		return scimError(c, err)
+	}
+
+	su := toScimUser(user, profile)
+	return scimResource(c, code, su, su.Meta)
+}
+
+// checkUserVersion compare If-Match header with the current version of user, when the header is given
+func (sh *ScimHandler) checkUserVersion(c echo.Context) error {
+	if c.Request().Header.Get("If-Match") == "" {
+		return nil
+	}
+
+	user, profile, err := sh.ProvisioningUsecase.GetUser(requestContext(c), scimTenant(c), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(c, toScimUser(user, profile).Meta)
+}
+
+// checkVersion compare If-Match header with version of resource, when the header is given
+func checkVersion(c echo.Context, meta *scim.Meta) error {
+	match := c.Request().Header.Get("If-Match")
+	if match == "" || match == "*" {
+		return nil
+	}
+
+	for _, m := range strings.Split(match, ",") {
+		if strings.TrimSpace(m) == resourceVersion(meta) {
+			return nil
+		}
+	}
+
+	return domain.ErrPreconditionFailed
+}
+
+// scimPagination returns 1-based start index and page size of list request
+func scimPagination(c echo.Context) (startIndex int, count int) {
+	startIndex, err := strconv.Atoi(c.QueryParam("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err = strconv.Atoi(c.QueryParam("count"))
+	if err != nil {
+		count = scim.DefaultCount
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > scim.MaxCount {
+		count = scim.MaxCount
+	}
+
+	return startIndex, count
+}
+
+func scimTenant(c echo.Context) *domain.Tenant {
+	return c.Get(scimTenantKey).(*domain.Tenant)
+}
+
+func scimResource(c echo.Context, code int, resource interface{}, meta *scim.Meta) error {
+	c.Response().Header().Set("ETag", resourceVersion(meta))
+	c.Response().Header().Set(echo.HeaderLocation, meta.Location)
+	return scimJSON(c, code, resource)
+}
+
+func scimJSON(c echo.Context, code int, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, scim.MediaType, b)
+}
+
+// scimError write the given error as SCIM error response
+func scimError(c echo.Context, err error) error {
+	scimErr, ok := err.(*scim.Error)
+	if !ok {
+		status := domain.GetStatusCode(err)
+
+		var scimType string
+		if status == http.StatusConflict && errors.Cause(err) != domain.ErrConflict {
+			scimType = "uniqueness"
+		}
+		scimErr = scim.NewError(status, scimType, err.Error())
+	}
+
+	return scimJSON(c, scimErr.StatusCode(), scimErr)
+}
+
+func scimInvalidFilter(detail string) error {
+	return scim.NewError(http.StatusBadRequest, "invalidFilter", detail)
+}
+
+func requestContext(c echo.Context) context.Context {
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ctx
+}
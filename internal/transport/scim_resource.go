@@ -0,0 +1,236 @@
+package transport
+
+import (
+	"strings"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/scim"
+)
+
+const scimBasePath = "/scim/v2"
+
+// scimUserColumns maps filterable user attributes to users column and whether the comparison is case sensitive
+var scimUserColumns = map[string]struct {
+	column    string
+	caseExact bool
+}{
+	"id":           {"uuid", true},
+	"username":     {"email", false},
+	"emails":       {"email", false},
+	"emails.value": {"email", false},
+	"externalid":   {"external_id", true},
+	"active":       {"is_active", true},
+}
+
+// scimGroupColumns maps filterable group attributes to groups column and whether the comparison is case sensitive
+var scimGroupColumns = map[string]struct {
+	column    string
+	caseExact bool
+}{
+	"id":          {"uuid", true},
+	"displayname": {"display_name", false},
+	"externalid":  {"external_id", true},
+}
+
+func toScimUser(user *domain.User, profile *domain.Profile) scim.User {
+	var (
+		active       = user.IsActive
+		lastModified = user.UpdatedAt
+		su           = scim.User{
+			Schemas:  []string{scim.SchemaUser},
+			ID:       user.UUID,
+			UserName: user.Email,
+			Emails:   []scim.MultiValued{{Value: user.Email, Type: "work", Primary: true}},
+			Active:   &active,
+		}
+	)
+
+	if user.ExternalID != nil {
+		su.ExternalID = *user.ExternalID
+	}
+
+	if profile != nil {
+		if profile.FirstName != nil || profile.LastName != nil {
+			su.Name = &scim.Name{
+				GivenName:  stringValue(profile.FirstName),
+				FamilyName: stringValue(profile.LastName),
+			}
+		}
+		if profile.Phone != nil {
+			su.PhoneNumbers = []scim.MultiValued{{Value: *profile.Phone}}
+		}
+		if profile.Address != nil {
+			su.Addresses = []scim.Address{{Formatted: *profile.Address}}
+		}
+		if profile.UpdatedAt.After(lastModified) {
+			lastModified = profile.UpdatedAt
+		}
+	}
+
+	su.Meta = &scim.Meta{
+		ResourceType: "User",
+		Created:      &user.CreatedAt,
+		LastModified: &lastModified,
+		Location:     scimBasePath + "/Users/" + user.UUID,
+		Version:      scim.Version(lastModified),
+	}
+
+	return su
+}
+
+// fromScimUser maps user resource to domain user and profile.
+// From multi-valued attributes the primary value is taken, otherwise the last one.
+func fromScimUser(su scim.User) (*domain.User, *domain.Profile) {
+	user := &domain.User{
+		UUID:     su.ID,
+		Email:    su.UserName,
+		Password: su.Password,
+		IsActive: su.Active == nil || *su.Active,
+	}
+	if su.ExternalID != "" {
+		user.ExternalID = &su.ExternalID
+	}
+
+	profile := new(domain.Profile)
+	if su.Name != nil {
+		profile.FirstName = stringPointer(su.Name.GivenName)
+		profile.LastName = stringPointer(su.Name.FamilyName)
+	}
+
+	for i, phone := range su.PhoneNumbers {
+		if phone.Primary || i == len(su.PhoneNumbers)-1 {
+			profile.Phone = stringPointer(phone.Value)
+			break
+		}
+	}
+
+	for i, address := range su.Addresses {
+		if address.Primary || i == len(su.Addresses)-1 {
+			profile.Address = stringPointer(address.Formatted)
+			break
+		}
+	}
+
+	return user, profile
+}
+
+func toScimGroup(group *domain.Group) scim.Group {
+	sg := scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          group.UUID,
+		DisplayName: group.DisplayName,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      &group.CreatedAt,
+			LastModified: &group.UpdatedAt,
+			Location:     scimBasePath + "/Groups/" + group.UUID,
+			Version:      scim.Version(group.UpdatedAt),
+		},
+	}
+
+	if group.ExternalID != nil {
+		sg.ExternalID = *group.ExternalID
+	}
+
+	for _, member := range group.Members {
+		sg.Members = append(sg.Members, scim.Member{
+			Value: member,
+			Ref:   scimBasePath + "/Users/" + member,
+		})
+	}
+
+	return sg
+}
+
+func fromScimGroup(sg scim.Group) *domain.Group {
+	group := &domain.Group{
+		UUID:        sg.ID,
+		DisplayName: sg.DisplayName,
+		Members:     []string{},
+	}
+	if sg.ExternalID != "" {
+		group.ExternalID = &sg.ExternalID
+	}
+
+	for _, member := range sg.Members {
+		group.Members = append(group.Members, member.Value)
+	}
+
+	return group
+}
+
+// scimQuery translate parsed filter to repository query using the given attribute to column mapping
+func scimQuery(filter string, schema string, columns map[string]struct {
+	column    string
+	caseExact bool
+}) (*domain.Query, error) {
+	query := domain.Where()
+	if filter == "" {
+		return query, nil
+	}
+
+	filters, err := scim.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make(map[string]bool)
+
+	for _, f := range filters {
+		attr := strings.ToLower(f.AttrPath)
+		attr = strings.TrimPrefix(attr, strings.ToLower(schema)+":")
+
+		col, ok := columns[attr]
+		if !ok {
+			return nil, scimInvalidFilter("unsupported filter attribute " + f.AttrPath)
+		}
+		if filtered[col.column] {
+			return nil, scimInvalidFilter("attribute " + f.AttrPath + " is filtered more than once")
+		}
+		filtered[col.column] = true
+
+		switch value := f.Value.(type) {
+		case bool:
+			if f.Operator != scim.OperatorEqual {
+				return nil, scimInvalidFilter("boolean attribute only supports eq operator")
+			}
+			query = query.And(domain.Eq(col.column, value))
+		case string:
+			switch {
+			case f.Operator == scim.OperatorEqual && col.caseExact && col.column != "uuid":
+				query = query.And(domain.Eq(col.column, value))
+			case f.Operator == scim.OperatorEqual:
+				query = query.And(domain.Like(col.column, domain.EscapeLike(value)))
+			case f.Operator == scim.OperatorContains:
+				query = query.And(domain.Like(col.column, "%"+domain.EscapeLike(value)+"%"))
+			case f.Operator == scim.OperatorStartsWith:
+				query = query.And(domain.Like(col.column, domain.EscapeLike(value)+"%"))
+			}
+		default:
+			return nil, scimInvalidFilter("unsupported filter value of " + f.AttrPath)
+		}
+	}
+
+	return query, nil
+}
+
+func resourceVersion(meta *scim.Meta) string {
+	if meta == nil {
+		return ""
+	}
+	return meta.Version
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func stringPointer(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
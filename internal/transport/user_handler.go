@@ -2,50 +2,41 @@ package transport
 
 import (
 	"context"
-	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
 
 	"github.com/wicaker/user/internal/domain"
 	"github.com/wicaker/user/internal/middleware"
-	"github.com/wicaker/user/internal/pkg/rmq"
 )
 
 // UserHandler represent the httphandler for user
 type UserHandler struct {
-	UserUsecase                domain.UserUsecase
-	queuePublishRegister       rmq.Queue
-	queuePublishChangePassword rmq.Queue
-	queuePublishForgotPassword rmq.Queue
+	UserUsecase domain.UserUsecase
 }
 
-// NewUserHandler will initialize the user endpoint
-func NewUserHandler(e *echo.Echo, rmqQueue []rmq.Queue, u domain.UserUsecase) {
+// reauthenticateRequest represent request body of confirming password again
+type reauthenticateRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// NewUserHandler will initialize the user endpoint, stepUp guards sensitive endpoints
+func NewUserHandler(e *echo.Echo, u domain.UserUsecase, stepUp echo.MiddlewareFunc) {
 	handler := &UserHandler{
 		UserUsecase: u,
 	}
 
-	for _, rmqQ := range rmqQueue {
-		switch name := rmqQ.GetQueueName(); name {
-		case "publish-user-register":
-			handler.queuePublishRegister = rmqQ
-		case "publish-user-change-password":
-			handler.queuePublishChangePassword = rmqQ
-		case "publish-user-forgot-password":
-			handler.queuePublishForgotPassword = rmqQ
-		}
-	}
-
 	e.POST("/user/register", handler.Register)
 	e.POST("/user/login", handler.Login)
 	e.PUT("/user/activation/:token", handler.Activation)
-	e.PUT("/user/email", handler.ChangeEmail)
-	e.PUT("/user/password/change", handler.ChangePassword)
+	e.POST(middleware.ReauthenticatePath, handler.Reauthenticate)
+	e.PUT("/user/email", handler.ChangeEmail, stepUp)
+	e.PUT("/user/password/change", handler.ChangePassword, stepUp)
 	e.PUT("/user/password/change/:token", handler.PasswordConfirm)
 	e.PUT("/user/password/forgot", handler.ForgotPasswordRequest)
 	e.PUT("/user/password/forgot/:token", handler.ForgotPasswordConfirm)
+	e.POST("/user/logout/everywhere", handler.LogoutEverywhere)
 }
 
 // Register will handle register request
@@ -66,17 +57,11 @@ func (uh *UserHandler) Register(c echo.Context) error {
 		ctx = context.Background()
 	}
 
-	token, err := uh.UserUsecase.Register(ctx, &user)
+	_, err = uh.UserUsecase.Register(ctx, &user)
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
 
-	rabbitMessage := fmt.Sprintf(`{"email_destination":"%s","token":"%s"}`, user.Email, token)
-	err = uh.queuePublishRegister.Publish(rabbitMessage, "user.register", make(map[string]interface{}))
-	if err != nil {
-		log.Println(err)
-	}
-
 	return c.JSON(http.StatusCreated, domain.Response{Message: "Successfully register new user. Please confirm your email address!"})
 }
 
@@ -99,6 +84,14 @@ func (uh *UserHandler) Login(c echo.Context) error {
 	}
 
 	token, err := uh.UserUsecase.Login(ctx, &user)
+	if errors.Cause(err) == domain.ErrPasswordChangeRequired {
+		// token is restricted to change the expired password
+		respData := map[string]interface{}{
+			"error": "password_change_required",
+			"token": token,
+		}
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error(), Data: respData})
+	}
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
@@ -110,6 +103,43 @@ func (uh *UserHandler) Login(c echo.Context) error {
 	return c.JSON(http.StatusOK, domain.Response{Message: "Login successfully", Data: respData})
 }
 
+// Reauthenticate will handle request to confirm password again, the new token passes step-up check of sensitive endpoints
+func (uh *UserHandler) Reauthenticate(c echo.Context) error {
+	var request reauthenticateRequest
+
+	err := c.Bind(&request)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, domain.Response{Message: err.Error()})
+	}
+
+	if ok, err := middleware.Validate(&request); !ok {
+		return c.JSON(http.StatusBadRequest, domain.Response{Message: "Validation error", Errors: err})
+	}
+
+	// get token
+	tokenHeader := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	token, err := uh.UserUsecase.Reauthenticate(ctx, request.Password, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	respData := map[string]interface{}{
+		"token": token,
+	}
+
+	return c.JSON(http.StatusOK, domain.Response{Message: "Reauthenticate successfully", Data: respData})
+}
+
 // Activation will handle activation request for user first time register
 func (uh *UserHandler) Activation(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -119,7 +149,7 @@ func (uh *UserHandler) Activation(c echo.Context) error {
 
 	// get token
 	token := c.Param("token")
-	parsedToken, err := middleware.JwtVerify(token)
+	parsedToken, err := middleware.JwtVerifyType(token, domain.TokenActivation)
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
@@ -184,7 +214,7 @@ func (uh *UserHandler) ChangePassword(c echo.Context) error {
 
 	// get token
 	tokenHeader := c.Request().Header.Get("x-access-token")
-	parsedToken, err := middleware.JwtVerify(tokenHeader)
+	parsedToken, err := middleware.JwtVerifyScope(tokenHeader, domain.ScopePasswordChange)
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
@@ -194,17 +224,11 @@ func (uh *UserHandler) ChangePassword(c echo.Context) error {
 		ctx = context.Background()
 	}
 
-	token, err := uh.UserUsecase.ChangePassword(ctx, &user, *parsedToken)
+	_, err = uh.UserUsecase.ChangePassword(ctx, &user, *parsedToken)
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
 
-	rabbitMessage := fmt.Sprintf(`{"email_destination":"%s","token":"%s"}`, user.Email, token)
-	err = uh.queuePublishChangePassword.Publish(rabbitMessage, "user.change_password", make(map[string]interface{}))
-	if err != nil {
-		log.Println(err)
-	}
-
 	return c.JSON(http.StatusNoContent, domain.Response{Message: "Successfully change password. Please confirm through your email address! "})
 }
 
@@ -217,7 +241,7 @@ func (uh *UserHandler) PasswordConfirm(c echo.Context) error {
 
 	// get token
 	token := c.Param("token")
-	parsedToken, err := middleware.JwtVerify(token)
+	parsedToken, err := middleware.JwtVerifyType(token, domain.TokenPasswordConfirm)
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
@@ -244,17 +268,11 @@ func (uh *UserHandler) ForgotPasswordRequest(c echo.Context) error {
 		ctx = context.Background()
 	}
 
-	tokenConfirm, err := uh.UserUsecase.ForgotPasswordRequest(ctx, user.Email)
+	_, err = uh.UserUsecase.ForgotPasswordRequest(ctx, user.Email)
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
 
-	rabbitMessage := fmt.Sprintf(`{"email_destination":"%s","token":"%s"}`, user.Email, tokenConfirm)
-	err = uh.queuePublishForgotPassword.Publish(rabbitMessage, "user.forgot_password", make(map[string]interface{}))
-	if err != nil {
-		log.Println(err)
-	}
-
 	return c.JSON(http.StatusNoContent, domain.Response{Message: "Please confirm forgot password request through your email address!"})
 }
 
@@ -273,7 +291,7 @@ func (uh *UserHandler) ForgotPasswordConfirm(c echo.Context) error {
 
 	// get token
 	token := c.Param("token")
-	parsedToken, err := middleware.JwtVerify(token)
+	parsedToken, err := middleware.JwtVerifyType(token, domain.TokenForgotPassword)
 	if err != nil {
 		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
 	}
@@ -290,3 +308,25 @@ func (uh *UserHandler) ForgotPasswordConfirm(c echo.Context) error {
 
 	return c.JSON(http.StatusNoContent, domain.Response{Message: "Successfully register new user"})
 }
+
+// LogoutEverywhere will handle request to revoke every token of user, including the token of the request
+func (uh *UserHandler) LogoutEverywhere(c echo.Context) error {
+	// get token
+	token := c.Request().Header.Get("x-access-token")
+	parsedToken, err := middleware.JwtVerify(token)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	err = uh.UserUsecase.LogoutEverywhere(ctx, *parsedToken)
+	if err != nil {
+		return c.JSON(domain.GetStatusCode(err), domain.Response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusNoContent, domain.Response{Message: "Successfully logout everywhere"})
+}
@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// findAdmin returns active user of token, it must have admin role. It is read on the primary,
+// a replica may not have the security stamp rotated by logout everywhere yet
+func findAdmin(ctx context.Context, userRepo domain.UserRepository, parsedToken domain.JWToken) (*domain.User, error) {
+	admin, err := userRepo.FindOneBy(domain.WithReadYourWrites(ctx), domain.Where(
+		domain.Eq("uuid", parsedToken.UUID),
+		domain.Eq("email", parsedToken.Email),
+		domain.Eq("security_stamp", parsedToken.SecurityStamp),
+		domain.Eq("is_active", true),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil {
+		return nil, domain.ErrUnauthorized
+	}
+	if admin.Role != domain.RoleAdmin {
+		return nil, domain.ErrForbidden
+	}
+
+	return admin, nil
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// auditTimeout bounds storing of an audit event, it does not share deadline of the request
+const auditTimeout = time.Second * 5
+
+// sensitiveFields are never written to audit metadata, only the fact they are changed
+var sensitiveFields = map[string]bool{
+	"password":       true,
+	"new_password":   true,
+	"security_stamp": true,
+	"token":          true,
+}
+
+// auditRecord collects audit event while a usecase method runs, it is stored by done
+type auditRecord struct {
+	repo     domain.AuditEventRepository
+	event    domain.AuditEvent
+	metadata map[string]interface{}
+}
+
+func newAuditRecord(ctx context.Context, repo domain.AuditEventRepository, action string) *auditRecord {
+	client := domain.ClientInfoFromContext(ctx)
+	return &auditRecord{
+		repo: repo,
+		event: domain.AuditEvent{
+			Action:    action,
+			IPAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+		},
+		metadata: make(map[string]interface{}),
+	}
+}
+
+// actor set the user who does the action
+func (a *auditRecord) actor(uuid string) {
+	if uuid != "" {
+		a.event.ActorUUID = &uuid
+	}
+}
+
+// target set the user whose account is affected by the action
+func (a *auditRecord) target(uuid string) {
+	if uuid != "" {
+		a.event.TargetUUID = &uuid
+	}
+}
+
+// set add metadata of the action, value of sensitive key is redacted
+func (a *auditRecord) set(key string, value interface{}) {
+	if sensitiveFields[key] {
+		value = domain.AuditRedacted
+	}
+	a.metadata[key] = value
+}
+
+// diff add changed fields of user to metadata, sensitive values are redacted
+func (a *auditRecord) diff(before domain.User, after domain.User) {
+	changes := make(map[string]interface{})
+	change := func(field string, from interface{}, to interface{}) {
+		if sensitiveFields[field] {
+			from, to = domain.AuditRedacted, domain.AuditRedacted
+		}
+		changes[field] = map[string]interface{}{"from": from, "to": to}
+	}
+
+	if before.Email != after.Email {
+		change("email", before.Email, after.Email)
+	}
+	if before.IsActive != after.IsActive {
+		change("is_active", before.IsActive, after.IsActive)
+	}
+	if before.Role != after.Role {
+		change("role", before.Role, after.Role)
+	}
+	if before.Password != after.Password {
+		change("password", before.Password, after.Password)
+	}
+	if !equalStringPointer(before.NewPassword, after.NewPassword) {
+		change("new_password", before.NewPassword, after.NewPassword)
+	}
+	if before.SecurityStamp != after.SecurityStamp {
+		change("security_stamp", before.SecurityStamp, after.SecurityStamp)
+	}
+
+	if len(changes) > 0 {
+		a.metadata["diff"] = changes
+	}
+}
+
+// done store the event with outcome of err, failing to store is logged and does not fail the action
+func (a *auditRecord) done(err error) {
+	a.event.Outcome = domain.AuditSuccess
+	if err != nil {
+		reason := err.Error()
+		a.event.Outcome = domain.AuditFailure
+		a.event.Reason = &reason
+	}
+
+	metadata, jsonErr := json.Marshal(a.metadata)
+	if jsonErr != nil {
+		logrus.Errorf("audit %s: %s", a.event.Action, jsonErr)
+		return
+	}
+	a.event.Metadata = metadata
+
+	ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	defer cancel()
+
+	if _, storeErr := a.repo.Store(ctx, &a.event); storeErr != nil {
+		logrus.Errorf("audit %s: %s", a.event.Action, storeErr)
+	}
+}
+
+func equalStringPointer(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+const (
+	// auditVerifyBatch is number of audit events loaded at once while verifying the chain
+	auditVerifyBatch = 500
+)
+
+type auditUsecase struct {
+	auditRepo      domain.AuditEventRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+}
+
+// NewAuditUsecase will create new an auditUsecase object representation of domain.AuditUsecase interface
+func NewAuditUsecase(timeout time.Duration, auditRepo domain.AuditEventRepository, userRepo domain.UserRepository) domain.AuditUsecase {
+	return &auditUsecase{
+		contextTimeout: timeout,
+		auditRepo:      auditRepo,
+		userRepo:       userRepo,
+	}
+}
+
+/**
+ * Used by admin to query audit events. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user is an active admin
+ * - return page of events, newest first
+ */
+func (a *auditUsecase) Fetch(ctx context.Context, filter domain.AuditEventFilter, page domain.PageRequest, parsedToken domain.JWToken) ([]*domain.AuditEvent, *domain.Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.contextTimeout)
+	defer cancel()
+
+	_, err := findAdmin(ctx, a.userRepo, parsedToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page.Desc = true
+	return a.auditRepo.FindPage(ctx, filter, page)
+}
+
+/**
+ * Used by admin to detect tampering of audit events. Pseudocode:
+ * - check token user is an active admin
+ * - walk events in chain order, each must link to hash of the previous one and match its own hash
+ * - report the first broken event, the chain is valid when there is none
+ */
+func (a *auditUsecase) Verify(ctx context.Context, parsedToken domain.JWToken) (*domain.AuditVerification, error) {
+	adminCtx, cancel := context.WithTimeout(ctx, a.contextTimeout)
+	defer cancel()
+
+	_, err := findAdmin(adminCtx, a.userRepo, parsedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		result   = &domain.AuditVerification{Valid: true}
+		prevHash = domain.AuditGenesisHash
+		seq      int64
+	)
+	for {
+		events, err := a.auditRepo.FindAfter(ctx, seq, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			if event.PrevHash != prevHash || event.ChainHash() != event.Hash {
+				result.Valid = false
+				result.BrokenAt = &event.Seq
+				return result, nil
+			}
+
+			result.Checked++
+			prevHash = event.Hash
+			seq = event.Seq
+		}
+
+		if len(events) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type consentUsecase struct {
+	userRepo       domain.UserRepository
+	policyRepo     domain.PolicyDocumentRepository
+	consentRepo    domain.ConsentRepository
+	contextTimeout time.Duration
+}
+
+// NewConsentUsecase will create new an consentUsecase object representation of domain.ConsentUsecase interface
+func NewConsentUsecase(
+	timeout time.Duration,
+	userRepo domain.UserRepository,
+	policyRepo domain.PolicyDocumentRepository,
+	consentRepo domain.ConsentRepository,
+) domain.ConsentUsecase {
+	return &consentUsecase{
+		contextTimeout: timeout,
+		userRepo:       userRepo,
+		policyRepo:     policyRepo,
+		consentRepo:    consentRepo,
+	}
+}
+
+func (c *consentUsecase) Current(ctx context.Context) ([]*domain.PolicyDocument, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	return c.policyRepo.FindCurrent(ctx)
+}
+
+/**
+ * Used by admin to publish a new version of policy document. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user is an active admin in database
+ * - check version of the kind does not exist yet
+ * - publish now when publish time is not given
+ * - save document, users have to accept it once it is published
+ */
+func (c *consentUsecase) Publish(ctx context.Context, document *domain.PolicyDocument, parsedToken domain.JWToken) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	if _, err := findAdmin(ctx, c.userRepo, parsedToken); err != nil {
+		return err
+	}
+
+	checkDocument, err := c.policyRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("kind", document.Kind),
+		domain.Eq("version", document.Version),
+	))
+	if err != nil {
+		return err
+	}
+	if checkDocument != nil {
+		return domain.ErrPolicyAlreadyExist
+	}
+
+	if document.PublishedAt.IsZero() {
+		document.PublishedAt = time.Now()
+	}
+
+	_, err = c.policyRepo.Store(ctx, document)
+	if err != nil {
+		return errors.Wrap(err, "Store policy document data")
+	}
+
+	return nil
+}
+
+/**
+ * Used to check current policy documents user has not accepted. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user in database
+ * - find current documents and consents of token user
+ * - return documents without consent
+ */
+func (c *consentUsecase) Pending(ctx context.Context, parsedToken domain.JWToken) ([]*domain.PolicyDocument, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	checkUser, err := c.findUser(ctx, parsedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := c.policyRepo.FindCurrent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return documents, nil
+	}
+
+	consents, err := c.consentRepo.FindByUser(ctx, checkUser.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*domain.PolicyDocument
+	for _, document := range documents {
+		if !hasConsent(consents, document) {
+			pending = append(pending, document)
+		}
+	}
+
+	return pending, nil
+}
+
+/**
+ * Used to accept current policy documents. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user in database
+ * - every given kind and version must be a current document
+ * - save consent with client ip address and user agent
+ */
+func (c *consentUsecase) Accept(ctx context.Context, consents []domain.Consent, parsedToken domain.JWToken) error {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	checkUser, err := c.findUser(ctx, parsedToken)
+	if err != nil {
+		return err
+	}
+
+	documents, err := c.policyRepo.FindCurrent(ctx)
+	if err != nil {
+		return err
+	}
+
+	accepted, err := acceptedDocuments(documents, consents)
+	if err != nil {
+		return err
+	}
+
+	return storeConsents(ctx, c.consentRepo, checkUser.UUID, accepted)
+}
+
+func (c *consentUsecase) History(ctx context.Context, parsedToken domain.JWToken, page domain.PageRequest) ([]*domain.Consent, *domain.Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	checkUser, err := c.findUser(ctx, parsedToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page.Desc = true
+	return c.consentRepo.FindPageByUser(ctx, checkUser.UUID, page)
+}
+
+func (c *consentUsecase) findUser(ctx context.Context, parsedToken domain.JWToken) (*domain.User, error) {
+	// security stamp is checked on the primary, a replica may not have the rotated one yet
+	checkUser, err := c.userRepo.FindOneBy(domain.WithReadYourWrites(ctx), domain.Where(
+		domain.Eq("uuid", parsedToken.UUID),
+		domain.Eq("email", parsedToken.Email),
+		domain.Eq("security_stamp", parsedToken.SecurityStamp),
+		domain.Eq("is_active", true),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if checkUser == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return checkUser, nil
+}
+
+// acceptedDocuments returns current documents of the given consents, a consent of another version is rejected
+func acceptedDocuments(documents []*domain.PolicyDocument, consents []domain.Consent) ([]*domain.PolicyDocument, error) {
+	var accepted []*domain.PolicyDocument
+
+	for _, consent := range consents {
+		var match *domain.PolicyDocument
+		for _, document := range documents {
+			if document.Kind == consent.Kind && document.Version == consent.Version {
+				match = document
+				break
+			}
+		}
+		if match == nil {
+			return nil, domain.ErrPolicyNotAccepted
+		}
+		accepted = append(accepted, match)
+	}
+
+	return accepted, nil
+}
+
+func containsDocument(documents []*domain.PolicyDocument, document *domain.PolicyDocument) bool {
+	for _, d := range documents {
+		if d.UUID == document.UUID {
+			return true
+		}
+	}
+	return false
+}
+
+func hasConsent(consents []*domain.Consent, document *domain.PolicyDocument) bool {
+	for _, consent := range consents {
+		if consent.DocumentUUID == document.UUID {
+			return true
+		}
+	}
+	return false
+}
+
+// storeConsents save consent of every document, client of the request is taken from ctx
+func storeConsents(ctx context.Context, consentRepo domain.ConsentRepository, userUUID string, documents []*domain.PolicyDocument) error {
+	client := domain.ClientInfoFromContext(ctx)
+
+	for _, document := range documents {
+		consent := &domain.Consent{
+			UserUUID:     userUUID,
+			DocumentUUID: document.UUID,
+		}
+		if client.IPAddress != "" {
+			consent.IPAddress = &client.IPAddress
+		}
+		if client.UserAgent != "" {
+			consent.UserAgent = &client.UserAgent
+		}
+
+		if _, err := consentRepo.Store(ctx, consent); err != nil {
+			return errors.Wrap(err, "Store consent data")
+		}
+	}
+
+	return nil
+}
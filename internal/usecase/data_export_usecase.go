@@ -0,0 +1,410 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/domain"
+)
+
+// exportSection is a JSON file of the export archive
+type exportSection struct {
+	name    string
+	collect func(ctx context.Context, user *domain.User) (interface{}, error)
+}
+
+type dataExportUsecase struct {
+	conf           *config.ExportConfig
+	transactor     domain.Transactor
+	exportRepo     domain.DataExportRepository
+	userRepo       domain.UserRepository
+	profileRepo    domain.ProfileRepository
+	consentRepo    domain.ConsentRepository
+	auditRepo      domain.AuditEventRepository
+	loginRepo      domain.LoginAttemptRepository
+	outboxRepo     domain.OutboxRepository
+	contextTimeout time.Duration
+}
+
+// NewDataExportUsecase will create new an dataExportUsecase object representation of domain.DataExportUsecase interface.
+// user.export_ready event is written to outboxRepo with the ready export in a transaction of transactor
+func NewDataExportUsecase(
+	timeout time.Duration,
+	conf *config.ExportConfig,
+	transactor domain.Transactor,
+	exportRepo domain.DataExportRepository,
+	userRepo domain.UserRepository,
+	profileRepo domain.ProfileRepository,
+	consentRepo domain.ConsentRepository,
+	auditRepo domain.AuditEventRepository,
+	loginRepo domain.LoginAttemptRepository,
+	outboxRepo domain.OutboxRepository,
+) domain.DataExportUsecase {
+	return &dataExportUsecase{
+		contextTimeout: timeout,
+		conf:           conf,
+		transactor:     transactor,
+		exportRepo:     exportRepo,
+		userRepo:       userRepo,
+		profileRepo:    profileRepo,
+		consentRepo:    consentRepo,
+		auditRepo:      auditRepo,
+		loginRepo:      loginRepo,
+		outboxRepo:     outboxRepo,
+	}
+}
+
+/**
+ * Used to request export of personal data. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user in database
+ * - in a transaction, an export which is still pending is returned as is
+ * - an export pending for longer than ExportTimeout was left by a stopped build, it is marked failed
+ * - otherwise save a new pending export
+ * - build the archive in background once committed, see build
+ */
+func (d *dataExportUsecase) Request(ctx context.Context, parsedToken domain.JWToken) (*domain.DataExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.contextTimeout)
+	defer cancel()
+
+	checkUser, err := d.findUser(ctx, parsedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		export  *domain.DataExport
+		pending bool
+	)
+	err = d.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		found, err := d.exportRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("user_uuid", checkUser.UUID),
+			domain.Eq("status", domain.ExportPending),
+		))
+		if err != nil {
+			return err
+		}
+		if found != nil && time.Since(found.CreatedAt) < domain.ExportTimeout {
+			export, pending = found, true
+			return nil
+		}
+		if found != nil {
+			message := domain.ExportTimedOut
+			found.Status = domain.ExportFailed
+			found.Error = &message
+			if _, err := d.exportRepo.Update(ctx, found); err != nil {
+				return errors.Wrap(err, "Update data export")
+			}
+		}
+
+		export, err = d.exportRepo.Store(ctx, &domain.DataExport{
+			UserUUID: checkUser.UUID,
+			Status:   domain.ExportPending,
+		})
+		if err != nil {
+			return errors.Wrap(err, "Store data export")
+		}
+		pending = false
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return export, nil
+	}
+
+	go d.build(domain.CorrelationIDFromContext(ctx), *export, checkUser)
+
+	return export, nil
+}
+
+func (d *dataExportUsecase) Get(ctx context.Context, id string, parsedToken domain.JWToken) (*domain.DataExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.contextTimeout)
+	defer cancel()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, domain.ErrExportNotFound
+	}
+
+	checkUser, err := d.findUser(ctx, parsedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	export, err := d.exportRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("uuid", id),
+		domain.Eq("user_uuid", checkUser.UUID),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, domain.ErrExportNotFound
+	}
+
+	if export.Status == domain.ExportReady && export.ExpiresAt != nil && time.Now().Before(*export.ExpiresAt) {
+		export.DownloadURL = d.downloadURL(export)
+	}
+
+	return export, nil
+}
+
+/**
+ * Used to download export through signed link. Pseudocode:
+ * - set context.WithTimeout
+ * - check signature and expiry of the link
+ * - check export in database, it must be ready
+ * - an export past its retention is removed
+ */
+func (d *dataExportUsecase) Download(ctx context.Context, id string, expires string, signature string) (*domain.DataExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.contextTimeout)
+	defer cancel()
+
+	if !hmac.Equal([]byte(signature), []byte(d.sign(id, expires))) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, domain.ErrExportExpired
+	}
+
+	export, err := d.exportRepo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, domain.ErrExportNotFound
+	}
+	if export.Status != domain.ExportReady || export.FilePath == nil {
+		return nil, domain.ErrExportNotReady
+	}
+
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		if err := os.Remove(*export.FilePath); err != nil && !os.IsNotExist(err) {
+			logrus.Error(err)
+		}
+		return nil, domain.ErrExportExpired
+	}
+
+	return export, nil
+}
+
+/**
+ * Used to build export archive in background. Pseudocode:
+ * - collect every section of personal data
+ * - write each section as JSON file inside zip archive
+ * - mark export ready with retention, or failed
+ * - write user.export_ready event with signed download link to outbox in the transaction marking it ready
+ */
+func (d *dataExportUsecase) build(correlationID string, export domain.DataExport, user *domain.User) {
+	ctx, cancel := context.WithTimeout(domain.WithCorrelationID(context.Background(), correlationID), domain.ExportTimeout)
+	defer cancel()
+
+	path, err := d.writeArchive(ctx, &export, user)
+	if err != nil {
+		logrus.Errorf("data export %s: %s", export.UUID, err)
+
+		message := err.Error()
+		export.Status = domain.ExportFailed
+		export.Error = &message
+		if _, err := d.exportRepo.Update(ctx, &export); err != nil {
+			logrus.Error(err)
+		}
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(d.conf.Retention)
+	export.Status = domain.ExportReady
+	export.FilePath = &path
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	err = d.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := d.exportRepo.Update(ctx, &export); err != nil {
+			return err
+		}
+
+		return storeEvent(ctx, d.outboxRepo, domain.DataExportReady{
+			UserUUID:         user.UUID,
+			ExportUUID:       export.UUID,
+			EmailDestination: user.Email,
+			DownloadURL:      d.downloadURL(&export),
+			ExpiresAt:        export.ExpiresAt,
+		})
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+func (d *dataExportUsecase) writeArchive(ctx context.Context, export *domain.DataExport, user *domain.User) (string, error) {
+	if err := os.MkdirAll(d.conf.Dir, 0700); err != nil {
+		return "", errors.Wrap(err, "create export directory")
+	}
+
+	path := filepath.Join(d.conf.Dir, export.UUID+".zip")
+	file, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", errors.Wrap(err, "create export file")
+	}
+	defer os.Remove(path + ".tmp")
+
+	archive := zip.NewWriter(file)
+	for _, section := range d.sections() {
+		data, err := section.collect(ctx, user)
+		if err != nil {
+			file.Close()
+			return "", errors.Wrapf(err, "collect %s", section.name)
+		}
+
+		w, err := archive.Create(section.name + ".json")
+		if err != nil {
+			file.Close()
+			return "", errors.Wrap(err, "create archive entry")
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			file.Close()
+			return "", errors.Wrapf(err, "encode %s", section.name)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		file.Close()
+		return "", errors.Wrap(err, "close archive")
+	}
+	if err := file.Close(); err != nil {
+		return "", errors.Wrap(err, "close export file")
+	}
+
+	return path, os.Rename(path+".tmp", path)
+}
+
+// findUser returns active user of token
+func (d *dataExportUsecase) findUser(ctx context.Context, parsedToken domain.JWToken) (*domain.User, error) {
+	// security stamp is checked on the primary, a replica may not have the rotated one yet
+	checkUser, err := d.userRepo.FindOneBy(domain.WithReadYourWrites(ctx), domain.Where(
+		domain.Eq("uuid", parsedToken.UUID),
+		domain.Eq("email", parsedToken.Email),
+		domain.Eq("security_stamp", parsedToken.SecurityStamp),
+		domain.Eq("is_active", true),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if checkUser == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return checkUser, nil
+}
+
+// sections list every personal data tied to a user, secrets such as password and security stamp are left out
+func (d *dataExportUsecase) sections() []exportSection {
+	return []exportSection{
+		{
+			name: "user",
+			collect: func(ctx context.Context, user *domain.User) (interface{}, error) {
+				return map[string]interface{}{
+					"uuid":        user.UUID,
+					"email":       user.Email,
+					"is_active":   user.IsActive,
+					"role":        user.Role,
+					"tenant_uuid": user.TenantUUID,
+					"external_id": user.ExternalID,
+					"created_at":  user.CreatedAt,
+					"updated_at":  user.UpdatedAt,
+				}, nil
+			},
+		},
+		{
+			name: "profile",
+			collect: func(ctx context.Context, user *domain.User) (interface{}, error) {
+				profile, err := d.profileRepo.FindOneBy(ctx, domain.Where(
+					domain.Eq("user_uuid", user.UUID),
+				))
+				if err != nil || profile == nil {
+					return nil, err
+				}
+
+				return map[string]interface{}{
+					"uuid":       profile.UUID,
+					"first_name": profile.FirstName,
+					"last_name":  profile.LastName,
+					"phone":      profile.Phone,
+					"address":    profile.Address,
+					"gender":     profile.Gender,
+					"dob":        profile.Dob,
+					"created_at": profile.CreatedAt,
+					"updated_at": profile.UpdatedAt,
+				}, nil
+			},
+		},
+		{
+			name: "consents",
+			collect: func(ctx context.Context, user *domain.User) (interface{}, error) {
+				return d.consentRepo.FindByUser(ctx, user.UUID)
+			},
+		},
+		{
+			name: "logins",
+			collect: func(ctx context.Context, user *domain.User) (interface{}, error) {
+				return d.loginRepo.FindBy(ctx, domain.Where(
+					domain.Eq("user_uuid", user.UUID),
+				).OrderBy(domain.Desc("created_at")))
+			},
+		},
+		{
+			name: "audit_events",
+			collect: func(ctx context.Context, user *domain.User) (interface{}, error) {
+				events, err := d.auditRepo.FindBy(ctx, domain.AuditEventFilter{UserUUID: user.UUID})
+				if err != nil {
+					return nil, err
+				}
+
+				records := make([]map[string]interface{}, len(events))
+				for i, event := range events {
+					records[i] = map[string]interface{}{
+						"action":     event.Action,
+						"outcome":    event.Outcome,
+						"reason":     event.Reason,
+						"ip_address": event.IPAddress,
+						"user_agent": event.UserAgent,
+						"metadata":   event.Metadata,
+						"created_at": event.CreatedAt,
+					}
+				}
+				return records, nil
+			},
+		},
+	}
+}
+
+// downloadURL create short-lived link of the export, signed with JWT_SECRET
+func (d *dataExportUsecase) downloadURL(export *domain.DataExport) string {
+	expires := strconv.FormatInt(time.Now().Add(d.conf.LinkTTL).Unix(), 10)
+	return fmt.Sprintf("%s/user/exports/%s/download?expires=%s&signature=%s", d.conf.BaseURL, export.UUID, expires, d.sign(export.UUID, expires))
+}
+
+func (d *dataExportUsecase) sign(id string, expires string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(id + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// invitationExpiry is the lifetime of an invitation
+const invitationExpiry = time.Hour * 24 * 7
+
+type invitationUsecase struct {
+	transactor     domain.Transactor
+	userRepo       domain.UserRepository
+	invitationRepo domain.InvitationRepository
+	outboxRepo     domain.OutboxRepository
+	contextTimeout time.Duration
+}
+
+// NewInvitationUsecase will create new an invitationUsecase object representation of domain.InvitationUsecase interface.
+// Invitation is saved with its user.invite event in a transaction of transactor
+func NewInvitationUsecase(
+	timeout time.Duration,
+	transactor domain.Transactor,
+	userRepo domain.UserRepository,
+	invitationRepo domain.InvitationRepository,
+	outboxRepo domain.OutboxRepository,
+) domain.InvitationUsecase {
+	return &invitationUsecase{
+		contextTimeout: timeout,
+		transactor:     transactor,
+		userRepo:       userRepo,
+		invitationRepo: invitationRepo,
+		outboxRepo:     outboxRepo,
+	}
+}
+
+/**
+ * Used by admin to invite an email address. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user is an active admin in database
+ * - check invited email is not an active user yet
+ * - create random invite token, only its hash is persisted
+ * - in a transaction, save invitation and write user.invite event with invite token to outbox
+ * - return invite token
+ */
+func (i *invitationUsecase) Invite(ctx context.Context, invitation *domain.Invitation, parsedToken domain.JWToken) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, i.contextTimeout)
+	defer cancel()
+
+	admin, err := findAdmin(ctx, i.userRepo, parsedToken)
+	if err != nil {
+		return "", err
+	}
+
+	invitation.Email = strings.TrimSpace(invitation.Email)
+	checkUser, err := i.userRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("email", invitation.Email),
+		domain.Eq("is_active", true),
+	))
+	if err != nil {
+		return "", err
+	}
+	if checkUser != nil {
+		return "", domain.ErrUserAlreadyExist
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+
+	invitation.Token = hashToken(token)
+	invitation.InvitedBy = &admin.UUID
+	invitation.ExpiresAt = time.Now().Add(invitationExpiry)
+	invitation.AcceptedAt = nil
+
+	err = i.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := i.invitationRepo.Store(ctx, invitation)
+		if err != nil {
+			return errors.Wrap(err, "Store invitation data")
+		}
+
+		return storeEvent(ctx, i.outboxRepo, domain.UserInvited{
+			InvitationUUID:   invitation.UUID,
+			InvitedBy:        invitation.InvitedBy,
+			EmailDestination: invitation.Email,
+			Token:            token,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mssola/user_agent"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+const (
+	// loginHistoryTimeout bounds recording of a login attempt, it does not share deadline of the login request
+	loginHistoryTimeout = time.Second * 5
+)
+
+type loginHistoryUsecase struct {
+	loginRepo      domain.LoginAttemptRepository
+	userRepo       domain.UserRepository
+	locator        domain.GeoLocator
+	outboxRepo     domain.OutboxRepository
+	contextTimeout time.Duration
+}
+
+// NewLoginHistoryUsecase will create new an loginHistoryUsecase object representation of domain.LoginHistoryUsecase interface.
+// user.new_login event is written to outboxRepo when a user logs in from a new device or country, users are not notified when it is nil
+func NewLoginHistoryUsecase(
+	timeout time.Duration,
+	loginRepo domain.LoginAttemptRepository,
+	userRepo domain.UserRepository,
+	locator domain.GeoLocator,
+	outboxRepo domain.OutboxRepository,
+) domain.LoginHistoryUsecase {
+	return &loginHistoryUsecase{
+		contextTimeout: timeout,
+		loginRepo:      loginRepo,
+		userRepo:       userRepo,
+		locator:        locator,
+		outboxRepo:     outboxRepo,
+	}
+}
+
+/**
+ * Used to record a login attempt. Pseudocode:
+ * - parse user agent of client and locate its ip address
+ * - failed attempt is linked to user of the email if any
+ * - successful attempt from a device or country the user has not logged in from before is notified,
+ *   except for the first login of user
+ * - save the attempt, errors are logged and never fail the login
+ */
+func (l *loginHistoryUsecase) Record(ctx context.Context, method string, email string, user *domain.User, loginErr error) {
+	client := domain.ClientInfoFromContext(ctx)
+	correlationID := domain.CorrelationIDFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(domain.WithCorrelationID(context.Background(), correlationID), loginHistoryTimeout)
+	defer cancel()
+
+	attempt := &domain.LoginAttempt{
+		Email:     email,
+		Method:    method,
+		Success:   loginErr == nil,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+	}
+	parseUserAgent(attempt)
+
+	if loginErr != nil {
+		reason := loginErr.Error()
+		attempt.FailureReason = &reason
+	}
+
+	location, err := l.locator.Locate(client.IPAddress)
+	if err != nil {
+		logrus.Warnf("locate %s: %s", client.IPAddress, err)
+	}
+	if location != nil {
+		attempt.CountryCode = &location.CountryCode
+		attempt.Country = &location.Country
+		if location.City != "" {
+			attempt.City = &location.City
+		}
+	}
+
+	if user == nil && email != "" {
+		user, err = l.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("email", email),
+		))
+		if err != nil {
+			logrus.Error(err)
+		}
+	}
+	if user != nil {
+		attempt.UserUUID = &user.UUID
+	}
+
+	var reasons []string
+	if attempt.Success && user != nil {
+		reasons, err = l.newLoginReasons(ctx, attempt)
+		if err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	if _, err := l.loginRepo.Store(ctx, attempt); err != nil {
+		logrus.Errorf("record login of %s: %s", attempt.Email, err)
+		return
+	}
+
+	if len(reasons) > 0 {
+		l.notifyNewLogin(ctx, user, attempt, reasons)
+	}
+}
+
+/**
+ * Used to get login history of user. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user in database
+ * - return attempts, newest first
+ */
+func (l *loginHistoryUsecase) Fetch(ctx context.Context, parsedToken domain.JWToken, page domain.PageRequest) ([]*domain.LoginAttempt, *domain.Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.contextTimeout)
+	defer cancel()
+
+	// security stamp is checked on the primary, a replica may not have the rotated one yet
+	checkUser, err := l.userRepo.FindOneBy(domain.WithReadYourWrites(ctx), domain.Where(
+		domain.Eq("uuid", parsedToken.UUID),
+		domain.Eq("email", parsedToken.Email),
+		domain.Eq("security_stamp", parsedToken.SecurityStamp),
+		domain.Eq("is_active", true),
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+	if checkUser == nil {
+		return nil, nil, domain.ErrUserNotFound
+	}
+
+	page.Desc = true
+	return l.loginRepo.FindPage(ctx, domain.Where(
+		domain.Eq("user_uuid", checkUser.UUID),
+	), page)
+}
+
+// newLoginReasons tells why a successful attempt is unusual, it is empty for the first login of user
+func (l *loginHistoryUsecase) newLoginReasons(ctx context.Context, attempt *domain.LoginAttempt) ([]string, error) {
+	previous, err := l.loginRepo.Count(ctx, domain.Where(
+		domain.Eq("user_uuid", *attempt.UserUUID),
+		domain.Eq("success", true),
+	))
+	if err != nil || previous == 0 {
+		return nil, err
+	}
+
+	var reasons []string
+
+	sameDevice, err := l.loginRepo.Count(ctx, domain.Where(
+		domain.Eq("user_uuid", *attempt.UserUUID),
+		domain.Eq("success", true),
+		domain.Eq("device_fingerprint", attempt.DeviceFingerprint),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if sameDevice == 0 {
+		reasons = append(reasons, "new_device")
+	}
+
+	if attempt.CountryCode != nil {
+		sameCountry, err := l.loginRepo.Count(ctx, domain.Where(
+			domain.Eq("user_uuid", *attempt.UserUUID),
+			domain.Eq("success", true),
+			domain.Eq("country_code", *attempt.CountryCode),
+		))
+		if err != nil {
+			return nil, err
+		}
+		if sameCountry == 0 {
+			reasons = append(reasons, "new_country")
+		}
+	}
+
+	return reasons, nil
+}
+
+func (l *loginHistoryUsecase) notifyNewLogin(ctx context.Context, user *domain.User, attempt *domain.LoginAttempt, reasons []string) {
+	if l.outboxRepo == nil {
+		return
+	}
+
+	err := storeEvent(ctx, l.outboxRepo, domain.NewLoginDetected{
+		UserUUID:         user.UUID,
+		EmailDestination: user.Email,
+		Reasons:          reasons,
+		IPAddress:        attempt.IPAddress,
+		Browser:          attempt.Browser,
+		OS:               attempt.OS,
+		Device:           attempt.Device,
+		Country:          attempt.Country,
+		City:             attempt.City,
+		LoggedInAt:       attempt.CreatedAt,
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+// parseUserAgent fill browser, os and device of attempt, fingerprint is coarse so browser updates are not a new device
+func parseUserAgent(attempt *domain.LoginAttempt) {
+	ua := user_agent.New(attempt.UserAgent)
+
+	attempt.Browser, attempt.BrowserVersion = ua.Browser()
+	attempt.OS = ua.OSInfo().Name
+	switch {
+	case ua.Bot():
+		attempt.Device = domain.DeviceBot
+	case ua.Mobile():
+		attempt.Device = domain.DeviceMobile
+	default:
+		attempt.Device = domain.DeviceDesktop
+	}
+
+	attempt.DeviceFingerprint = hashToken(strings.Join([]string{attempt.Browser, attempt.OS, ua.Platform(), attempt.Device}, "|"))
+}
@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// storeEvent writes event to the outbox as JSON, it is published once the transaction of ctx is committed.
+// The event carries correlation id of ctx
+func storeEvent(ctx context.Context, outboxRepo domain.OutboxRepository, event domain.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	message := &domain.OutboxMessage{RoutingKey: event.EventType(), Version: event.EventVersion(), Payload: string(payload)}
+	if id := domain.CorrelationIDFromContext(ctx); id != "" {
+		message.CorrelationID = &id
+	}
+
+	_, err = outboxRepo.Store(ctx, message)
+	if err != nil {
+		return errors.Wrap(err, "Store outbox message")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// passwordPolicy returns default policy overridden by tenant of user
+func (u *userUsecase) passwordPolicy(ctx context.Context, user *domain.User) (domain.PasswordPolicy, error) {
+	policy := u.password
+	if user.TenantUUID == nil {
+		return policy, nil
+	}
+
+	tenant, err := u.tenantRepo.Find(ctx, *user.TenantUUID)
+	if err != nil || tenant == nil {
+		return policy, err
+	}
+
+	if tenant.PasswordHistorySize != nil {
+		policy.HistorySize = *tenant.PasswordHistorySize
+	}
+	if tenant.PasswordMaxAgeDays != nil {
+		policy.MaxAge = time.Duration(*tenant.PasswordMaxAgeDays) * time.Hour * 24
+	}
+
+	return policy, nil
+}
+
+// checkPasswordReuse rejects password which matches the current or a recently replaced password of user
+func (u *userUsecase) checkPasswordReuse(ctx context.Context, user *domain.User, password string) error {
+	policy, err := u.passwordPolicy(ctx, user)
+	if err != nil {
+		return err
+	}
+	if policy.HistorySize <= 0 {
+		return nil
+	}
+
+	hashes := []string{user.Password}
+	if policy.HistorySize > 1 {
+		histories, err := u.passwordHistoryRepo.FindRecent(ctx, user.UUID, uint(policy.HistorySize-1))
+		if err != nil {
+			return err
+		}
+		for _, history := range histories {
+			hashes = append(hashes, history.Password)
+		}
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return domain.ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// rememberPassword keeps the current password of user in history before it is replaced, only as many as the policy checks
+func (u *userUsecase) rememberPassword(ctx context.Context, user *domain.User) error {
+	policy, err := u.passwordPolicy(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	keep := policy.HistorySize - 1
+	if keep <= 0 {
+		return nil
+	}
+
+	_, err = u.passwordHistoryRepo.Store(ctx, &domain.PasswordHistory{
+		UserUUID: user.UUID,
+		Password: user.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.passwordHistoryRepo.Prune(ctx, user.UUID, uint(keep))
+}
+
+// passwordExpired report whether user logged in with a local password older than the policy allows.
+// Password of directory user is not managed here, it never matches the unusable local hash
+func (u *userUsecase) passwordExpired(ctx context.Context, user *domain.User, password string) (bool, error) {
+	policy, err := u.passwordPolicy(ctx, user)
+	if err != nil || policy.MaxAge <= 0 {
+		return false, err
+	}
+	if time.Since(user.PasswordChangedAt) <= policy.MaxAge {
+		return false, nil
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil, nil
+}
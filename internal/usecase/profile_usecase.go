@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type profileUsecase struct {
+	transactor     domain.Transactor
+	userRepo       domain.UserRepository
+	profileRepo    domain.ProfileRepository
+	contextTimeout time.Duration
+}
+
+// NewProfileUsecase will create new an profileUsecase object representation of domain.ProfileUsecase interface
+func NewProfileUsecase(
+	timeout time.Duration,
+	transactor domain.Transactor,
+	userRepo domain.UserRepository,
+	profileRepo domain.ProfileRepository,
+) domain.ProfileUsecase {
+	return &profileUsecase{
+		contextTimeout: timeout,
+		transactor:     transactor,
+		userRepo:       userRepo,
+		profileRepo:    profileRepo,
+	}
+}
+
+/**
+ * Used to get profile of user. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user in database
+ * - return profile of user, its version is the entity tag
+ * - profile is read on the primary, an entity tag read on a lagging replica after an update would fail If-Match
+ */
+func (p *profileUsecase) Get(ctx context.Context, parsedToken domain.JWToken) (*domain.Profile, error) {
+	ctx, cancel := context.WithTimeout(domain.WithReadYourWrites(ctx), p.contextTimeout)
+	defer cancel()
+
+	checkUser, err := p.findUser(ctx, parsedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := p.profileRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("user_uuid", checkUser.UUID),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, domain.ErrProfileNotFound
+	}
+
+	return profile, nil
+}
+
+/**
+ * Used to replace profile of user. Pseudocode:
+ * - set context.WithTimeout
+ * - in a transaction, check token user in database
+ * - the expected version, when given, must be the current version of profile
+ * - save a new profile or update the current one, update fails if profile has been updated concurrently
+ * - conflict of a conditional request is a failed precondition
+ */
+func (p *profileUsecase) Update(ctx context.Context, profile *domain.Profile, parsedToken domain.JWToken) (*domain.Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	var result *domain.Profile
+	err := p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := p.findUser(ctx, parsedToken)
+		if err != nil {
+			return err
+		}
+
+		current, err := p.profileRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("user_uuid", checkUser.UUID),
+		))
+		if err != nil {
+			return err
+		}
+
+		if current == nil {
+			if profile.Version != 0 {
+				return domain.ErrPreconditionFailed
+			}
+
+			newProfile := *profile
+			newProfile.User = *checkUser
+			result, err = p.profileRepo.Store(ctx, &newProfile)
+			if err != nil {
+				return errors.Wrap(err, "Store profile data")
+			}
+			return nil
+		}
+
+		if profile.Version != 0 && profile.Version != current.Version {
+			return domain.ErrPreconditionFailed
+		}
+
+		current.User = *checkUser
+		current.FirstName = profile.FirstName
+		current.LastName = profile.LastName
+		current.Phone = profile.Phone
+		current.Address = profile.Address
+		current.Gender = profile.Gender
+		current.Dob = profile.Dob
+
+		err = p.profileRepo.Update(ctx, current)
+		if errors.Cause(err) == domain.ErrConflict && profile.Version != 0 {
+			return domain.ErrPreconditionFailed
+		}
+		if err != nil {
+			return err
+		}
+
+		result = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (p *profileUsecase) findUser(ctx context.Context, parsedToken domain.JWToken) (*domain.User, error) {
+	// security stamp is checked on the primary, a replica may not have the rotated one yet
+	checkUser, err := p.userRepo.FindOneBy(domain.WithReadYourWrites(ctx), domain.Where(
+		domain.Eq("uuid", parsedToken.UUID),
+		domain.Eq("email", parsedToken.Email),
+		domain.Eq("security_stamp", parsedToken.SecurityStamp),
+		domain.Eq("is_active", true),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if checkUser == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return checkUser, nil
+}
@@ -0,0 +1,508 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+type provisioningUsecase struct {
+	transactor     domain.Transactor
+	tenantRepo     domain.TenantRepository
+	userRepo       domain.UserRepository
+	profileRepo    domain.ProfileRepository
+	groupRepo      domain.GroupRepository
+	contextTimeout time.Duration
+}
+
+// NewProvisioningUsecase will create new an provisioningUsecase object representation of domain.ProvisioningUsecase interface
+func NewProvisioningUsecase(
+	timeout time.Duration,
+	transactor domain.Transactor,
+	tenantRepo domain.TenantRepository,
+	userRepo domain.UserRepository,
+	profileRepo domain.ProfileRepository,
+	groupRepo domain.GroupRepository,
+) domain.ProvisioningUsecase {
+	return &provisioningUsecase{
+		contextTimeout: timeout,
+		transactor:     transactor,
+		tenantRepo:     tenantRepo,
+		userRepo:       userRepo,
+		profileRepo:    profileRepo,
+		groupRepo:      groupRepo,
+	}
+}
+
+/**
+ * Used to authenticate tenant's identity provider. Pseudocode:
+ * - set context.WithTimeout
+ * - hash bearer token, only the hash is persisted
+ * - check tenant by hashed token in database
+ */
+func (p *provisioningUsecase) Authenticate(ctx context.Context, token string) (*domain.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	if token == "" {
+		return nil, domain.ErrUnauthorized
+	}
+
+	tenant, err := p.tenantRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("scim_token", hashToken(token)),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if tenant == nil {
+		return nil, domain.ErrUnauthorized
+	}
+
+	return tenant, nil
+}
+
+/**
+ * Used to list users of tenant. Pseudocode:
+ * - set context.WithTimeout
+ * - restrict query to tenant
+ * - count all matching users, then find the requested page
+ * - find profile of every user, the profile is nil when user has none
+ */
+func (p *provisioningUsecase) FetchUsers(ctx context.Context, tenant *domain.Tenant, query *domain.Query, limit uint, offset uint) ([]*domain.User, []*domain.Profile, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	query = query.And(domain.Eq("tenant_uuid", tenant.UUID))
+
+	total, err := p.userRepo.Count(ctx, query)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	users, err := p.userRepo.FindBy(ctx, query.OrderBy(domain.Asc("created_at")).Paginate(limit, offset))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	profiles := make([]*domain.Profile, len(users))
+	for i, user := range users {
+		profiles[i], err = p.profileRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("user_uuid", user.UUID),
+		))
+		if err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	return users, profiles, total, nil
+}
+
+func (p *provisioningUsecase) GetUser(ctx context.Context, tenant *domain.Tenant, uuid string) (*domain.User, *domain.Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	checkUser, err := p.findUser(ctx, tenant, uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile, err := p.profileRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("user_uuid", checkUser.UUID),
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return checkUser, profile, nil
+}
+
+/**
+ * Used to provision a new user of tenant. Pseudocode:
+ * - set context.WithTimeout
+ * - hash given password or an unusable random one
+ * - in a transaction, check email in database
+ * - if not exist, save a new user, then activate if requested
+ * - save profile if any
+ */
+func (p *provisioningUsecase) StoreUser(ctx context.Context, tenant *domain.Tenant, user *domain.User, profile *domain.Profile) error {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	password, err := hashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+
+	isActive := user.IsActive
+	user.Password = password
+	user.TenantUUID = &tenant.UUID
+
+	return p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := p.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("email", user.Email),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser != nil {
+			return domain.ErrUserAlreadyExist
+		}
+
+		_, err = p.userRepo.Store(ctx, user)
+		if err != nil {
+			return errors.Wrap(err, "Store user data")
+		}
+
+		if isActive {
+			user.IsActive = true
+			_, err = p.userRepo.Update(ctx, user)
+			if err != nil {
+				return errors.Wrap(err, "Update user data")
+			}
+		}
+
+		if profile == nil {
+			return nil
+		}
+
+		profile.User = *user
+		_, err = p.profileRepo.Store(ctx, profile)
+		if err != nil {
+			return errors.Wrap(err, "Store profile data")
+		}
+
+		return nil
+	})
+}
+
+/**
+ * Used to replace user of tenant. Pseudocode:
+ * - set context.WithTimeout
+ * - hash password when given
+ * - in a transaction, check user of tenant in database
+ * - if email changed, check it is not used by another user
+ * - sync data, rotate security stamp when email or password changed
+ * - update user, then update or save profile
+ */
+func (p *provisioningUsecase) UpdateUser(ctx context.Context, tenant *domain.Tenant, user *domain.User, profile *domain.Profile) error {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	var password string
+	if user.Password != "" {
+		hashed, err := hashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+		password = hashed
+	}
+
+	var updatedUser *domain.User
+	err := p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := p.findUser(ctx, tenant, user.UUID)
+		if err != nil {
+			return err
+		}
+
+		if checkUser.Email != user.Email {
+			emailUser, err := p.userRepo.FindOneBy(ctx, domain.Where(
+				domain.Eq("email", user.Email),
+			))
+			if err != nil {
+				return err
+			}
+			if emailUser != nil {
+				return domain.ErrEmailAlreadyExist
+			}
+		}
+
+		if password != "" || checkUser.Email != user.Email {
+			if err := rotateSecurityStamp(checkUser); err != nil {
+				return err
+			}
+		}
+		if password != "" {
+			checkUser.Password = password
+		}
+
+		checkUser.Email = user.Email
+		checkUser.IsActive = user.IsActive
+		checkUser.ExternalID = user.ExternalID
+
+		updatedUser, err = p.userRepo.Update(ctx, checkUser)
+		if err != nil {
+			return errors.Wrap(err, "Update user data")
+		}
+
+		if profile == nil {
+			profile = new(domain.Profile)
+		}
+		profile.User = *updatedUser
+
+		checkProfile, err := p.profileRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("user_uuid", updatedUser.UUID),
+		))
+		if err != nil {
+			return err
+		}
+
+		if checkProfile == nil {
+			_, err = p.profileRepo.Store(ctx, profile)
+			if err != nil {
+				return errors.Wrap(err, "Store profile data")
+			}
+			return nil
+		}
+
+		profile.UUID = checkProfile.UUID
+		profile.Version = checkProfile.Version
+		profile.Gender = checkProfile.Gender
+		profile.Dob = checkProfile.Dob
+		err = p.profileRepo.Update(ctx, profile)
+		if err != nil {
+			return errors.Wrap(err, "Update profile data")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*user = *updatedUser
+	return nil
+}
+
+/**
+ * Used to deprovision user of tenant. The user row is kept, it is deactivated,
+ * detached from tenant and removed from every group. Pseudocode:
+ * - set context.WithTimeout
+ * - check user of tenant in database
+ * - sync data, rotate security stamp to revoke its tokens
+ * - update, then delete group membership
+ */
+func (p *provisioningUsecase) DeleteUser(ctx context.Context, tenant *domain.Tenant, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	return p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := p.findUser(ctx, tenant, uuid)
+		if err != nil {
+			return err
+		}
+
+		checkUser.IsActive = false
+		checkUser.TenantUUID = nil
+		if err := rotateSecurityStamp(checkUser); err != nil {
+			return err
+		}
+
+		_, err = p.userRepo.Update(ctx, checkUser)
+		if err != nil {
+			return errors.Wrap(err, "Update user data")
+		}
+
+		return p.groupRepo.DeleteMember(ctx, checkUser.UUID)
+	})
+}
+
+func (p *provisioningUsecase) FetchGroups(ctx context.Context, tenant *domain.Tenant, query *domain.Query, limit uint, offset uint) ([]*domain.Group, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	query = query.And(domain.Eq("tenant_uuid", tenant.UUID))
+
+	total, err := p.groupRepo.Count(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groups, err := p.groupRepo.FindBy(ctx, query.OrderBy(domain.Asc("created_at")).Paginate(limit, offset))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return groups, total, nil
+}
+
+func (p *provisioningUsecase) GetGroup(ctx context.Context, tenant *domain.Tenant, uuid string) (*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	return p.findGroup(ctx, tenant, uuid)
+}
+
+/**
+ * Used to provision a new group of tenant. Pseudocode:
+ * - set context.WithTimeout
+ * - check display name in tenant's groups
+ * - check every member is user of tenant
+ * - save a new group with its members
+ */
+func (p *provisioningUsecase) StoreGroup(ctx context.Context, tenant *domain.Tenant, group *domain.Group) error {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	return p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkGroup, err := p.groupRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("tenant_uuid", tenant.UUID),
+			domain.Eq("display_name", group.DisplayName),
+		))
+		if err != nil {
+			return err
+		}
+		if checkGroup != nil {
+			return domain.ErrGroupAlreadyExist
+		}
+
+		if err := p.checkMembers(ctx, tenant, group.Members); err != nil {
+			return err
+		}
+
+		group.TenantUUID = tenant.UUID
+		_, err = p.groupRepo.Store(ctx, group)
+		if err != nil {
+			return errors.Wrap(err, "Store group data")
+		}
+
+		return nil
+	})
+}
+
+/**
+ * Used to replace group of tenant. Pseudocode:
+ * - set context.WithTimeout
+ * - check group of tenant in database
+ * - if display name changed, check it is not used by another group
+ * - check every member is user of tenant
+ * - update group and its members
+ */
+func (p *provisioningUsecase) UpdateGroup(ctx context.Context, tenant *domain.Tenant, group *domain.Group) error {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	var updatedGroup *domain.Group
+	err := p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkGroup, err := p.findGroup(ctx, tenant, group.UUID)
+		if err != nil {
+			return err
+		}
+
+		if checkGroup.DisplayName != group.DisplayName {
+			nameGroup, err := p.groupRepo.FindOneBy(ctx, domain.Where(
+				domain.Eq("tenant_uuid", tenant.UUID),
+				domain.Eq("display_name", group.DisplayName),
+			))
+			if err != nil {
+				return err
+			}
+			if nameGroup != nil {
+				return domain.ErrGroupAlreadyExist
+			}
+		}
+
+		if err := p.checkMembers(ctx, tenant, group.Members); err != nil {
+			return err
+		}
+
+		checkGroup.DisplayName = group.DisplayName
+		checkGroup.ExternalID = group.ExternalID
+		checkGroup.Members = group.Members
+
+		updatedGroup, err = p.groupRepo.Update(ctx, checkGroup)
+		if err != nil {
+			return errors.Wrap(err, "Update group data")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*group = *updatedGroup
+	return nil
+}
+
+func (p *provisioningUsecase) DeleteGroup(ctx context.Context, tenant *domain.Tenant, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.contextTimeout)
+	defer cancel()
+
+	return p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkGroup, err := p.findGroup(ctx, tenant, uuid)
+		if err != nil {
+			return err
+		}
+
+		return p.groupRepo.Delete(ctx, checkGroup.UUID)
+	})
+}
+
+func (p *provisioningUsecase) findUser(ctx context.Context, tenant *domain.Tenant, id string) (*domain.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	checkUser, err := p.userRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("uuid", id),
+		domain.Eq("tenant_uuid", tenant.UUID),
+	))
+	if err != nil {
+		return nil, err
+	}
+	if checkUser == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return checkUser, nil
+}
+
+func (p *provisioningUsecase) findGroup(ctx context.Context, tenant *domain.Tenant, id string) (*domain.Group, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, domain.ErrGroupNotFound
+	}
+
+	checkGroup, err := p.groupRepo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if checkGroup == nil || checkGroup.TenantUUID != tenant.UUID {
+		return nil, domain.ErrGroupNotFound
+	}
+
+	return checkGroup, nil
+}
+
+func (p *provisioningUsecase) checkMembers(ctx context.Context, tenant *domain.Tenant, members []string) error {
+	for _, member := range members {
+		if _, err := p.findUser(ctx, tenant, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashPassword hash the given password, an empty one is replaced by random secret nobody knows
+func hashPassword(plain string) (string, error) {
+	if plain == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return "", errors.Wrap(err, "generate random password")
+		}
+		plain = hex.EncodeToString(secret)
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.Wrap(err, "Password Encryption failed")
+	}
+
+	return string(password), nil
+}
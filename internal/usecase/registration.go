@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+/**
+ * Used to check whether the given user may register. Pseudocode:
+ * - email of disposable domain is rejected in every mode
+ * - if invite token is given, the invitation must be pending and for the same email
+ * - invite mode requires an invitation
+ * - domain mode requires email of allowed domain
+ */
+func (u *userUsecase) checkRegistration(ctx context.Context, user *domain.User) (*domain.Invitation, error) {
+	userDomain := emailDomain(user.Email)
+	if matchDomain(userDomain, u.registration.DisposableDomains) {
+		return nil, domain.ErrDisposableEmail
+	}
+
+	if user.InviteToken != "" {
+		invitation, err := u.invitationRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("token", hashToken(user.InviteToken)),
+		))
+		if err != nil {
+			return nil, err
+		}
+		if invitation == nil || invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) ||
+			!strings.EqualFold(invitation.Email, user.Email) {
+			return nil, domain.ErrInvalidInvitation
+		}
+
+		return invitation, nil
+	}
+
+	switch u.registration.Mode {
+	case domain.RegistrationInvite:
+		return nil, domain.ErrInvitationRequired
+	case domain.RegistrationDomain:
+		for _, d := range u.registration.AllowedDomains {
+			if userDomain == d {
+				return nil, nil
+			}
+		}
+		return nil, domain.ErrEmailDomainNotAllowed
+	}
+
+	return nil, nil
+}
+
+// matchDomain report whether the given domain or one of its parent domains is in the list
+func matchDomain(emailDomain string, domains []string) bool {
+	for _, d := range domains {
+		if emailDomain == d || strings.HasSuffix(emailDomain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
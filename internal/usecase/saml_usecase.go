@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+const (
+	// relayStateAudience distinguish relay state token from another token signed with JWT_SECRET
+	relayStateAudience = "saml-relay-state"
+	relayStateExpiry   = time.Minute * 10
+)
+
+type samlUsecase struct {
+	baseURL        string
+	key            *rsa.PrivateKey
+	certificate    *x509.Certificate
+	transactor     domain.Transactor
+	providerRepo   domain.SamlProviderRepository
+	assertionRepo  domain.SamlAssertionRepository
+	userRepo       domain.UserRepository
+	profileRepo    domain.ProfileRepository
+	loginHistory   domain.LoginHistoryUsecase
+	contextTimeout time.Duration
+}
+
+// NewSamlUsecase will create new an samlUsecase object representation of domain.SamlUsecase interface.
+// baseURL is the public url of this service, key and certificate are optional and used to decrypt assertions.
+func NewSamlUsecase(
+	timeout time.Duration,
+	baseURL string,
+	key *rsa.PrivateKey,
+	certificate *x509.Certificate,
+	transactor domain.Transactor,
+	providerRepo domain.SamlProviderRepository,
+	assertionRepo domain.SamlAssertionRepository,
+	userRepo domain.UserRepository,
+	profileRepo domain.ProfileRepository,
+	loginHistory domain.LoginHistoryUsecase,
+) domain.SamlUsecase {
+	return &samlUsecase{
+		contextTimeout: timeout,
+		baseURL:        baseURL,
+		key:            key,
+		certificate:    certificate,
+		transactor:     transactor,
+		providerRepo:   providerRepo,
+		assertionRepo:  assertionRepo,
+		userRepo:       userRepo,
+		profileRepo:    profileRepo,
+		loginHistory:   loginHistory,
+	}
+}
+
+func (s *samlUsecase) Metadata(ctx context.Context, providerUUID string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
+	defer cancel()
+
+	provider, err := s.findProvider(ctx, providerUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := s.serviceProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return xml.MarshalIndent(sp.Metadata(), "", "  ")
+}
+
+/**
+ * Used to start single sign-on. Pseudocode:
+ * - set context.WithTimeout
+ * - check provider of email domain, or of tenant when email is empty
+ * - create AuthnRequest
+ * - create signed relay state carrying request id, so the response can be matched on ACS
+ * - return redirect url to identity provider
+ */
+func (s *samlUsecase) AuthnRequest(ctx context.Context, email string, tenantUUID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
+	defer cancel()
+
+	var query *domain.Query
+	switch {
+	case email != "":
+		query = domain.Where(domain.Eq("email_domain", emailDomain(email)))
+	case tenantUUID != "":
+		if _, err := uuid.Parse(tenantUUID); err != nil {
+			return "", domain.ErrSamlProviderNotFound
+		}
+		query = domain.Where(domain.Eq("tenant_uuid", tenantUUID))
+	default:
+		return "", domain.ErrSamlProviderNotFound
+	}
+
+	provider, err := s.providerRepo.FindOneBy(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	if provider == nil {
+		return "", domain.ErrSamlProviderNotFound
+	}
+
+	sp, err := s.serviceProvider(provider)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", errors.Wrap(err, "make saml authentication request")
+	}
+
+	tk := &jwt.StandardClaims{
+		Id:        req.ID,
+		Subject:   provider.UUID,
+		Audience:  relayStateAudience,
+		ExpiresAt: time.Now().Add(relayStateExpiry).Unix(),
+	}
+	relayState, err := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return "", err
+	}
+
+	redirectURL, err := req.Redirect(relayState, sp)
+	if err != nil {
+		return "", errors.Wrap(err, "make saml redirect url")
+	}
+
+	return redirectURL.String(), nil
+}
+
+/**
+ * Used to login through assertion consumer service. Pseudocode:
+ * - set context.WithTimeout
+ * - verify relay state and take the request id
+ * - check provider in database
+ * - validate response signature, audience, destination, time and request id
+ * - map assertion attributes
+ * - email must belong to provider's email domain, provider of a tenant must have one
+ * - record assertion id until it expires, a replayed assertion is rejected
+ * - provision or sync user and profile in a transaction
+ * - create login token
+ * - login history is written once the assertion carries an email
+ */
+func (s *samlUsecase) Login(ctx context.Context, providerUUID string, samlResponse string, relayState string) (token string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
+	defer cancel()
+
+	tk := new(jwt.StandardClaims)
+	_, err = jwt.ParseWithClaims(relayState, tk, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !tk.VerifyAudience(relayStateAudience, true) || tk.Subject != providerUUID {
+		return "", domain.ErrInvalidSamlResponse
+	}
+
+	provider, err := s.findProvider(ctx, providerUUID)
+	if err != nil {
+		return "", err
+	}
+
+	sp, err := s.serviceProvider(provider)
+	if err != nil {
+		return "", err
+	}
+
+	responseXML, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return "", domain.ErrInvalidSamlResponse
+	}
+
+	assertion, err := sp.ParseXMLResponse(responseXML, []string{tk.Id})
+	if err != nil {
+		if ire, ok := err.(*saml.InvalidResponseError); ok {
+			err = ire.PrivateErr
+		}
+		logrus.Warnf("saml provider %s: %s", provider.UUID, err)
+		return "", domain.ErrInvalidSamlResponse
+	}
+
+	email := assertionAttribute(assertion, provider.EmailAttribute)
+	if email == "" {
+		return "", domain.ErrInvalidSamlResponse
+	}
+
+	var user *domain.User
+	defer func() { s.loginHistory.Record(ctx, domain.LoginSaml, email, user, err) }()
+
+	if provider.EmailDomain == nil {
+		if provider.TenantUUID != nil {
+			logrus.Warnf("saml provider %s: provider of a tenant has no email domain", provider.UUID)
+			return "", domain.ErrInvalidSamlResponse
+		}
+	} else if !strings.EqualFold(emailDomain(email), *provider.EmailDomain) {
+		return "", domain.ErrInvalidSamlResponse
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		recorded, err := s.assertionRepo.Store(ctx, provider.UUID, assertion.ID, assertionExpiry(assertion))
+		if err != nil {
+			return err
+		}
+		if !recorded {
+			logrus.Warnf("saml provider %s: assertion %s is replayed", provider.UUID, assertion.ID)
+			return domain.ErrInvalidSamlResponse
+		}
+
+		provisioned, err := s.provision(ctx, provider, email, assertionAttribute(assertion, provider.FirstNameAttribute), assertionAttribute(assertion, provider.LastNameAttribute))
+		user = provisioned
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return newLoginToken(user)
+}
+
+/**
+ * Used to provision user of identity provider just in time. Pseudocode:
+ * - check user in database
+ * - user of another tenant, or of no tenant when provider has one, is rejected
+ * - if not exist, store a new user with an unusable random password
+ * - activate user
+ * - save or update profile names
+ */
+func (s *samlUsecase) provision(ctx context.Context, provider *domain.SamlProvider, email string, firstName string, lastName string) (*domain.User, error) {
+	checkUser, err := s.userRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("email", email),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if checkUser != nil && !sameTenant(checkUser.TenantUUID, provider.TenantUUID) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	if checkUser == nil {
+		password, err := hashPassword("")
+		if err != nil {
+			return nil, err
+		}
+
+		checkUser, err = s.userRepo.Store(ctx, &domain.User{
+			Email:      email,
+			Password:   password,
+			TenantUUID: provider.TenantUUID,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Store user data")
+		}
+	}
+
+	if !checkUser.IsActive {
+		checkUser.IsActive = true
+		checkUser, err = s.userRepo.Update(ctx, checkUser)
+		if err != nil {
+			return nil, errors.Wrap(err, "Update user data")
+		}
+	}
+
+	if firstName == "" && lastName == "" {
+		return checkUser, nil
+	}
+
+	profile, err := s.profileRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("user_uuid", checkUser.UUID),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if profile == nil {
+		_, err = s.profileRepo.Store(ctx, &domain.Profile{
+			User:      *checkUser,
+			FirstName: stringPointer(firstName),
+			LastName:  stringPointer(lastName),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Store profile data")
+		}
+		return checkUser, nil
+	}
+
+	profile.User = *checkUser
+	profile.FirstName = stringPointer(firstName)
+	profile.LastName = stringPointer(lastName)
+	err = s.profileRepo.Update(ctx, profile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Update profile data")
+	}
+
+	return checkUser, nil
+}
+
+// sameTenant is true when both tenants are empty, or both are the same tenant
+func sameTenant(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// assertionExpiry returns the time after which assertion is rejected anyway, by its issue instant or its conditions
+func assertionExpiry(assertion *saml.Assertion) time.Time {
+	expiresAt := assertion.IssueInstant.Add(saml.MaxIssueDelay)
+	if assertion.Conditions != nil && !assertion.Conditions.NotOnOrAfter.IsZero() {
+		if notOnOrAfter := assertion.Conditions.NotOnOrAfter.Add(saml.MaxClockSkew); notOnOrAfter.Before(expiresAt) {
+			expiresAt = notOnOrAfter
+		}
+	}
+	return expiresAt
+}
+
+func (s *samlUsecase) findProvider(ctx context.Context, providerUUID string) (*domain.SamlProvider, error) {
+	if _, err := uuid.Parse(providerUUID); err != nil {
+		return nil, domain.ErrSamlProviderNotFound
+	}
+
+	provider, err := s.providerRepo.Find(ctx, providerUUID)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, domain.ErrSamlProviderNotFound
+	}
+
+	return provider, nil
+}
+
+// serviceProvider build saml service provider of the given identity provider, each one has its own metadata and ACS url
+func (s *samlUsecase) serviceProvider(provider *domain.SamlProvider) (*saml.ServiceProvider, error) {
+	idpMetadata, err := samlsp.ParseMetadata([]byte(provider.IdpMetadata))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse identity provider metadata")
+	}
+
+	metadataURL, err := url.Parse(s.baseURL + "/saml/" + provider.UUID + "/metadata")
+	if err != nil {
+		return nil, errors.Wrap(err, "parse saml metadata url")
+	}
+
+	acsURL, err := url.Parse(s.baseURL + "/saml/" + provider.UUID + "/acs")
+	if err != nil {
+		return nil, errors.Wrap(err, "parse saml acs url")
+	}
+
+	return &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               s.key,
+		Certificate:       s.certificate,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.EmailAddressNameIDFormat,
+	}, nil
+}
+
+// assertionAttribute returns first value of attribute matched by name or friendly name, or NameID when name is empty
+func assertionAttribute(assertion *saml.Assertion, name string) string {
+	if name == "" {
+		if assertion.Subject != nil && assertion.Subject.NameID != nil {
+			return assertion.Subject.NameID.Value
+		}
+		return ""
+	}
+
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			if (attr.Name == name || attr.FriendlyName == name) && len(attr.Values) > 0 {
+				return attr.Values[0].Value
+			}
+		}
+	}
+
+	return ""
+}
+
+func stringPointer(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
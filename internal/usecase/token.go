@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// loginTokenExpiry is the lifetime of token issued after successful login
+const loginTokenExpiry = time.Minute * 100000
+
+// restrictedTokenExpiry is the lifetime of restricted token issued on login with an expired password
+const restrictedTokenExpiry = time.Minute * 15
+
+// newLoginToken create token of the given logged in user, auth_time is now as credentials have just been checked
+func newLoginToken(user *domain.User) (string, error) {
+	now := time.Now()
+	expiresAt := now.Add(loginTokenExpiry).Unix()
+	tk := &domain.JWToken{
+		UUID:          user.UUID,
+		Email:         user.Email,
+		SecurityStamp: user.SecurityStamp,
+		Role:          user.Role,
+		AuthTime:      now.Unix(),
+		Type:          domain.TokenLogin,
+		StandardClaims: &jwt.StandardClaims{
+			ExpiresAt: expiresAt,
+		},
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// newRestrictedToken create token of user which is only accepted for the operation of scope
+func newRestrictedToken(user *domain.User, scope string) (string, error) {
+	now := time.Now()
+	tk := &domain.JWToken{
+		UUID:          user.UUID,
+		Email:         user.Email,
+		SecurityStamp: user.SecurityStamp,
+		Role:          user.Role,
+		AuthTime:      now.Unix(),
+		Scope:         scope,
+		Type:          domain.TokenLogin,
+		StandardClaims: &jwt.StandardClaims{
+			ExpiresAt: now.Add(restrictedTokenExpiry).Unix(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// newEmailToken create token of user sent by email, it is only accepted by the operation of typ
+func newEmailToken(user *domain.User, typ string, expiry time.Duration) (string, error) {
+	tk := &domain.JWToken{
+		UUID:          user.UUID,
+		Email:         user.Email,
+		SecurityStamp: user.SecurityStamp,
+		Type:          typ,
+		StandardClaims: &jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(expiry).Unix(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk)
+	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return "", errors.Wrap(err, "Sign token")
+	}
+	return tokenString, nil
+}
+
+// checkTokenType rejects token issued for another operation than typ
+func checkTokenType(parsedToken domain.JWToken, typ string) error {
+	if parsedToken.Type != typ {
+		return domain.ErrUnauthorized
+	}
+	return nil
+}
+
+func emailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.ToLower(email[i+1:])
+}
+
+// newSecretToken create random opaque token, only its hash should be persisted
+func newSecretToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.Wrap(err, "generate random token")
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// rotateSecurityStamp replaces security stamp of user, every token issued before is rejected once user is updated.
+// It is done on security events only: activation, password change, email change and logout everywhere,
+// so a token sent by email is used once
+func rotateSecurityStamp(user *domain.User) error {
+	stamp := make([]byte, 20)
+	if _, err := rand.Read(stamp); err != nil {
+		return errors.Wrap(err, "generate security stamp")
+	}
+	user.SecurityStamp = hex.EncodeToString(stamp)
+	return nil
+}
+
+// hashToken returns hex encoded sha256 of the given opaque token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
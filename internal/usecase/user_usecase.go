@@ -2,10 +2,8 @@ package usecase
 
 import (
 	"context"
-	"os"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 
@@ -13,75 +11,177 @@ import (
 )
 
 type userUsecase struct {
-	userRepo       domain.UserRepository
-	contextTimeout time.Duration
+	transactor          domain.Transactor
+	userRepo            domain.UserRepository
+	invitationRepo      domain.InvitationRepository
+	policyRepo          domain.PolicyDocumentRepository
+	consentRepo         domain.ConsentRepository
+	auditRepo           domain.AuditEventRepository
+	outboxRepo          domain.OutboxRepository
+	loginHistory        domain.LoginHistoryUsecase
+	tenantRepo          domain.TenantRepository
+	passwordHistoryRepo domain.PasswordHistoryRepository
+	authenticator       domain.Authenticator
+	registration        domain.RegistrationPolicy
+	password            domain.PasswordPolicy
+	contextTimeout      time.Duration
 }
 
-// NewUserUsecase will create new an userUsecase object representation of domain.UserUsecase interface
-func NewUserUsecase(timeout time.Duration, userRepo domain.UserRepository) domain.UserUsecase {
+// NewUserUsecase will create new an userUsecase object representation of domain.UserUsecase interface.
+// Methods which read and then update a user run in a transaction of transactor, emails are sent through events written to outbox in it
+func NewUserUsecase(
+	timeout time.Duration,
+	transactor domain.Transactor,
+	userRepo domain.UserRepository,
+	invitationRepo domain.InvitationRepository,
+	policyRepo domain.PolicyDocumentRepository,
+	consentRepo domain.ConsentRepository,
+	auditRepo domain.AuditEventRepository,
+	outboxRepo domain.OutboxRepository,
+	loginHistory domain.LoginHistoryUsecase,
+	tenantRepo domain.TenantRepository,
+	passwordHistoryRepo domain.PasswordHistoryRepository,
+	authenticator domain.Authenticator,
+	registration domain.RegistrationPolicy,
+	password domain.PasswordPolicy,
+) domain.UserUsecase {
 	return &userUsecase{
-		contextTimeout: timeout,
-		userRepo:       userRepo,
+		contextTimeout:      timeout,
+		transactor:          transactor,
+		userRepo:            userRepo,
+		invitationRepo:      invitationRepo,
+		policyRepo:          policyRepo,
+		consentRepo:         consentRepo,
+		auditRepo:           auditRepo,
+		outboxRepo:          outboxRepo,
+		loginHistory:        loginHistory,
+		tenantRepo:          tenantRepo,
+		passwordHistoryRepo: passwordHistoryRepo,
+		authenticator:       authenticator,
+		registration:        registration,
+		password:            password,
 	}
 }
 
 /**
  * Used to register a new user. Pseudocode:
  * - set context.WithTimeout
- * - check user input in database
- * - if not exist, do hashing password
- * - do sync data before persist to db
- * - save a new user or update if existing user isActive=false
- * - create token as a key for user activation
+ * - hash password
+ * - in a transaction:
+ *   - check email against registration policy, take invitation if any
+ *   - every current policy document must be accepted
+ *   - check user input in database
+ *   - do sync data before persist to db
+ *   - save a new user or update if existing user isActive=false, rotating its security stamp
+ *   - save consents with client ip address
+ *   - create token as a key for user activation, write user.register event with it to outbox
+ * - write audit event
  */
-func (u *userUsecase) Register(ctx context.Context, user *domain.User) (string, error) {
+func (u *userUsecase) Register(ctx context.Context, user *domain.User) (tokenString string, err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	// check user
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"email": user.Email,
-	}, nil)
-	if err != nil {
-		return "", err
-	}
-	if checkUser != nil && checkUser.IsActive == true {
-		return "", domain.ErrUserAlreadyExist
-	}
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditRegister)
+	audit.set("email", user.Email)
+	defer func() { audit.done(err) }()
 
-	// hash password
+	// hash password, once since the transaction may be run again
 	password, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", errors.Wrap(err, "Password Encryption failed")
 	}
 
-	// Save new user or update data
-	if checkUser == nil {
-		user.Password = string(password)
-		user, err = u.userRepo.Store(ctx, user)
+	var registered *domain.User
+	err = u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// check registration policy
+		invitation, err := u.checkRegistration(ctx, user)
 		if err != nil {
-			return "", errors.Wrap(err, "Store user data")
+			return err
 		}
-	} else {
-		checkUser.Password = string(password)
-		user, err = u.userRepo.Update(ctx, checkUser)
+
+		// check consents
+		documents, err := u.policyRepo.FindCurrent(ctx)
 		if err != nil {
-			return "", errors.Wrap(err, "Update user data")
+			return err
+		}
+		accepted, err := acceptedDocuments(documents, user.Consents)
+		if err != nil {
+			return err
+		}
+		for _, document := range documents {
+			if !containsDocument(accepted, document) {
+				return domain.ErrPolicyNotAccepted
+			}
 		}
-	}
 
-	// create token
-	expiresAt := time.Now().Add(time.Hour * 24 * 30).Unix()
-	tk := &domain.JWToken{
-		UUID:  user.UUID,
-		Email: user.Email,
-		Salt:  user.Salt,
-		StandardClaims: &jwt.StandardClaims{
-			ExpiresAt: expiresAt,
-		},
+		// check user
+		checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("email", user.Email),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser != nil && checkUser.IsActive == true {
+			audit.target(checkUser.UUID)
+			return domain.ErrUserAlreadyExist
+		}
+
+		// Save new user or update data
+		if checkUser == nil {
+			newUser := *user
+			newUser.Password = string(password)
+			registered, err = u.userRepo.Store(ctx, &newUser)
+			if err != nil {
+				return errors.Wrap(err, "Store user data")
+			}
+		} else {
+			checkUser.Password = string(password)
+			checkUser.PasswordChangedAt = time.Now()
+			if err := rotateSecurityStamp(checkUser); err != nil {
+				return err
+			}
+			registered, err = u.userRepo.Update(ctx, checkUser)
+			if err != nil {
+				return errors.Wrap(err, "Update user data")
+			}
+		}
+
+		audit.actor(registered.UUID)
+		audit.target(registered.UUID)
+		if invitation != nil {
+			audit.set("invitation_uuid", invitation.UUID)
+		}
+
+		err = storeConsents(ctx, u.consentRepo, registered.UUID, accepted)
+		if err != nil {
+			return err
+		}
+
+		// invitation can be accepted once
+		if invitation != nil {
+			now := time.Now()
+			invitation.AcceptedAt = &now
+			_, err = u.invitationRepo.Update(ctx, invitation)
+			if err != nil {
+				return errors.Wrap(err, "Update invitation data")
+			}
+		}
+
+		// create token
+		tokenString, err = newEmailToken(registered, domain.TokenActivation, time.Hour*24*30)
+		if err != nil {
+			return err
+		}
+
+		return storeEvent(ctx, u.outboxRepo, domain.UserRegistered{
+			UserUUID:         registered.UUID,
+			EmailDestination: registered.Email,
+			Token:            tokenString,
+		})
+	})
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 
 	return tokenString, nil
 }
@@ -89,246 +189,377 @@ func (u *userUsecase) Register(ctx context.Context, user *domain.User) (string,
 /**
  * Used to login. Pseudocode:
  * - set context.WithTimeout
- * - check user input in database
- * - if exist do compare password
+ * - verify email and password through authenticator (local password or directory)
+ * - if local password has expired, return restricted token which only allows changing password
  * - if match do create token
+ * - write audit event and login history
  */
-func (u *userUsecase) Login(ctx context.Context, user *domain.User) (string, error) {
+func (u *userUsecase) Login(ctx context.Context, user *domain.User) (token string, err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	// check user
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"email":     user.Email,
-		"is_active": true,
-	}, nil)
+	var checkUser *domain.User
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditLogin)
+	audit.set("email", user.Email)
+	defer func() {
+		audit.done(err)
+		u.loginHistory.Record(ctx, domain.LoginPassword, user.Email, checkUser, err)
+	}()
+
+	// check user and password on the primary, a replica may not have the latest activation or password yet
+	ctx = domain.WithReadYourWrites(ctx)
+	checkUser, err = u.authenticator.Authenticate(ctx, user.Email, user.Password)
 	if err != nil {
 		return "", err
 	}
-	if checkUser == nil {
-		return "", domain.ErrUserNotFound
-	}
+	audit.actor(checkUser.UUID)
+	audit.target(checkUser.UUID)
 
-	// check password
-	err = bcrypt.CompareHashAndPassword([]byte(checkUser.Password), []byte(user.Password))
+	// check password expiry
+	expired, err := u.passwordExpired(ctx, checkUser, user.Password)
 	if err != nil {
-		return "", domain.ErrWrongPassword
+		return "", err
 	}
-
-	// create token
-	expiresAt := time.Now().Add(time.Minute * 100000).Unix()
-	tk := &domain.JWToken{
-		UUID:  checkUser.UUID,
-		Email: checkUser.Email,
-		StandardClaims: &jwt.StandardClaims{
-			ExpiresAt: expiresAt,
-		},
+	if expired {
+		token, err = newRestrictedToken(checkUser, domain.ScopePasswordChange)
+		if err != nil {
+			return "", err
+		}
+		return token, domain.ErrPasswordChangeRequired
 	}
-	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 
-	return tokenString, err
+	// create token
+	return newLoginToken(checkUser)
 }
 
 /**
- * Used to change email address. Pseudocode:
+ * Used to confirm credentials again before a sensitive operation. Pseudocode:
  * - set context.WithTimeout
- * - check token user id and email in db
- * - if exist, do compare password
- * - if match, do sync data
- * - update
+ * - check token user id, email, security stamp and is_active=true in db
+ * - verify password through authenticator (local password or directory)
+ * - create token with fresh auth_time
+ * - write audit event
  */
-func (u *userUsecase) ChangeEmail(ctx context.Context, user *domain.User, parsedToken domain.JWToken) error {
+func (u *userUsecase) Reauthenticate(ctx context.Context, password string, parsedToken domain.JWToken) (token string, err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"uuid":      parsedToken.UUID,
-		"email":     parsedToken.Email,
-		"is_active": true,
-	}, nil)
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditReauthenticate)
+	audit.actor(parsedToken.UUID)
+	audit.target(parsedToken.UUID)
+	defer func() { audit.done(err) }()
+
+	if err = checkTokenType(parsedToken, domain.TokenLogin); err != nil {
+		return "", err
+	}
+
+	// security stamp and credentials are checked on the primary, a replica may not have the latest ones yet
+	ctx = domain.WithReadYourWrites(ctx)
+	checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+		domain.Eq("uuid", parsedToken.UUID),
+		domain.Eq("email", parsedToken.Email),
+		domain.Eq("security_stamp", parsedToken.SecurityStamp),
+		domain.Eq("is_active", true),
+	))
 	if err != nil {
-		return err
+		return "", err
 	}
 	if checkUser == nil {
-		return domain.ErrUserNotFound
+		return "", domain.ErrUserNotFound
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(checkUser.Password), []byte(user.Password))
+	checkUser, err = u.authenticator.Authenticate(ctx, checkUser.Email, password)
 	if err != nil {
-		return domain.ErrWrongPassword
+		return "", err
 	}
 
-	checkUser.Email = user.Email
+	return newLoginToken(checkUser)
+}
 
-	_, err = u.userRepo.Update(ctx, checkUser)
-	if err != nil {
+/**
+ * Used to change email address. Pseudocode:
+ * - set context.WithTimeout
+ * - check token user id, email and security stamp in db
+ * - if exist, do compare password
+ * - if match, do sync data and rotate security stamp
+ * - update
+ * - write audit event
+ */
+func (u *userUsecase) ChangeEmail(ctx context.Context, user *domain.User, parsedToken domain.JWToken) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditChangeEmail)
+	audit.actor(parsedToken.UUID)
+	audit.target(parsedToken.UUID)
+	defer func() { audit.done(err) }()
+
+	if err = checkTokenType(parsedToken, domain.TokenLogin); err != nil {
 		return err
 	}
 
-	return nil
+	return u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("uuid", parsedToken.UUID),
+			domain.Eq("email", parsedToken.Email),
+			domain.Eq("security_stamp", parsedToken.SecurityStamp),
+			domain.Eq("is_active", true),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser == nil {
+			return domain.ErrUserNotFound
+		}
+
+		err = bcrypt.CompareHashAndPassword([]byte(checkUser.Password), []byte(user.Password))
+		if err != nil {
+			return domain.ErrWrongPassword
+		}
+
+		before := *checkUser
+		checkUser.Email = user.Email
+		if err := rotateSecurityStamp(checkUser); err != nil {
+			return err
+		}
+		audit.diff(before, *checkUser)
+
+		_, err = u.userRepo.Update(ctx, checkUser)
+		return err
+	})
 }
 
 /**
  * Used to change password. Pseudocode:
  * - set context.WithTimeout
- * - check token user id, email and is_active=true in db
+ * - check token user id, email, security stamp and is_active=true in db
  * - if exist, do compare password
- * - if match, create hash new password
+ * - if match, new password must not be one of the latest passwords
+ * - create hash new password
  * - sync data
  * - update
- * - create token as a key for change password confirmation
+ * - create token as a key for change password confirmation, write user.change_password event with it to outbox
+ * - write audit event
  */
-func (u *userUsecase) ChangePassword(ctx context.Context, user *domain.User, parsedToken domain.JWToken) (string, error) {
+func (u *userUsecase) ChangePassword(ctx context.Context, user *domain.User, parsedToken domain.JWToken) (tokenConfirmation string, err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"uuid":      parsedToken.UUID,
-		"email":     parsedToken.Email,
-		"is_active": true,
-	}, nil)
-	if err != nil {
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditChangePassword)
+	audit.actor(parsedToken.UUID)
+	audit.target(parsedToken.UUID)
+	defer func() { audit.done(err) }()
+
+	if err = checkTokenType(parsedToken, domain.TokenLogin); err != nil {
 		return "", err
 	}
-	if checkUser == nil {
-		return "", domain.ErrUserNotFound
-	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(checkUser.Password), []byte(user.Password))
-	if err != nil {
-		return "", domain.ErrWrongPassword
-	}
+	err = u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("uuid", parsedToken.UUID),
+			domain.Eq("email", parsedToken.Email),
+			domain.Eq("security_stamp", parsedToken.SecurityStamp),
+			domain.Eq("is_active", true),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser == nil {
+			return domain.ErrUserNotFound
+		}
 
-	// hash new password
-	newPassword, err := bcrypt.GenerateFromPassword([]byte(*user.NewPassword), bcrypt.DefaultCost)
-	if err != nil {
-		return "", errors.Wrap(err, "Password Encryption failed")
-	}
+		err = bcrypt.CompareHashAndPassword([]byte(checkUser.Password), []byte(user.Password))
+		if err != nil {
+			return domain.ErrWrongPassword
+		}
+
+		err = u.checkPasswordReuse(ctx, checkUser, *user.NewPassword)
+		if err != nil {
+			return err
+		}
+
+		// hash new password
+		newPassword, err := bcrypt.GenerateFromPassword([]byte(*user.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return errors.Wrap(err, "Password Encryption failed")
+		}
 
-	newPass := string(newPassword)
-	checkUser.NewPassword = &newPass
+		before := *checkUser
+		newPass := string(newPassword)
+		checkUser.NewPassword = &newPass
+		audit.diff(before, *checkUser)
 
-	user, err = u.userRepo.Update(ctx, checkUser)
+		updated, err := u.userRepo.Update(ctx, checkUser)
+		if err != nil {
+			return err
+		}
+
+		tokenConfirmation, err = newEmailToken(updated, domain.TokenPasswordConfirm, time.Minute*60)
+		if err != nil {
+			return err
+		}
+
+		return storeEvent(ctx, u.outboxRepo, domain.PasswordChangeRequested{
+			UserUUID:         updated.UUID,
+			EmailDestination: updated.Email,
+			Token:            tokenConfirmation,
+		})
+	})
 	if err != nil {
 		return "", err
 	}
 
-	expiresAt := time.Now().Add(time.Minute * 60).Unix()
-	tk := &domain.JWToken{
-		UUID:  user.UUID,
-		Email: user.Email,
-		Salt:  user.Salt,
-		StandardClaims: &jwt.StandardClaims{
-			ExpiresAt: expiresAt,
-		},
-	}
-	tok := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk)
-	tokenString, err := tok.SignedString([]byte(os.Getenv("JWT_SECRET")))
-
-	return tokenString, nil
+	return tokenConfirmation, nil
 }
 
 /**
  * Used to activate user after register for first time. Pseudocode:
  * - set context.WithTimeout
- * - check token user uuid, email, salt, is_active=false in db
- * - if match, do sync data
+ * - token must be an activation token
+ * - check token user uuid, email, security stamp, is_active=false in db
+ * - if match, do sync data and rotate security stamp, so the token is used once
  * - update
+ * - write audit event
  */
-func (u *userUsecase) Activation(ctx context.Context, parsedToken domain.JWToken) error {
+func (u *userUsecase) Activation(ctx context.Context, parsedToken domain.JWToken) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"uuid":      parsedToken.UUID,
-		"email":     parsedToken.Email,
-		"salt":      parsedToken.Salt,
-		"is_active": false,
-	}, nil)
-	if err != nil {
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditActivation)
+	audit.actor(parsedToken.UUID)
+	audit.target(parsedToken.UUID)
+	defer func() { audit.done(err) }()
+
+	if err = checkTokenType(parsedToken, domain.TokenActivation); err != nil {
 		return err
 	}
-	if checkUser == nil {
-		return domain.ErrUserNotFound
-	}
 
-	checkUser.IsActive = true
+	return u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("uuid", parsedToken.UUID),
+			domain.Eq("email", parsedToken.Email),
+			domain.Eq("security_stamp", parsedToken.SecurityStamp),
+			domain.Eq("is_active", false),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser == nil {
+			return domain.ErrUserNotFound
+		}
 
-	_, err = u.userRepo.Update(ctx, checkUser)
-	if err != nil {
-		return err
-	}
+		before := *checkUser
+		checkUser.IsActive = true
+		if err := rotateSecurityStamp(checkUser); err != nil {
+			return err
+		}
+		audit.diff(before, *checkUser)
 
-	return nil
+		_, err = u.userRepo.Update(ctx, checkUser)
+		return err
+	})
 }
 
 /**
  * Used to confirm new user password. Pseudocode:
  * - set context.WithTimeout
- * - check token user uuid, email, salt, is_active=true in db
- * - if match, do sync data (password= new_password)
+ * - token must be a password confirm token
+ * - check token user uuid, email, security stamp, is_active=true in db
+ * - if match, keep current password in history
+ * - do sync data (password= new_password) and rotate security stamp
  * - update
+ * - write audit event
  */
-func (u *userUsecase) PasswordConfirm(ctx context.Context, parsedToken domain.JWToken) error {
+func (u *userUsecase) PasswordConfirm(ctx context.Context, parsedToken domain.JWToken) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"uuid":      parsedToken.UUID,
-		"email":     parsedToken.Email,
-		"salt":      parsedToken.Salt,
-		"is_active": true,
-	}, nil)
-	if err != nil {
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditPasswordConfirm)
+	audit.actor(parsedToken.UUID)
+	audit.target(parsedToken.UUID)
+	defer func() { audit.done(err) }()
+
+	if err = checkTokenType(parsedToken, domain.TokenPasswordConfirm); err != nil {
 		return err
 	}
-	if checkUser == nil {
-		return domain.ErrUserNotFound
-	}
 
-	checkUser.Password = *checkUser.NewPassword
+	return u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("uuid", parsedToken.UUID),
+			domain.Eq("email", parsedToken.Email),
+			domain.Eq("security_stamp", parsedToken.SecurityStamp),
+			domain.Eq("is_active", true),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser == nil {
+			return domain.ErrUserNotFound
+		}
 
-	_, err = u.userRepo.Update(ctx, checkUser)
-	if err != nil {
-		return err
-	}
+		if checkUser.NewPassword == nil {
+			return domain.ErrUserNotFound
+		}
+
+		err = u.rememberPassword(ctx, checkUser)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		before := *checkUser
+		checkUser.Password = *checkUser.NewPassword
+		checkUser.PasswordChangedAt = time.Now()
+		if err := rotateSecurityStamp(checkUser); err != nil {
+			return err
+		}
+		audit.diff(before, *checkUser)
+
+		_, err = u.userRepo.Update(ctx, checkUser)
+		return err
+	})
 }
 
 /**
  * Used when user forgot their password. Pseudocode:
  * - set context.WithTimeout
  * - check email in db
- * - if match, return token
+ * - if match, write user.forgot_password event with token to outbox and return token
+ * - write audit event
  */
-func (u *userUsecase) ForgotPasswordRequest(ctx context.Context, email string) (string, error) {
+func (u *userUsecase) ForgotPasswordRequest(ctx context.Context, email string) (token string, err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"email":     email,
-		"is_active": true,
-	}, nil)
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditForgotPasswordRequest)
+	audit.set("email", email)
+	defer func() { audit.done(err) }()
+
+	// user is read on the primary, a replica may not have the activation yet
+	checkUser, err := u.userRepo.FindOneBy(domain.WithReadYourWrites(ctx), domain.Where(
+		domain.Eq("email", email),
+		domain.Eq("is_active", true),
+	))
 	if err != nil {
 		return "", err
 	}
 	if checkUser == nil {
 		return "", domain.ErrUserNotFound
 	}
+	audit.target(checkUser.UUID)
+
+	tokenString, err := newEmailToken(checkUser, domain.TokenForgotPassword, time.Minute*60)
+	if err != nil {
+		return "", err
+	}
 
-	expiresAt := time.Now().Add(time.Minute * 60).Unix()
-	tk := &domain.JWToken{
-		UUID:  checkUser.UUID,
-		Email: checkUser.Email,
-		Salt:  checkUser.Salt,
-		StandardClaims: &jwt.StandardClaims{
-			ExpiresAt: expiresAt,
-		},
+	err = storeEvent(ctx, u.outboxRepo, domain.PasswordResetRequested{
+		UserUUID:         checkUser.UUID,
+		EmailDestination: checkUser.Email,
+		Token:            tokenString,
+	})
+	if err != nil {
+		return "", err
 	}
-	tok := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk)
-	tokenString, err := tok.SignedString([]byte(os.Getenv("JWT_SECRET")))
 
 	return tokenString, nil
 }
@@ -336,33 +567,116 @@ func (u *userUsecase) ForgotPasswordRequest(ctx context.Context, email string) (
 /**
  * Used when user confirm their forgot password via email. Pseudocode:
  * - set context.WithTimeout
- * - check token user uuid, email, salt, is_active=true in db
- * - if match, sync data
+ * - token must be a forgot password token
+ * - check token user uuid, email, security stamp, is_active=true in db
+ * - if match, new password must not be one of the latest passwords
+ * - keep current password in history, hash new password
+ * - sync data and rotate security stamp
  * - update new data or password
+ * - write audit event
  */
-func (u *userUsecase) ForgotPasswordConfirm(ctx context.Context, user *domain.User, parsedToken domain.JWToken) error {
+func (u *userUsecase) ForgotPasswordConfirm(ctx context.Context, user *domain.User, parsedToken domain.JWToken) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	checkUser, err := u.userRepo.FindOneBy(ctx, map[string]interface{}{
-		"uuid":      parsedToken.UUID,
-		"email":     parsedToken.Email,
-		"salt":      parsedToken.Salt,
-		"is_active": true,
-	}, nil)
-	if err != nil {
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditForgotPasswordConfirm)
+	audit.actor(parsedToken.UUID)
+	audit.target(parsedToken.UUID)
+	defer func() { audit.done(err) }()
+
+	if err = checkTokenType(parsedToken, domain.TokenForgotPassword); err != nil {
 		return err
 	}
-	if checkUser == nil {
-		return domain.ErrUserNotFound
-	}
 
-	checkUser.Password = *user.NewPassword
+	return u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("uuid", parsedToken.UUID),
+			domain.Eq("email", parsedToken.Email),
+			domain.Eq("security_stamp", parsedToken.SecurityStamp),
+			domain.Eq("is_active", true),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser == nil {
+			return domain.ErrUserNotFound
+		}
+
+		if user.NewPassword == nil {
+			return domain.ErrStatusUnprocessableEntity
+		}
 
-	_, err = u.userRepo.Update(ctx, checkUser)
-	if err != nil {
+		err = u.checkPasswordReuse(ctx, checkUser, *user.NewPassword)
+		if err != nil {
+			return err
+		}
+
+		newPassword, err := bcrypt.GenerateFromPassword([]byte(*user.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return errors.Wrap(err, "Password Encryption failed")
+		}
+
+		err = u.rememberPassword(ctx, checkUser)
+		if err != nil {
+			return err
+		}
+
+		before := *checkUser
+		checkUser.Password = string(newPassword)
+		checkUser.PasswordChangedAt = time.Now()
+		if err := rotateSecurityStamp(checkUser); err != nil {
+			return err
+		}
+		audit.diff(before, *checkUser)
+
+		_, err = u.userRepo.Update(ctx, checkUser)
+		return err
+	})
+}
+
+/**
+ * Used to revoke every token issued to user, including the one of the request. Pseudocode:
+ * - set context.WithTimeout
+ * - token must be a login token
+ * - check token user id, email, security stamp and is_active=true in db
+ * - rotate security stamp
+ * - update
+ * - write audit event
+ */
+func (u *userUsecase) LogoutEverywhere(ctx context.Context, parsedToken domain.JWToken) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	audit := newAuditRecord(ctx, u.auditRepo, domain.AuditLogoutEverywhere)
+	audit.actor(parsedToken.UUID)
+	audit.target(parsedToken.UUID)
+	defer func() { audit.done(err) }()
+
+	if err = checkTokenType(parsedToken, domain.TokenLogin); err != nil {
 		return err
 	}
 
-	return nil
+	return u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		checkUser, err := u.userRepo.FindOneBy(ctx, domain.Where(
+			domain.Eq("uuid", parsedToken.UUID),
+			domain.Eq("email", parsedToken.Email),
+			domain.Eq("security_stamp", parsedToken.SecurityStamp),
+			domain.Eq("is_active", true),
+		))
+		if err != nil {
+			return err
+		}
+		if checkUser == nil {
+			return domain.ErrUserNotFound
+		}
+
+		before := *checkUser
+		if err := rotateSecurityStamp(checkUser); err != nil {
+			return err
+		}
+		audit.diff(before, *checkUser)
+
+		_, err = u.userRepo.Update(ctx, checkUser)
+		return err
+	})
 }
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// ExportSweeper fails data exports left pending by a build which was stopped, e.g. by a restart of the process
+type ExportSweeper struct {
+	repo     domain.DataExportRepository
+	interval time.Duration
+}
+
+// NewExportSweeper will create new an ExportSweeper worker
+func NewExportSweeper(repo domain.DataExportRepository, interval time.Duration) *ExportSweeper {
+	return &ExportSweeper{repo, interval}
+}
+
+/**
+ * Run fails stale exports until ctx is done. Pseudocode:
+ * - mark failed the exports pending for longer than domain.ExportTimeout, no build can still be running them
+ * - wait for interval, errors are logged and retried after interval
+ */
+func (w *ExportSweeper) Run(ctx context.Context) {
+	for {
+		if _, err := w.Sweep(ctx); err != nil && ctx.Err() == nil {
+			logrus.WithFields(logrus.Fields{
+				"at": time.Now().Format("2006-01-02 15:04:05"),
+			}).Errorln("fail stale data exports: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+// Sweep marks failed the exports pending for longer than domain.ExportTimeout, it returns number of them
+func (w *ExportSweeper) Sweep(ctx context.Context) (int, error) {
+	n, err := w.repo.FailStale(ctx, time.Now().Add(-domain.ExportTimeout), domain.ExportTimedOut)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Printf("failed %d stale data exports", n)
+	}
+	return n, nil
+}
@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+const (
+	// outboxMinBackoff is delay of the first retry of a message which could not be published
+	outboxMinBackoff = time.Second
+	// outboxMaxBackoff caps delay of the retries, a message is retried until it is published
+	outboxMaxBackoff = time.Minute * 10
+)
+
+// OutboxRelay publishes messages of the outbox to the publisher of their routing key. A message is published
+// at least once, it is published again when it can not be marked sent
+type OutboxRelay struct {
+	transactor domain.Transactor
+	repo       domain.OutboxRepository
+	publishers map[string]domain.Publisher
+	interval   time.Duration
+	batchSize  uint
+	lease      time.Duration
+	retention  time.Duration
+}
+
+// NewOutboxRelay will create new an OutboxRelay worker. A batch is claimed for lease, messages of the batch which
+// are not published meanwhile are left to the next batch. Sent messages are kept for retention
+func NewOutboxRelay(
+	transactor domain.Transactor,
+	repo domain.OutboxRepository,
+	publishers map[string]domain.Publisher,
+	interval time.Duration,
+	batchSize uint,
+	lease time.Duration,
+	retention time.Duration,
+) *OutboxRelay {
+	return &OutboxRelay{
+		transactor: transactor,
+		repo:       repo,
+		publishers: publishers,
+		interval:   interval,
+		batchSize:  batchSize,
+		lease:      lease,
+		retention:  retention,
+	}
+}
+
+/**
+ * Run publishes pending messages until ctx is done. Pseudocode:
+ * - relay batches until a batch is not full
+ * - delete messages sent before retention
+ * - wait for interval, errors are logged and retried after interval
+ */
+func (w *OutboxRelay) Run(ctx context.Context) {
+	for {
+		for {
+			n, err := w.Relay(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logrus.WithFields(logrus.Fields{
+						"at": time.Now().Format("2006-01-02 15:04:05"),
+					}).Errorln("relay outbox: ", err)
+				}
+				break
+			}
+			if uint(n) < w.batchSize {
+				break
+			}
+		}
+
+		if _, err := w.repo.DeleteSent(ctx, time.Now().Add(-w.retention)); err != nil && ctx.Err() == nil {
+			logrus.WithFields(logrus.Fields{
+				"at": time.Now().Format("2006-01-02 15:04:05"),
+			}).Errorln("delete sent outbox messages: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+/**
+ * Relay publishes a batch of pending messages, it returns number of messages taken. Pseudocode:
+ * - claim a batch of due messages for lease, skipping the ones claimed by another relay
+ * - publish envelope of each message with its UUID as message id, outside of any transaction
+ * - messages left when the lease is over are not published, they are released for the next batch
+ * - in a transaction, mark published message sent, or record the error and retry it after a backoff
+ */
+func (w *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	messages, err := w.repo.Claim(ctx, w.batchSize, w.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	claimedAt := time.Now()
+	for _, message := range messages {
+		if time.Since(claimedAt) >= w.lease || ctx.Err() != nil {
+			break
+		}
+		w.publish(message)
+	}
+
+	err = w.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, message := range messages {
+			if _, err := w.repo.Update(ctx, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(messages), err
+}
+
+func (w *OutboxRelay) publish(message *domain.OutboxMessage) {
+	message.Attempts++
+
+	body, err := json.Marshal(message.Envelope())
+	if err == nil {
+		err = fmt.Errorf("no publisher of routing key %s", message.RoutingKey)
+		if publisher, ok := w.publishers[message.RoutingKey]; ok {
+			err = publisher.Publish(string(body), message.RoutingKey, map[string]interface{}{domain.MessageIDHeader: message.UUID})
+		}
+	}
+
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"at": time.Now().Format("2006-01-02 15:04:05"),
+		}).Errorf("publish outbox message %s: %s", message.UUID, err)
+
+		lastError := err.Error()
+		message.LastError = &lastError
+		message.NextAttemptAt = time.Now().Add(outboxBackoff(message.Attempts))
+		return
+	}
+
+	now := time.Now()
+	message.SentAt = &now
+	message.LastError = nil
+}
+
+// outboxBackoff doubles delay of each failed attempt up to outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxMinBackoff
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return delay
+}
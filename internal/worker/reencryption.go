@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// Reencryption moves profiles encrypted by a retired master key, or not encrypted yet, to the current master key
+type Reencryption struct {
+	repo      domain.ProfileKeyRepository
+	interval  time.Duration
+	batchSize uint
+}
+
+// NewReencryption will create new a Reencryption worker
+func NewReencryption(repo domain.ProfileKeyRepository, interval time.Duration, batchSize uint) *Reencryption {
+	return &Reencryption{repo, interval, batchSize}
+}
+
+/**
+ * Run re-encrypts profiles until ctx is done. Pseudocode:
+ * - re-encrypt batches until a batch is not full, every profile uses the current key then
+ * - wait for interval, new keys are picked up on restart only so this catches rows written by older instances
+ * - errors are logged and retried after interval
+ */
+func (w *Reencryption) Run(ctx context.Context) {
+	for {
+		for {
+			n, err := w.repo.ReencryptBatch(ctx, w.batchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					logrus.WithFields(logrus.Fields{
+						"at": time.Now().Format("2006-01-02 15:04:05"),
+					}).Errorln("re-encrypt profiles: ", err)
+				}
+				break
+			}
+			if n > 0 {
+				logrus.WithFields(logrus.Fields{
+					"at": time.Now().Format("2006-01-02 15:04:05"),
+				}).Printf("re-encrypted %d profiles", n)
+			}
+			if uint(n) < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.interval):
+		}
+	}
+}
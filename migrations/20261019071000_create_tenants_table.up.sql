@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    name VARCHAR(255) NOT NULL CHECK (name <> ''),
+    scim_token VARCHAR(64) UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TRIGGER set_timestamp BEFORE UPDATE ON tenants FOR EACH ROW EXECUTE PROCEDURE  trigger_set_timestamp();
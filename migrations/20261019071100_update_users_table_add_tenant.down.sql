@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_tenant_uuid_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_uuid;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_uuid uuid REFERENCES tenants(uuid) ON DELETE RESTRICT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS users_tenant_uuid_idx ON users (tenant_uuid);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS groups (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    tenant_uuid uuid NOT NULL REFERENCES tenants(uuid) ON DELETE RESTRICT,
+    display_name VARCHAR(255) NOT NULL CHECK (display_name <> ''),
+    external_id VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid),
+    UNIQUE (tenant_uuid, display_name)
+);
+
+CREATE TRIGGER set_timestamp BEFORE UPDATE ON groups FOR EACH ROW EXECUTE PROCEDURE  trigger_set_timestamp();
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_uuid uuid NOT NULL REFERENCES groups(uuid) ON DELETE CASCADE,
+    user_uuid uuid NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (group_uuid, user_uuid)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS saml_providers (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    tenant_uuid uuid REFERENCES tenants(uuid) ON DELETE RESTRICT UNIQUE,
+    email_domain VARCHAR(255) UNIQUE,
+    idp_metadata TEXT NOT NULL CHECK (idp_metadata <> ''),
+    email_attribute VARCHAR(255) NOT NULL DEFAULT '',
+    first_name_attribute VARCHAR(255) NOT NULL DEFAULT 'givenName',
+    last_name_attribute VARCHAR(255) NOT NULL DEFAULT 'sn',
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid),
+    CHECK (tenant_uuid IS NOT NULL OR email_domain IS NOT NULL)
+);
+
+CREATE TRIGGER set_timestamp BEFORE UPDATE ON saml_providers FOR EACH ROW EXECUTE PROCEDURE  trigger_set_timestamp();
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    email VARCHAR(255) NOT NULL CHECK (email <> ''),
+    token VARCHAR(64) NOT NULL UNIQUE,
+    invited_by uuid REFERENCES users(uuid) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS invitations_email_idx ON invitations (email);
+
+CREATE TRIGGER set_timestamp BEFORE UPDATE ON invitations FOR EACH ROW EXECUTE PROCEDURE  trigger_set_timestamp();
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS policy_documents (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    kind VARCHAR(50) NOT NULL CHECK (kind <> ''),
+    version VARCHAR(50) NOT NULL CHECK (version <> ''),
+    url VARCHAR(2048) NOT NULL CHECK (url <> ''),
+    published_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid),
+    UNIQUE (kind, version)
+);
+
+CREATE INDEX IF NOT EXISTS policy_documents_kind_published_at_idx ON policy_documents (kind, published_at DESC);
+
+CREATE TRIGGER set_timestamp BEFORE UPDATE ON policy_documents FOR EACH ROW EXECUTE PROCEDURE  trigger_set_timestamp();
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS consents (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    user_uuid uuid NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    document_uuid uuid NOT NULL REFERENCES policy_documents(uuid) ON DELETE RESTRICT,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    accepted_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid),
+    UNIQUE (user_uuid, document_uuid)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    user_uuid uuid NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    file_path TEXT,
+    error TEXT,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    updated_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_uuid_idx ON data_exports (user_uuid);
+
+CREATE TRIGGER set_timestamp BEFORE UPDATE ON data_exports FOR EACH ROW EXECUTE PROCEDURE  trigger_set_timestamp();
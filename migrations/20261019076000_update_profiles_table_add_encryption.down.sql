@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS profiles_key_version_idx;
+DROP INDEX IF EXISTS profiles_phone_bidx_idx;
+
+ALTER TABLE profiles
+  DROP COLUMN IF EXISTS phone_enc,
+  DROP COLUMN IF EXISTS address_enc,
+  DROP COLUMN IF EXISTS dob_enc,
+  DROP COLUMN IF EXISTS data_key,
+  DROP COLUMN IF EXISTS key_version,
+  DROP COLUMN IF EXISTS phone_bidx;
@@ -0,0 +1,10 @@
+ALTER TABLE profiles
+  ADD COLUMN phone_enc BYTEA,
+  ADD COLUMN address_enc BYTEA,
+  ADD COLUMN dob_enc BYTEA,
+  ADD COLUMN data_key BYTEA,
+  ADD COLUMN key_version INTEGER,
+  ADD COLUMN phone_bidx VARCHAR(64);
+
+CREATE INDEX profiles_phone_bidx_idx ON profiles (phone_bidx);
+CREATE INDEX profiles_key_version_idx ON profiles (key_version);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    seq BIGSERIAL NOT NULL UNIQUE,
+    actor_uuid uuid,
+    target_uuid uuid,
+    action VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL CHECK (outcome IN ('success', 'failure')),
+    reason TEXT,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    metadata JSON NOT NULL DEFAULT '{}',
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_action_idx ON audit_events (action);
+CREATE INDEX IF NOT EXISTS audit_events_actor_uuid_idx ON audit_events (actor_uuid);
+CREATE INDEX IF NOT EXISTS audit_events_target_uuid_idx ON audit_events (target_uuid);
+
+-- audit events are append-only
+CREATE OR REPLACE FUNCTION trigger_audit_events_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER append_only BEFORE UPDATE OR DELETE ON audit_events FOR EACH ROW EXECUTE PROCEDURE trigger_audit_events_append_only();
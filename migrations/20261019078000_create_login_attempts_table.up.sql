@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    user_uuid uuid REFERENCES users(uuid) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    method VARCHAR(20) NOT NULL,
+    success BOOLEAN NOT NULL,
+    failure_reason TEXT,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    browser TEXT NOT NULL DEFAULT '',
+    browser_version TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    device VARCHAR(20) NOT NULL DEFAULT '',
+    device_fingerprint VARCHAR(64) NOT NULL,
+    country_code CHAR(2),
+    country VARCHAR(100),
+    city VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_user_uuid_created_at_idx ON login_attempts (user_uuid, created_at DESC);
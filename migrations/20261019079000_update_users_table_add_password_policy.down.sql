@@ -0,0 +1,5 @@
+ALTER TABLE tenants
+  DROP COLUMN IF EXISTS password_history_size,
+  DROP COLUMN IF EXISTS password_max_age_days;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
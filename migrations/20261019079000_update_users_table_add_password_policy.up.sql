@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp;
+
+ALTER TABLE tenants
+  ADD COLUMN password_history_size INTEGER CHECK (password_history_size >= 0),
+  ADD COLUMN password_max_age_days INTEGER CHECK (password_max_age_days >= 0);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS password_history (
+    uuid uuid DEFAULT uuid_generate_v4 (),
+    user_uuid uuid NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL default current_timestamp,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS password_history_user_uuid_created_at_idx ON password_history (user_uuid, created_at DESC);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS consents_user_uuid_accepted_at_uuid_idx;
+
+DROP INDEX IF EXISTS audit_events_created_at_uuid_idx;
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+
+DROP INDEX IF EXISTS login_attempts_user_uuid_created_at_uuid_idx;
+CREATE INDEX IF NOT EXISTS login_attempts_user_uuid_created_at_idx ON login_attempts (user_uuid, created_at DESC);
+
+DROP INDEX IF EXISTS groups_created_at_uuid_idx;
+DROP INDEX IF EXISTS profiles_created_at_uuid_idx;
+DROP INDEX IF EXISTS users_created_at_uuid_idx;
@@ -0,0 +1,11 @@
+CREATE INDEX IF NOT EXISTS users_created_at_uuid_idx ON users (created_at, uuid);
+CREATE INDEX IF NOT EXISTS profiles_created_at_uuid_idx ON profiles (created_at, uuid);
+CREATE INDEX IF NOT EXISTS groups_created_at_uuid_idx ON groups (created_at, uuid);
+
+DROP INDEX IF EXISTS login_attempts_user_uuid_created_at_idx;
+CREATE INDEX IF NOT EXISTS login_attempts_user_uuid_created_at_uuid_idx ON login_attempts (user_uuid, created_at, uuid);
+
+DROP INDEX IF EXISTS audit_events_created_at_idx;
+CREATE INDEX IF NOT EXISTS audit_events_created_at_uuid_idx ON audit_events (created_at, uuid);
+
+CREATE INDEX IF NOT EXISTS consents_user_uuid_accepted_at_uuid_idx ON consents (user_uuid, accepted_at, uuid);
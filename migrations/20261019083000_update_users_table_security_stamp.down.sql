@@ -0,0 +1,11 @@
+ALTER TABLE users RENAME COLUMN security_stamp TO salt;
+
+CREATE OR REPLACE FUNCTION trigger_salt_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.salt = digest(NEW.uuid || random()::text || clock_timestamp()::text, 'sha1');
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_salt BEFORE UPDATE ON users FOR EACH ROW EXECUTE PROCEDURE trigger_salt_update();
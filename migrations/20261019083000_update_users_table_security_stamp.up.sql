@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS update_salt ON users;
+
+DROP FUNCTION IF EXISTS trigger_salt_update();
+
+ALTER TABLE users RENAME COLUMN salt TO security_stamp;
@@ -0,0 +1,2 @@
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255);
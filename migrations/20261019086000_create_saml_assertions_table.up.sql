@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS saml_assertions (
+    provider_uuid uuid NOT NULL REFERENCES saml_providers(uuid) ON DELETE CASCADE,
+    assertion_id VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (provider_uuid, assertion_id)
+);
+
+CREATE INDEX IF NOT EXISTS saml_assertions_expires_at_idx ON saml_assertions (expires_at);
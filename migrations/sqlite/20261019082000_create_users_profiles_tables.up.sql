@@ -0,0 +1,44 @@
+-- uuid, salt, version and timestamps of users and profiles are generated by the sqlite repositories,
+-- they replace uuid_generate_v4, trigger_set_timestamp and trigger_salt_update of postgres
+CREATE TABLE IF NOT EXISTS users (
+    uuid TEXT NOT NULL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL CHECK (email <> '') UNIQUE,
+    password VARCHAR(255) NOT NULL CHECK (password <> ''),
+    new_password VARCHAR(255) CHECK (password <> ''),
+    is_active BOOLEAN NOT NULL DEFAULT FALSE,
+    role VARCHAR(50) NOT NULL DEFAULT 'user',
+    tenant_uuid TEXT REFERENCES tenants(uuid) ON DELETE RESTRICT,
+    external_id VARCHAR(255),
+    salt VARCHAR(255) NOT NULL,
+    password_changed_at TIMESTAMP NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS users_tenant_uuid_idx ON users (tenant_uuid);
+CREATE INDEX IF NOT EXISTS users_created_at_uuid_idx ON users (created_at, uuid);
+
+CREATE TABLE IF NOT EXISTS profiles (
+    uuid TEXT NOT NULL PRIMARY KEY,
+    user_uuid TEXT NOT NULL REFERENCES users(uuid) ON DELETE RESTRICT UNIQUE,
+    first_name VARCHAR(255),
+    last_name VARCHAR(255),
+    address VARCHAR(255),
+    phone VARCHAR(21),
+    gender CHAR(1) CHECK (gender IN ('m', 'f')),
+    dob DATE,
+    phone_enc BLOB,
+    address_enc BLOB,
+    dob_enc BLOB,
+    data_key BLOB,
+    key_version INTEGER,
+    phone_bidx VARCHAR(64),
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS profiles_phone_bidx_idx ON profiles (phone_bidx);
+CREATE INDEX IF NOT EXISTS profiles_key_version_idx ON profiles (key_version);
+CREATE INDEX IF NOT EXISTS profiles_created_at_uuid_idx ON profiles (created_at, uuid);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS password_history;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS consents;
+DROP TABLE IF EXISTS policy_documents;
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS saml_providers;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS tenants;
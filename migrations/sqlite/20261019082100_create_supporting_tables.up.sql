@@ -0,0 +1,190 @@
+-- tables of the sqlx repositories, uuid and timestamps are generated by sqlite as postgres does.
+-- Timestamps are written in the format the sqlite3 driver writes UTC times, so they compare with bound values as text.
+-- updated_at is set after an update, a RETURNING updated_at clause returns the previous value
+CREATE TABLE IF NOT EXISTS tenants (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    name VARCHAR(255) NOT NULL CHECK (name <> ''),
+    scim_token VARCHAR(64) UNIQUE,
+    password_history_size INTEGER CHECK (password_history_size >= 0),
+    password_max_age_days INTEGER CHECK (password_max_age_days >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00')
+);
+
+CREATE TRIGGER IF NOT EXISTS tenants_set_timestamp AFTER UPDATE ON tenants FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE tenants SET updated_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00' WHERE uuid = NEW.uuid;
+END;
+
+CREATE TABLE IF NOT EXISTS groups (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    tenant_uuid TEXT NOT NULL REFERENCES tenants(uuid) ON DELETE RESTRICT,
+    display_name VARCHAR(255) NOT NULL CHECK (display_name <> ''),
+    external_id VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    UNIQUE (tenant_uuid, display_name)
+);
+
+CREATE INDEX IF NOT EXISTS groups_created_at_uuid_idx ON groups (created_at, uuid);
+
+CREATE TRIGGER IF NOT EXISTS groups_set_timestamp AFTER UPDATE ON groups FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE groups SET updated_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00' WHERE uuid = NEW.uuid;
+END;
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_uuid TEXT NOT NULL REFERENCES groups(uuid) ON DELETE CASCADE,
+    user_uuid TEXT NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    PRIMARY KEY (group_uuid, user_uuid)
+);
+
+CREATE TABLE IF NOT EXISTS saml_providers (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    tenant_uuid TEXT REFERENCES tenants(uuid) ON DELETE RESTRICT UNIQUE,
+    email_domain VARCHAR(255) UNIQUE,
+    idp_metadata TEXT NOT NULL CHECK (idp_metadata <> ''),
+    email_attribute VARCHAR(255) NOT NULL DEFAULT '',
+    first_name_attribute VARCHAR(255) NOT NULL DEFAULT 'givenName',
+    last_name_attribute VARCHAR(255) NOT NULL DEFAULT 'sn',
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    CHECK (tenant_uuid IS NOT NULL OR email_domain IS NOT NULL)
+);
+
+CREATE TRIGGER IF NOT EXISTS saml_providers_set_timestamp AFTER UPDATE ON saml_providers FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE saml_providers SET updated_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00' WHERE uuid = NEW.uuid;
+END;
+
+CREATE TABLE IF NOT EXISTS invitations (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    email VARCHAR(255) NOT NULL CHECK (email <> ''),
+    token VARCHAR(64) NOT NULL UNIQUE,
+    invited_by TEXT REFERENCES users(uuid) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00')
+);
+
+CREATE INDEX IF NOT EXISTS invitations_email_idx ON invitations (email);
+
+CREATE TRIGGER IF NOT EXISTS invitations_set_timestamp AFTER UPDATE ON invitations FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE invitations SET updated_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00' WHERE uuid = NEW.uuid;
+END;
+
+CREATE TABLE IF NOT EXISTS policy_documents (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    kind VARCHAR(50) NOT NULL CHECK (kind <> ''),
+    version VARCHAR(50) NOT NULL CHECK (version <> ''),
+    url VARCHAR(2048) NOT NULL CHECK (url <> ''),
+    published_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    UNIQUE (kind, version)
+);
+
+CREATE INDEX IF NOT EXISTS policy_documents_kind_published_at_idx ON policy_documents (kind, published_at DESC);
+
+CREATE TRIGGER IF NOT EXISTS policy_documents_set_timestamp AFTER UPDATE ON policy_documents FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE policy_documents SET updated_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00' WHERE uuid = NEW.uuid;
+END;
+
+CREATE TABLE IF NOT EXISTS consents (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    user_uuid TEXT NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    document_uuid TEXT NOT NULL REFERENCES policy_documents(uuid) ON DELETE RESTRICT,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    accepted_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    UNIQUE (user_uuid, document_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS consents_user_uuid_accepted_at_uuid_idx ON consents (user_uuid, accepted_at, uuid);
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    user_uuid TEXT NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    file_path TEXT,
+    error TEXT,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00')
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_uuid_idx ON data_exports (user_uuid);
+
+CREATE TRIGGER IF NOT EXISTS data_exports_set_timestamp AFTER UPDATE ON data_exports FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE data_exports SET updated_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00' WHERE uuid = NEW.uuid;
+END;
+
+-- seq is the rowid, sqlite has no second auto increment column
+CREATE TABLE IF NOT EXISTS audit_events (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid TEXT NOT NULL UNIQUE,
+    actor_uuid TEXT,
+    target_uuid TEXT,
+    action VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL CHECK (outcome IN ('success', 'failure')),
+    reason TEXT,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    metadata TEXT NOT NULL DEFAULT '{}',
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_uuid_idx ON audit_events (created_at, uuid);
+CREATE INDEX IF NOT EXISTS audit_events_action_idx ON audit_events (action);
+CREATE INDEX IF NOT EXISTS audit_events_actor_uuid_idx ON audit_events (actor_uuid);
+CREATE INDEX IF NOT EXISTS audit_events_target_uuid_idx ON audit_events (target_uuid);
+
+-- audit events are append-only
+CREATE TRIGGER IF NOT EXISTS audit_events_append_only_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_events_append_only_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    user_uuid TEXT REFERENCES users(uuid) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    method VARCHAR(20) NOT NULL,
+    success BOOLEAN NOT NULL,
+    failure_reason TEXT,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    browser TEXT NOT NULL DEFAULT '',
+    browser_version TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    device VARCHAR(20) NOT NULL DEFAULT '',
+    device_fingerprint VARCHAR(64) NOT NULL,
+    country_code CHAR(2),
+    country VARCHAR(100),
+    city VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00')
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_user_uuid_created_at_uuid_idx ON login_attempts (user_uuid, created_at, uuid);
+
+CREATE TABLE IF NOT EXISTS password_history (
+    uuid TEXT NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    user_uuid TEXT NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00')
+);
+
+CREATE INDEX IF NOT EXISTS password_history_user_uuid_created_at_idx ON password_history (user_uuid, created_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE users RENAME COLUMN security_stamp TO salt;
@@ -0,0 +1 @@
+ALTER TABLE users RENAME COLUMN salt TO security_stamp;
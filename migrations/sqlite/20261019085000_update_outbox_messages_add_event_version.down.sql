@@ -0,0 +1,2 @@
+ALTER TABLE outbox_messages DROP COLUMN correlation_id;
+ALTER TABLE outbox_messages DROP COLUMN version;
@@ -0,0 +1,2 @@
+ALTER TABLE outbox_messages ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE outbox_messages ADD COLUMN correlation_id VARCHAR(255);
@@ -0,0 +1 @@
+ALTER TABLE outbox_messages ADD COLUMN locked_until TIMESTAMP;
@@ -0,0 +1,132 @@
+package contract_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/pkg/cache"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+// backend creates empty user and profile repositories of a storage
+type backend struct {
+	name string
+	new  func(t *testing.T) (domain.UserRepository, domain.ProfileRepository)
+}
+
+var backends = []backend{
+	{
+		name: "memory",
+		new: func(t *testing.T) (domain.UserRepository, domain.ProfileRepository) {
+			userRepo := repository.NewUserMemoryRepository()
+			return userRepo, repository.NewProfileMemoryRepository(userRepo)
+		},
+	},
+	{
+		name: "cache",
+		new: func(t *testing.T) (domain.UserRepository, domain.ProfileRepository) {
+			userRepo := repository.NewUserMemoryRepository()
+			lru := cache.NewLRU(100, time.Minute)
+			return repository.NewUserCacheRepository(userRepo, lru, nil, "contract"),
+				repository.NewProfileCacheRepository(repository.NewProfileMemoryRepository(userRepo), lru, nil, "contract")
+		},
+	},
+	{
+		name: "sqlite",
+		new: func(t *testing.T) (domain.UserRepository, domain.ProfileRepository) {
+			dbConn := openSqlite(t)
+			return repository.NewUserSqliteRepository(dbConn), repository.NewProfileSqliteRepository(dbConn, nil)
+		},
+	},
+}
+
+// TestMain adds the sqlx backend when CONTRACT_DATABASE_URL is set.
+// Its tables are truncated by every test, so it must not be the database of the integration tests
+func TestMain(m *testing.M) {
+	url := os.Getenv("CONTRACT_DATABASE_URL")
+	if url == "" {
+		log.Println("CONTRACT_DATABASE_URL is not set, sqlx repositories are not checked")
+		os.Exit(m.Run())
+	}
+
+	os.Setenv("DATABASE_URL", url)
+	sqlxConf := config.NewSqlx()
+
+	dbConn, err := sqlxConf.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = sqlxConf.MigrateUp("file://../../migrations")
+	if err != nil && fmt.Sprintf("%s", err) != fmt.Sprintf("%s", migrate.ErrNoChange) {
+		log.Fatal(err)
+	}
+
+	backends = append(backends, backend{
+		name: "sqlx",
+		new: func(t *testing.T) (domain.UserRepository, domain.ProfileRepository) {
+			truncate(t, dbConn)
+			return repository.NewUserSqlxRepository(dbConn), repository.NewProfileSqlxRepository(dbConn, nil)
+		},
+	})
+
+	code := m.Run()
+
+	if err := sqlxConf.Close(dbConn); err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(code)
+}
+
+// openSqlite opens a new sqlite database migrated up in a temporary directory, it is removed when the test finishes
+func openSqlite(t *testing.T) *sqlx.DB {
+	dir, err := ioutil.TempDir("", "user-contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("DATABASE_URL", filepath.Join(dir, "user.db")+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate")
+	defer os.Unsetenv("DB_DRIVER")
+	sqlxConf := config.NewSqlx()
+
+	dbConn, err := sqlxConf.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlxConf.Close(dbConn) })
+
+	if err := sqlxConf.MigrateUp(sqlxConf.Migrations("../..")); err != nil {
+		t.Fatalf("error migrating sqlite database: %v", err)
+	}
+	return dbConn
+}
+
+func truncate(t *testing.T, dbConn *sqlx.DB) {
+	if err := dbfixture.Truncate(dbConn); err != nil {
+		t.Fatalf("error truncating test database tables: %v", err)
+	}
+}
+
+// forEachBackend runs the contract test against repositories of every backend
+func forEachBackend(t *testing.T, test func(t *testing.T, userRepo domain.UserRepository, profileRepo domain.ProfileRepository)) {
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			userRepo, profileRepo := b.new(t)
+			test(t, userRepo, profileRepo)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package contract_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+func TestProfileRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, userRepo domain.UserRepository, profileRepo domain.ProfileRepository) {
+		var (
+			users  = storeUsers(t, userRepo, "alice@mail.com", "bob@mail.com", "carol@mail.com")
+			alice  = "Alice"
+			female = "f"
+			dob    = time.Date(1990, time.March, 4, 0, 0, 0, 0, time.UTC)
+		)
+
+		var stored *domain.Profile
+		t.Run("success store", func(t *testing.T) {
+			var err error
+
+			stored, err = profileRepo.Store(context.TODO(), &domain.Profile{User: *users[0], FirstName: &alice, Gender: &female, Dob: &dob})
+			require.NoError(t, err)
+			assert.NotEmpty(t, stored.UUID)
+			assert.Equal(t, users[0].UUID, stored.UserUUID)
+			assert.Equal(t, 1, stored.Version)
+
+			found, err := profileRepo.FindOneBy(context.TODO(), domain.Where(domain.Eq("user_uuid", users[0].UUID)))
+			require.NoError(t, err)
+			require.NotNil(t, found)
+			assert.Equal(t, stored.UUID, found.UUID)
+			require.NotNil(t, found.FirstName)
+			assert.Equal(t, alice, *found.FirstName)
+			require.NotNil(t, found.Dob)
+			assert.Equal(t, dob.Format("2006-01-02"), found.Dob.Format("2006-01-02"))
+			assert.Empty(t, found.User.UUID)
+		})
+
+		t.Run("error second profile of user", func(t *testing.T) {
+			_, err := profileRepo.Store(context.TODO(), &domain.Profile{User: *users[0]})
+			assert.Error(t, err)
+		})
+
+		t.Run("error unknown user", func(t *testing.T) {
+			_, err := profileRepo.Store(context.TODO(), &domain.Profile{User: domain.User{UUID: "00000000-0000-0000-0000-000000000000"}})
+			assert.Error(t, err)
+		})
+
+		t.Run("error invalid gender", func(t *testing.T) {
+			gender := "x"
+			_, err := profileRepo.Store(context.TODO(), &domain.Profile{User: *users[1], Gender: &gender})
+			assert.Error(t, err)
+		})
+
+		t.Run("success update", func(t *testing.T) {
+			profile, err := profileRepo.Find(context.TODO(), stored.UUID)
+			require.NoError(t, err)
+			require.NotNil(t, profile)
+
+			name := "Alicia"
+			profile.User = *users[0]
+			profile.FirstName = &name
+			require.NoError(t, profileRepo.Update(context.TODO(), profile))
+			assert.Equal(t, 2, profile.Version)
+
+			found, err := profileRepo.Find(context.TODO(), stored.UUID)
+			require.NoError(t, err)
+			assert.Equal(t, 2, found.Version)
+			assert.Equal(t, name, *found.FirstName)
+		})
+
+		t.Run("error stale version", func(t *testing.T) {
+			stale := *stored
+			stale.User = *users[0]
+
+			assert.Equal(t, domain.ErrConflict, errors.Cause(profileRepo.Update(context.TODO(), &stale)))
+		})
+
+		t.Run("error unknown profile", func(t *testing.T) {
+			unknown := *stored
+			unknown.User = *users[0]
+			unknown.UUID = "00000000-0000-0000-0000-000000000000"
+
+			assert.Equal(t, domain.ErrProfileNotFound, errors.Cause(profileRepo.Update(context.TODO(), &unknown)))
+		})
+
+		t.Run("success query sorts null last", func(t *testing.T) {
+			bob := "bob"
+			_, err := profileRepo.Store(context.TODO(), &domain.Profile{User: *users[1], FirstName: &bob})
+			require.NoError(t, err)
+			_, err = profileRepo.Store(context.TODO(), &domain.Profile{User: *users[2]})
+			require.NoError(t, err)
+
+			found, err := profileRepo.FindBy(context.TODO(), domain.Where().OrderBy(domain.Asc("first_name")))
+			require.NoError(t, err)
+			require.Len(t, found, 3)
+			assert.Equal(t, "Alicia", *found[0].FirstName)
+			assert.Equal(t, "bob", *found[1].FirstName)
+			assert.Nil(t, found[2].FirstName)
+
+			found, err = profileRepo.FindBy(context.TODO(), domain.Where(domain.Like("first_name", "B%")))
+			require.NoError(t, err)
+			require.Len(t, found, 1)
+			assert.Equal(t, users[1].UUID, found[0].UserUUID)
+
+			count, err := profileRepo.Count(context.TODO(), domain.Where(domain.IsNull("first_name")))
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+	})
+}
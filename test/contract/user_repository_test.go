@@ -0,0 +1,277 @@
+package contract_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+func storeUsers(t *testing.T, userRepo domain.UserRepository, emails ...string) []*domain.User {
+	users := make([]*domain.User, len(emails))
+	for i, email := range emails {
+		user, err := userRepo.Store(context.TODO(), &domain.User{Email: email, Password: "hashed"})
+		require.NoError(t, err)
+		users[i] = user
+	}
+	return users
+}
+
+func emailsOf(users []*domain.User) []string {
+	emails := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = user.Email
+	}
+	return emails
+}
+
+func TestUserRepositoryStore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, userRepo domain.UserRepository, _ domain.ProfileRepository) {
+		t.Run("success generated columns", func(t *testing.T) {
+			user, err := userRepo.Store(context.TODO(), &domain.User{Email: "alice@mail.com", Password: "hashed"})
+			require.NoError(t, err)
+			assert.NotEmpty(t, user.UUID)
+			assert.NotEmpty(t, user.SecurityStamp)
+			assert.Equal(t, domain.RoleUser, user.Role)
+			assert.Equal(t, 1, user.Version)
+			assert.False(t, user.CreatedAt.IsZero())
+			assert.False(t, user.PasswordChangedAt.IsZero())
+
+			found, err := userRepo.Find(context.TODO(), user.UUID)
+			require.NoError(t, err)
+			require.NotNil(t, found)
+			assert.Equal(t, "alice@mail.com", found.Email)
+			assert.False(t, found.IsActive)
+			assert.Equal(t, user.SecurityStamp, found.SecurityStamp)
+			assert.True(t, user.CreatedAt.Equal(found.CreatedAt))
+		})
+
+		t.Run("success find unknown user", func(t *testing.T) {
+			found, err := userRepo.Find(context.TODO(), "00000000-0000-0000-0000-000000000000")
+			assert.NoError(t, err)
+			assert.Nil(t, found)
+
+			found, err = userRepo.FindOneBy(context.TODO(), domain.Where(domain.Eq("email", "nobody@mail.com")))
+			assert.NoError(t, err)
+			assert.Nil(t, found)
+		})
+
+		t.Run("error duplicate email", func(t *testing.T) {
+			_, err := userRepo.Store(context.TODO(), &domain.User{Email: "alice@mail.com", Password: "hashed"})
+			assert.Error(t, err)
+
+			count, err := userRepo.Count(context.TODO(), domain.Where(domain.Eq("email", "alice@mail.com")))
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+
+		t.Run("success concurrent stores of the same email", func(t *testing.T) {
+			var (
+				wg   sync.WaitGroup
+				errs = make([]error, 8)
+			)
+
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, errs[i] = userRepo.Store(context.TODO(), &domain.User{Email: "concurrent@mail.com", Password: "hashed"})
+				}(i)
+			}
+			wg.Wait()
+
+			stored := 0
+			for _, err := range errs {
+				if err == nil {
+					stored++
+				}
+			}
+			assert.Equal(t, 1, stored)
+		})
+
+		t.Run("error empty email", func(t *testing.T) {
+			_, err := userRepo.Store(context.TODO(), &domain.User{Password: "hashed"})
+			assert.Error(t, err)
+		})
+	})
+}
+
+func TestUserRepositoryUpdate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, userRepo domain.UserRepository, _ domain.ProfileRepository) {
+		users := storeUsers(t, userRepo, "alice@mail.com", "bob@mail.com")
+
+		t.Run("success update", func(t *testing.T) {
+			user, err := userRepo.Find(context.TODO(), users[0].UUID)
+			require.NoError(t, err)
+			stamp, createdAt := user.SecurityStamp, user.CreatedAt
+
+			user.IsActive = true
+			user.Role = ""
+			user, err = userRepo.Update(context.TODO(), user)
+			require.NoError(t, err)
+			assert.Equal(t, 2, user.Version)
+			assert.Equal(t, stamp, user.SecurityStamp)
+			assert.Equal(t, domain.RoleUser, user.Role)
+			assert.True(t, createdAt.Equal(user.CreatedAt))
+
+			found, err := userRepo.Find(context.TODO(), users[0].UUID)
+			require.NoError(t, err)
+			assert.True(t, found.IsActive)
+			assert.Equal(t, 2, found.Version)
+			assert.Equal(t, stamp, found.SecurityStamp)
+		})
+
+		t.Run("success update security stamp", func(t *testing.T) {
+			user, err := userRepo.Find(context.TODO(), users[1].UUID)
+			require.NoError(t, err)
+
+			user.SecurityStamp = "rotated"
+			user, err = userRepo.Update(context.TODO(), user)
+			require.NoError(t, err)
+			assert.Equal(t, "rotated", user.SecurityStamp)
+
+			user.SecurityStamp = ""
+			user, err = userRepo.Update(context.TODO(), user)
+			require.NoError(t, err)
+			assert.Equal(t, "rotated", user.SecurityStamp)
+
+			found, err := userRepo.FindOneBy(context.TODO(), domain.Where(domain.Eq("security_stamp", "rotated")))
+			require.NoError(t, err)
+			require.NotNil(t, found)
+			assert.Equal(t, users[1].UUID, found.UUID)
+		})
+
+		t.Run("error stale version", func(t *testing.T) {
+			stale := *users[0]
+			stale.IsActive = false
+
+			_, err := userRepo.Update(context.TODO(), &stale)
+			assert.Equal(t, domain.ErrConflict, errors.Cause(err))
+		})
+
+		t.Run("error unknown user", func(t *testing.T) {
+			unknown := *users[1]
+			unknown.UUID = "00000000-0000-0000-0000-000000000000"
+
+			_, err := userRepo.Update(context.TODO(), &unknown)
+			assert.Equal(t, domain.ErrUserNotFound, errors.Cause(err))
+		})
+
+		t.Run("error duplicate email", func(t *testing.T) {
+			user, err := userRepo.Find(context.TODO(), users[1].UUID)
+			require.NoError(t, err)
+
+			user.Email = "alice@mail.com"
+			_, err = userRepo.Update(context.TODO(), user)
+			assert.Error(t, err)
+		})
+	})
+}
+
+func TestUserRepositoryQuery(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, userRepo domain.UserRepository, _ domain.ProfileRepository) {
+		users := storeUsers(t, userRepo, "carol@mail.com", "alice@mail.com", "bob@test.com", "dave_x@mail.com")
+
+		externalID := "ext-1"
+		users[2].ExternalID = &externalID
+		_, err := userRepo.Update(context.TODO(), users[2])
+		require.NoError(t, err)
+
+		find := func(t *testing.T, query *domain.Query) []string {
+			found, err := userRepo.FindBy(context.TODO(), query)
+			require.NoError(t, err)
+			return emailsOf(found)
+		}
+
+		t.Run("success conditions", func(t *testing.T) {
+			byEmail := domain.Asc("email")
+
+			assert.Equal(t, []string{"bob@test.com"}, find(t, domain.Where(domain.Eq("email", "bob@test.com"))))
+			assert.Equal(t, []string{"alice@mail.com", "carol@mail.com", "dave_x@mail.com"},
+				find(t, domain.Where(domain.NotEq("email", "bob@test.com")).OrderBy(byEmail)))
+			assert.Equal(t, []string{"alice@mail.com", "carol@mail.com"},
+				find(t, domain.Where(domain.In("email", "carol@mail.com", "alice@mail.com", "nobody@mail.com")).OrderBy(byEmail)))
+			assert.Empty(t, find(t, domain.Where(domain.In("email"))))
+			assert.Equal(t, []string{"bob@test.com"}, find(t, domain.Where(domain.IsNotNull("external_id"))))
+			assert.Len(t, find(t, domain.Where(domain.IsNull("external_id"))), 3)
+			assert.Equal(t, []string{"alice@mail.com", "bob@test.com"},
+				find(t, domain.Where(domain.Or(domain.Eq("email", "alice@mail.com"), domain.Eq("external_id", externalID))).OrderBy(byEmail)))
+			assert.Empty(t, find(t, domain.Where(domain.Or())))
+			assert.Len(t, find(t, domain.Where(domain.And())), 4)
+		})
+
+		t.Run("success like is case insensitive and escaped", func(t *testing.T) {
+			assert.Equal(t, []string{"bob@test.com"}, find(t, domain.Where(domain.Like("email", "%@TEST.%"))))
+			assert.Equal(t, []string{"dave_x@mail.com"}, find(t, domain.Where(domain.Like("email", "%"+domain.EscapeLike("_x")+"%"))))
+			assert.Len(t, find(t, domain.Where(domain.Like("email", "%a_e%"))), 1)
+		})
+
+		t.Run("success time range", func(t *testing.T) {
+			from, to := users[0].CreatedAt, users[3].CreatedAt.Add(time.Second)
+
+			assert.Len(t, find(t, domain.Where(domain.Between("created_at", from, to))), 4)
+			assert.Empty(t, find(t, domain.Where(domain.Gt("created_at", to))))
+			assert.Len(t, find(t, domain.Where(domain.Gte("created_at", from))), 4)
+		})
+
+		t.Run("success order and paginate", func(t *testing.T) {
+			query := domain.Where(domain.Eq("is_active", false)).OrderBy(domain.Desc("email"))
+
+			assert.Equal(t, []string{"dave_x@mail.com", "carol@mail.com", "bob@test.com", "alice@mail.com"}, find(t, query))
+			assert.Equal(t, []string{"carol@mail.com", "bob@test.com"}, find(t, query.Paginate(2, 1)))
+			assert.Empty(t, find(t, query.Paginate(2, 4)))
+
+			count, err := userRepo.Count(context.TODO(), query.Paginate(1, 0))
+			require.NoError(t, err)
+			assert.Equal(t, 4, count)
+
+			first, err := userRepo.FindOneBy(context.TODO(), query)
+			require.NoError(t, err)
+			require.NotNil(t, first)
+			assert.Equal(t, "dave_x@mail.com", first.Email)
+		})
+
+		t.Run("success keyset pages", func(t *testing.T) {
+			var (
+				seen []string
+				page = domain.PageRequest{Limit: 3, WithTotal: true}
+			)
+
+			found, result, err := userRepo.FindPage(context.TODO(), nil, page)
+			require.NoError(t, err)
+			require.Len(t, found, 3)
+			require.NotNil(t, result.Next)
+			assert.Nil(t, result.Prev)
+			require.NotNil(t, result.Total)
+			assert.Equal(t, 4, *result.Total)
+			seen = append(seen, emailsOf(found)...)
+
+			found, result, err = userRepo.FindPage(context.TODO(), nil, domain.PageRequest{Limit: 3, After: result.Next})
+			require.NoError(t, err)
+			require.Len(t, found, 1)
+			assert.Nil(t, result.Next)
+			require.NotNil(t, result.Prev)
+			seen = append(seen, emailsOf(found)...)
+
+			assert.ElementsMatch(t, emailsOf(users), seen)
+
+			found, _, err = userRepo.FindPage(context.TODO(), nil, domain.PageRequest{Limit: 3, Before: result.Prev})
+			require.NoError(t, err)
+			assert.Equal(t, seen[:3], emailsOf(found))
+		})
+
+		t.Run("error unknown column", func(t *testing.T) {
+			_, err := userRepo.FindBy(context.TODO(), domain.Where(domain.Eq("email; DROP TABLE users", "x")))
+			assert.Equal(t, domain.ErrInvalidQuery, err)
+
+			_, err = userRepo.FindBy(context.TODO(), domain.Where().OrderBy(domain.Asc("unknown")))
+			assert.Equal(t, domain.ErrInvalidQuery, err)
+		})
+	})
+}
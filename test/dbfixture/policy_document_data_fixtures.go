@@ -0,0 +1,35 @@
+package dbfixture
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// SeedPolicyDocuments handles seeding the given version of terms of service and privacy policy, published an hour ago
+func SeedPolicyDocuments(dbConn *sqlx.DB, version string) ([]domain.PolicyDocument, error) {
+	var documents []domain.PolicyDocument
+
+	for _, kind := range []string{domain.PolicyTermsOfService, domain.PolicyPrivacy} {
+		document := domain.PolicyDocument{
+			Kind:        kind,
+			Version:     version,
+			URL:         fmt.Sprintf("https://example.com/legal/%s/%s", kind, version),
+			PublishedAt: time.Now().Add(-time.Hour),
+		}
+
+		err := dbConn.QueryRowx(`INSERT INTO policy_documents (kind, version, url, published_at) VALUES ($1, $2, $3, $4) RETURNING uuid, created_at, updated_at`,
+			document.Kind, document.Version, document.URL, document.PublishedAt).Scan(&document.UUID, &document.CreatedAt, &document.UpdatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "insert policy document")
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
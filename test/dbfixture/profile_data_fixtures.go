@@ -33,14 +33,14 @@ func SeedProfiles(dbConn *sqlx.DB, count int) ([]domain.Profile, error) {
 	}
 
 	for i := range profiles {
-		stmt, err := dbConn.Prepare("INSERT INTO profiles (user_uuid, first_name, last_name, address, phone, gender, dob) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING uuid, user_uuid, created_at, updated_at")
+		stmt, err := dbConn.Prepare("INSERT INTO profiles (user_uuid, first_name, last_name, address, phone, gender, dob) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING uuid, user_uuid, version, created_at, updated_at")
 		if err != nil {
 			return nil, errors.Wrap(err, "prepare profiles insertion")
 		}
 
 		row := stmt.QueryRow(profiles[i].User.UUID, profiles[i].FirstName, profiles[i].LastName, profiles[i].Address, profiles[i].Phone, profiles[i].Gender, profiles[i].Dob)
 
-		if err = row.Scan(&profiles[i].UUID, &profiles[i].UserUUID, &profiles[i].CreatedAt, &profiles[i].UpdatedAt); err != nil {
+		if err = row.Scan(&profiles[i].UUID, &profiles[i].UserUUID, &profiles[i].Version, &profiles[i].CreatedAt, &profiles[i].UpdatedAt); err != nil {
 			if err := stmt.Close(); err != nil {
 				return nil, errors.Wrap(err, "close psql statement")
 			}
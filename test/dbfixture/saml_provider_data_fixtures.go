@@ -0,0 +1,30 @@
+package dbfixture
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// SeedSamlProvider handles seeding an identity provider into saml_providers table for integration tests.
+// Attributes left empty use the database defaults
+func SeedSamlProvider(dbConn *sqlx.DB, provider domain.SamlProvider) (*domain.SamlProvider, error) {
+	query := `INSERT INTO saml_providers (tenant_uuid, email_domain, idp_metadata)
+		VALUES ($1, $2, $3)
+		RETURNING uuid, email_attribute, first_name_attribute, last_name_attribute, created_at, updated_at`
+
+	err := dbConn.QueryRowx(query, provider.TenantUUID, provider.EmailDomain, provider.IdpMetadata).Scan(
+		&provider.UUID,
+		&provider.EmailAttribute,
+		&provider.FirstNameAttribute,
+		&provider.LastNameAttribute,
+		&provider.CreatedAt,
+		&provider.UpdatedAt,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "insert saml provider")
+	}
+
+	return &provider, nil
+}
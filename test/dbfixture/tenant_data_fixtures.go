@@ -0,0 +1,51 @@
+package dbfixture
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/wicaker/user/internal/domain"
+)
+
+// SeedTenants handles seeding the tenants table in the database for integration tests.
+// The scim bearer token of tenant n is "scim-token-n"
+func SeedTenants(dbConn *sqlx.DB, count int) ([]domain.Tenant, error) {
+	var tenants []domain.Tenant
+
+	for i := 1; i <= count; i++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("scim-token-%d", i)))
+		token := hex.EncodeToString(sum[:])
+
+		tenant := domain.Tenant{
+			Name:      fmt.Sprintf("Tenant%d", i),
+			ScimToken: &token,
+		}
+
+		stmt, err := dbConn.Prepare("INSERT INTO tenants (name, scim_token) VALUES ($1, $2) RETURNING uuid, created_at, updated_at")
+		if err != nil {
+			return nil, errors.Wrap(err, "prepare tenants insertion")
+		}
+
+		row := stmt.QueryRow(tenant.Name, tenant.ScimToken)
+
+		if err = row.Scan(&tenant.UUID, &tenant.CreatedAt, &tenant.UpdatedAt); err != nil {
+			if err := stmt.Close(); err != nil {
+				return nil, errors.Wrap(err, "close psql statement")
+			}
+
+			return nil, errors.Wrap(err, "capture tenants id")
+		}
+
+		if err := stmt.Close(); err != nil {
+			return nil, errors.Wrap(err, "close psql statement")
+		}
+
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, nil
+}
@@ -7,7 +7,7 @@ import (
 
 // Truncate table
 func Truncate(dbConn *sqlx.DB) error {
-	stmt := "TRUNCATE TABLE users, profiles;"
+	stmt := "TRUNCATE TABLE users, profiles, tenants, groups, group_members, saml_providers, saml_assertions, invitations, policy_documents, consents, data_exports, audit_events, login_attempts, password_history, outbox_messages;"
 
 	if _, err := dbConn.Exec(stmt); err != nil {
 		return errors.Wrap(err, "truncate test database tables")
@@ -24,14 +24,14 @@ func SeedUsers(dbConn *sqlx.DB, count int) ([]domain.User, error) {
 		password, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		user.Password = string(password)
 
-		stmt, err := dbConn.Prepare("INSERT INTO users (email, password) VALUES ($1, $2) RETURNING uuid, created_at, updated_at")
+		stmt, err := dbConn.Prepare("INSERT INTO users (email, password) VALUES ($1, $2) RETURNING uuid, security_stamp, version, created_at, updated_at")
 		if err != nil {
 			return nil, errors.Wrap(err, "prepare users insertion")
 		}
 
 		row := stmt.QueryRow(user.Email, user.Password)
 
-		if err = row.Scan(&user.UUID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err = row.Scan(&user.UUID, &user.SecurityStamp, &user.Version, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			if err := stmt.Close(); err != nil {
 				return nil, errors.Wrap(err, "close psql statement")
 			}
@@ -63,14 +63,14 @@ func SeedActiveUsers(dbConn *sqlx.DB, count int) ([]domain.User, error) {
 		password, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		user.Password = string(password)
 
-		stmt, err := dbConn.Prepare("INSERT INTO users (email, password, is_active) VALUES ($1, $2, $3) RETURNING uuid, created_at, updated_at")
+		stmt, err := dbConn.Prepare("INSERT INTO users (email, password, is_active) VALUES ($1, $2, $3) RETURNING uuid, security_stamp, version, created_at, updated_at")
 		if err != nil {
 			return nil, errors.Wrap(err, "prepare users insertion")
 		}
 
 		row := stmt.QueryRow(user.Email, user.Password, true)
 
-		if err = row.Scan(&user.UUID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err = row.Scan(&user.UUID, &user.SecurityStamp, &user.Version, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			if err := stmt.Close(); err != nil {
 				return nil, errors.Wrap(err, "close psql statement")
 			}
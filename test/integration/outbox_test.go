@@ -0,0 +1,231 @@
+package integration_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+	"github.com/wicaker/user/internal/pkg/rmq"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/internal/transport"
+	"github.com/wicaker/user/internal/worker"
+	"github.com/wicaker/user/test/dbfixture"
+	"github.com/wicaker/user/test/mock"
+)
+
+// failingPublisher fails every publish as a broker which is down
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(message string, routingKey string, header map[string]interface{}) error {
+	return errors.New("broker is down")
+}
+
+func TestOutboxRelay(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	var (
+		transactor = repository.NewSqlxTransactor(dbConn, sql.LevelSerializable)
+		outboxRepo = repository.NewOutboxSqlxRepository(dbConn)
+		published  mock.Message
+	)
+
+	message, err := outboxRepo.Store(context.TODO(), &domain.OutboxMessage{RoutingKey: "user.register", Version: 1, Payload: `{"token":"abc"}`})
+	require.NoError(t, err)
+	require.NotEmpty(t, message.UUID)
+
+	t.Run("error publish is retried after backoff", func(t *testing.T) {
+		relay := worker.NewOutboxRelay(transactor, outboxRepo, map[string]domain.Publisher{"user.register": failingPublisher{}}, time.Second, 10, time.Minute, time.Hour)
+
+		n, err := relay.Relay(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		var pending domain.OutboxMessage
+		require.NoError(t, dbConn.Get(&pending, `SELECT * FROM outbox_messages WHERE uuid=$1`, message.UUID))
+		assert.Equal(t, 1, pending.Attempts)
+		assert.Nil(t, pending.SentAt)
+		require.NotNil(t, pending.LastError)
+		assert.Equal(t, "broker is down", *pending.LastError)
+		assert.True(t, pending.NextAttemptAt.After(time.Now()))
+
+		n, err = relay.Relay(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("success publish with message id", func(t *testing.T) {
+		_, err := dbConn.Exec(`UPDATE outbox_messages SET next_attempt_at=now() WHERE uuid=$1`, message.UUID)
+		require.NoError(t, err)
+
+		relay := worker.NewOutboxRelay(transactor, outboxRepo, map[string]domain.Publisher{
+			"user.register": mock.NewMockQueueRMQ("publish-user-register", &published),
+		}, time.Second, 10, time.Minute, time.Hour)
+
+		n, err := relay.Relay(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, "user.register", published.RoutingKey)
+
+		var envelope domain.EventEnvelope
+		require.NoError(t, json.Unmarshal([]byte(published.Message), &envelope))
+		assert.Equal(t, message.UUID, envelope.ID)
+		assert.Equal(t, "user.register", envelope.Type)
+		assert.Equal(t, 1, envelope.Version)
+		assert.JSONEq(t, `{"token":"abc"}`, string(envelope.Data))
+		assert.Equal(t, message.UUID, published.Headers[domain.MessageIDHeader])
+
+		var sent domain.OutboxMessage
+		require.NoError(t, dbConn.Get(&sent, `SELECT * FROM outbox_messages WHERE uuid=$1`, message.UUID))
+		assert.Equal(t, 2, sent.Attempts)
+		assert.NotNil(t, sent.SentAt)
+		assert.Nil(t, sent.LastError)
+	})
+
+	t.Run("success delete sent after retention", func(t *testing.T) {
+		n, err := outboxRepo.DeleteSent(context.TODO(), time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		n, err = outboxRepo.DeleteSent(context.TODO(), time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("success message is discarded with its rolled back transaction", func(t *testing.T) {
+		errAbort := errors.New("abort")
+		err := transactor.WithinTransaction(context.TODO(), func(ctx context.Context) error {
+			if _, err := outboxRepo.Store(ctx, &domain.OutboxMessage{RoutingKey: "user.register", Payload: `{}`}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.Equal(t, errAbort, err)
+
+		var count int
+		require.NoError(t, dbConn.Get(&count, `SELECT COUNT(*) FROM outbox_messages`))
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("success register event carries correlation id", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/user/register", strings.NewReader(`{"email":"correlated@mail.com","password":"Password1"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(middleware.CorrelationIDHeader, "correlation-1")
+
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		assert.Equal(t, "correlation-1", w.Header().Get(middleware.CorrelationIDHeader))
+
+		relayOutbox()
+		defer func() {
+			publishedMessage = mock.Message{}
+		}()
+
+		var envelope domain.EventEnvelope
+		require.NoError(t, json.Unmarshal([]byte(publishedMessage.Message), &envelope))
+		assert.Equal(t, domain.EventUserRegistered, envelope.Type)
+		assert.Equal(t, "correlation-1", envelope.CorrelationID)
+		assert.Equal(t, publishedMessage.Headers[domain.MessageIDHeader], envelope.ID)
+
+		var event domain.UserRegistered
+		require.NoError(t, json.Unmarshal(envelope.Data, &event))
+		assert.Equal(t, "correlated@mail.com", event.EmailDestination)
+		assert.NotEmpty(t, event.UserUUID)
+		assert.NotEmpty(t, event.Token)
+	})
+
+	t.Run("success envelope is mapped onto cloudevents attributes", func(t *testing.T) {
+		correlationID := "correlation-2"
+		message, err := outboxRepo.Store(context.TODO(), &domain.OutboxMessage{
+			RoutingKey:    "user.register",
+			Version:       1,
+			CorrelationID: &correlationID,
+			Payload:       `{"token":"abc"}`,
+		})
+		require.NoError(t, err)
+
+		var published mock.Message
+		relay := transport.NewOutboxRelay(dbConn, []rmq.Queue{
+			mock.NewMockFormatQueueRMQ("publish-user-register", rmq.FormatBinary, &published),
+		})
+		n, err := relay.Relay(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		assert.JSONEq(t, `{"token":"abc"}`, published.Message)
+		assert.Equal(t, message.UUID, published.Headers[domain.MessageIDHeader])
+		assert.Equal(t, message.UUID, published.Headers["ce-id"])
+		assert.Equal(t, "/user", published.Headers["ce-source"])
+		assert.Equal(t, "user.register", published.Headers["ce-type"])
+		assert.Equal(t, "1", published.Headers["ce-eventversion"])
+		assert.Equal(t, correlationID, published.Headers["ce-correlationid"])
+
+		event, err := rmq.NewCloudEvent(published.Message, published.RoutingKey, published.Headers)
+		require.NoError(t, err)
+		assert.NoError(t, event.Validate())
+		assert.WithinDuration(t, message.CreatedAt, event.Time, time.Second)
+	})
+}
+
+func TestOutboxRelayClaim(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	var (
+		transactor = repository.NewSqlxTransactor(dbConn, sql.LevelSerializable)
+		outboxRepo = repository.NewOutboxSqlxRepository(dbConn)
+		published  mock.Message
+		relay      = worker.NewOutboxRelay(transactor, outboxRepo, map[string]domain.Publisher{
+			"user.register": mock.NewMockQueueRMQ("publish-user-register", &published),
+		}, time.Second, 10, time.Minute, time.Hour)
+	)
+
+	message, err := outboxRepo.Store(context.TODO(), &domain.OutboxMessage{RoutingKey: "user.register", Version: 1, Payload: `{}`})
+	require.NoError(t, err)
+
+	t.Run("success message claimed by another relay is skipped", func(t *testing.T) {
+		claimed, err := outboxRepo.Claim(context.TODO(), 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.NotNil(t, claimed[0].LockedUntil)
+
+		n, err := relay.Relay(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+		assert.Empty(t, published.RoutingKey)
+
+		_, err = outboxRepo.Update(context.TODO(), claimed[0])
+		require.NoError(t, err)
+	})
+
+	t.Run("success released message is published", func(t *testing.T) {
+		n, err := relay.Relay(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, message.UUID, published.Headers[domain.MessageIDHeader])
+
+		var sent domain.OutboxMessage
+		require.NoError(t, dbConn.Get(&sent, `SELECT * FROM outbox_messages WHERE uuid=$1`, message.UUID))
+		assert.NotNil(t, sent.SentAt)
+		assert.Nil(t, sent.LockedUntil)
+	})
+}
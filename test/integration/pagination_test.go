@@ -0,0 +1,135 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+type loginPageResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		Logins []domain.LoginAttempt `json:"logins"`
+		Page   struct {
+			NextCursor *string `json:"next_cursor"`
+			PrevCursor *string `json:"prev_cursor"`
+			Total      *int    `json:"total"`
+		} `json:"page"`
+	} `json:"data"`
+}
+
+func TestKeysetPagination(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+	require.NoError(t, err)
+	token := createJWT(users[0], time.Minute*2)
+
+	// two attempts share created_at, uuid keeps their order stable
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, createdAt := range []time.Time{
+		now.Add(-time.Minute * 4),
+		now.Add(-time.Minute * 3),
+		now.Add(-time.Minute * 2),
+		now.Add(-time.Minute * 2),
+		now.Add(-time.Minute),
+	} {
+		_, err := dbConn.Exec(`INSERT INTO login_attempts (user_uuid, email, method, success, device_fingerprint, created_at)
+			VALUES ($1, $2, 'password', true, '', $3)`, users[0].UUID, users[0].Email, createdAt)
+		require.NoError(t, err)
+	}
+
+	fetch := func(t *testing.T, query url.Values) loginPageResponse {
+		var resp loginPageResponse
+
+		w := consentRequest(http.MethodGet, "/user/logins?"+query.Encode(), token, "")
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	var (
+		first, second, last loginPageResponse
+		seen                = map[string]bool{}
+	)
+
+	t.Run("success first page", func(t *testing.T) {
+		first = fetch(t, url.Values{"limit": {"2"}, "total": {"true"}})
+		require.Len(t, first.Data.Logins, 2)
+		require.NotNil(t, first.Data.Page.NextCursor)
+		assert.Nil(t, first.Data.Page.PrevCursor)
+		require.NotNil(t, first.Data.Page.Total)
+		assert.Equal(t, 5, *first.Data.Page.Total)
+
+		// newest first
+		assert.Equal(t, now.Add(-time.Minute).Unix(), first.Data.Logins[0].CreatedAt.Unix())
+		for _, login := range first.Data.Logins {
+			seen[login.UUID] = true
+		}
+	})
+
+	t.Run("success next pages", func(t *testing.T) {
+		second = fetch(t, url.Values{"limit": {"2"}, "after": {*first.Data.Page.NextCursor}})
+		require.Len(t, second.Data.Logins, 2)
+		require.NotNil(t, second.Data.Page.NextCursor)
+		require.NotNil(t, second.Data.Page.PrevCursor)
+		assert.Nil(t, second.Data.Page.Total)
+
+		last = fetch(t, url.Values{"limit": {"2"}, "after": {*second.Data.Page.NextCursor}})
+		require.Len(t, last.Data.Logins, 1)
+		assert.Nil(t, last.Data.Page.NextCursor)
+		require.NotNil(t, last.Data.Page.PrevCursor)
+
+		for _, login := range append(second.Data.Logins, last.Data.Logins...) {
+			assert.False(t, seen[login.UUID], "login %s returned twice", login.UUID)
+			seen[login.UUID] = true
+		}
+		assert.Len(t, seen, 5)
+	})
+
+	t.Run("success previous page", func(t *testing.T) {
+		prev := fetch(t, url.Values{"limit": {"2"}, "before": {*last.Data.Page.PrevCursor}})
+		require.Len(t, prev.Data.Logins, 2)
+		assert.Equal(t, second.Data.Logins[0].UUID, prev.Data.Logins[0].UUID)
+		assert.Equal(t, second.Data.Logins[1].UUID, prev.Data.Logins[1].UUID)
+		assert.NotNil(t, prev.Data.Page.PrevCursor)
+		assert.NotNil(t, prev.Data.Page.NextCursor)
+	})
+
+	t.Run("error tampered cursor", func(t *testing.T) {
+		cursor := []byte(*first.Data.Page.NextCursor)
+		cursor[0] ^= 1
+
+		w := consentRequest(http.MethodGet, "/user/logins?after="+url.QueryEscape(string(cursor)), token, "")
+		require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+		var resp domain.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, domain.ErrInvalidCursor.Error(), resp.Message)
+	})
+
+	t.Run("error both after and before", func(t *testing.T) {
+		w := consentRequest(http.MethodGet, "/user/logins?"+url.Values{
+			"after":  {*first.Data.Page.NextCursor},
+			"before": {*first.Data.Page.NextCursor},
+		}.Encode(), token, "")
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("error invalid limit", func(t *testing.T) {
+		w := consentRequest(http.MethodGet, "/user/logins?limit=0", token, "")
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,146 @@
+package integration_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/config"
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+type rawProfile struct {
+	Phone      *string `db:"phone"`
+	Address    *string `db:"address"`
+	PhoneEnc   []byte  `db:"phone_enc"`
+	AddressEnc []byte  `db:"address_enc"`
+	DobEnc     []byte  `db:"dob_enc"`
+	KeyVersion *int    `db:"key_version"`
+}
+
+func findRawProfile(t *testing.T, uuid string) rawProfile {
+	raw := rawProfile{}
+	err := dbConn.Get(&raw, `SELECT phone, address, phone_enc, address_enc, dob_enc, key_version FROM profiles WHERE uuid=$1`, uuid)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestProfileEncryption(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+	profileRepo := repository.NewProfileSqlxRepository(dbConn, testKeyring())
+
+	users, err := dbfixture.SeedUsers(dbConn, 1)
+	require.NoError(t, err)
+
+	phone := "+62 819-1919-1"
+	address := "Jl. Merdeka 1"
+	dob := time.Date(1990, time.May, 17, 0, 0, 0, 0, time.UTC)
+
+	t.Run("personal data is encrypted at rest", func(t *testing.T) {
+		profile, err := profileRepo.Store(context.TODO(), &domain.Profile{
+			User:    users[0],
+			Phone:   &phone,
+			Address: &address,
+			Dob:     &dob,
+		})
+		require.NoError(t, err)
+
+		raw := findRawProfile(t, profile.UUID)
+		assert.Nil(t, raw.Phone)
+		assert.Nil(t, raw.Address)
+		assert.NotEmpty(t, raw.PhoneEnc)
+		assert.NotEmpty(t, raw.AddressEnc)
+		assert.NotEmpty(t, raw.DobEnc)
+		assert.NotContains(t, string(raw.AddressEnc), address)
+		assert.Equal(t, 1, *raw.KeyVersion)
+
+		found, err := profileRepo.Find(context.TODO(), profile.UUID)
+		require.NoError(t, err)
+		assert.Equal(t, phone, *found.Phone)
+		assert.Equal(t, address, *found.Address)
+		assert.Equal(t, dob.Format("2006-01-02"), found.Dob.Format("2006-01-02"))
+	})
+
+	t.Run("find profile by phone with blind index", func(t *testing.T) {
+		found, err := profileRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("phone", "+6281919191"),
+		))
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, users[0].UUID, found.UserUUID)
+	})
+
+	t.Run("failed find profile by encrypted address", func(t *testing.T) {
+		found, err := profileRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("address", address),
+		))
+		assert.Nil(t, found)
+		assert.Equal(t, repository.ErrEncryptedCriteria, err)
+	})
+}
+
+func TestProfileReencryption(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+	keyRepo := repository.NewProfileKeySqlxRepository(dbConn, testKeyring())
+
+	// legacy rows are stored in plain text
+	profileFixtures, err := dbfixture.SeedProfiles(dbConn, 3)
+	require.NoError(t, err)
+
+	t.Run("encrypt legacy profiles", func(t *testing.T) {
+		n, err := keyRepo.ReencryptBatch(context.TODO(), 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		n, err = keyRepo.ReencryptBatch(context.TODO(), 2)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		raw := findRawProfile(t, profileFixtures[0].UUID)
+		assert.Nil(t, raw.Phone)
+		assert.NotEmpty(t, raw.PhoneEnc)
+		assert.Equal(t, 1, *raw.KeyVersion)
+	})
+
+	t.Run("rotate master key", func(t *testing.T) {
+		newKey := make([]byte, 32)
+		_, err := rand.Read(newKey)
+		require.NoError(t, err)
+
+		masterKeys := os.Getenv("ENCRYPTION_MASTER_KEYS")
+		os.Setenv("ENCRYPTION_MASTER_KEYS", masterKeys+";2:"+base64.StdEncoding.EncodeToString(newKey))
+		defer os.Setenv("ENCRYPTION_MASTER_KEYS", masterKeys)
+
+		keyring, err := config.NewEncryption().Keyring()
+		require.NoError(t, err)
+		assert.Equal(t, 2, keyring.CurrentVersion())
+
+		n, err := repository.NewProfileKeySqlxRepository(dbConn, keyring).ReencryptBatch(context.TODO(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		raw := findRawProfile(t, profileFixtures[0].UUID)
+		assert.Equal(t, 2, *raw.KeyVersion)
+
+		found, err := repository.NewProfileSqlxRepository(dbConn, keyring).Find(context.TODO(), profileFixtures[0].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, *profileFixtures[0].Phone, *found.Phone)
+		assert.Equal(t, *profileFixtures[0].Address, *found.Address)
+	})
+}
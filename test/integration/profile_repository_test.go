@@ -19,7 +19,7 @@ func TestProfileRepositoryFindAll(t *testing.T) {
 			t.Errorf("error truncating test database tables: %v", err)
 		}
 	}()
-	profileRepo := repository.NewProfileSqlxRepository(dbConn)
+	profileRepo := repository.NewProfileSqlxRepository(dbConn, testKeyring())
 
 	// prepare data
 	_, err := dbfixture.SeedProfiles(dbConn, 5)
@@ -53,7 +53,7 @@ func TestProfileRepositoryFindBy(t *testing.T) {
 			t.Errorf("error truncating test database tables: %v", err)
 		}
 	}()
-	profileRepo := repository.NewProfileSqlxRepository(dbConn)
+	profileRepo := repository.NewProfileSqlxRepository(dbConn, testKeyring())
 
 	// prepare data
 	profileFixtures, err := dbfixture.SeedProfiles(dbConn, 5)
@@ -63,9 +63,9 @@ func TestProfileRepositoryFindBy(t *testing.T) {
 
 	t.Run("success find profiles by ... with limit", func(t *testing.T) {
 		limit := uint(1)
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"dob": nil,
-		}, nil, &limit, nil)
+		profiles, err := profileRepo.FindBy(context.TODO(), domain.Where(
+			domain.IsNull("dob"),
+		).Paginate(limit, 0))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, profiles)
 		assert.Len(t, profiles, 1)
@@ -73,9 +73,10 @@ func TestProfileRepositoryFindBy(t *testing.T) {
 
 	t.Run("success find profiles by ... with offset", func(t *testing.T) {
 		offset := uint(1)
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"dob": nil,
-		}, nil, nil, &offset)
+		profiles, err := profileRepo.FindBy(context.TODO(), &domain.Query{
+			Conditions: []domain.Condition{domain.IsNull("dob")},
+			Offset:     &offset,
+		})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, profiles)
 		assert.Len(t, profiles, 4)
@@ -84,11 +85,11 @@ func TestProfileRepositoryFindBy(t *testing.T) {
 	t.Run("success find profiles by ... with limit and offset", func(t *testing.T) {
 		limit := uint(1)
 		offset := uint(1)
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"dob": nil,
-		}, &map[string]string{
-			"first_name": "ASC",
-		}, &limit, &offset)
+		profiles, err := profileRepo.FindBy(context.TODO(), domain.Where(
+			domain.IsNull("dob"),
+		).OrderBy(
+			domain.Asc("first_name"),
+		).Paginate(limit, offset))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, profiles)
 		assert.Len(t, profiles, 1)
@@ -99,11 +100,11 @@ func TestProfileRepositoryFindBy(t *testing.T) {
 	})
 
 	t.Run("success find profiles by ...", func(t *testing.T) {
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, nil, nil, nil)
+		profiles, err := profileRepo.FindBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, profiles)
 		assert.Len(t, profiles, 1)
@@ -115,14 +116,14 @@ func TestProfileRepositoryFindBy(t *testing.T) {
 	})
 
 	t.Run("success find profiles by ... with ORDER BY", func(t *testing.T) {
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, &map[string]string{
-			"first_name": "ASC",
-			"dob":        "DESC",
-		}, nil, nil)
+		profiles, err := profileRepo.FindBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		).OrderBy(
+			domain.Asc("first_name"),
+			domain.Desc("dob"),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, profiles)
 		assert.Len(t, profiles, 1)
@@ -133,36 +134,36 @@ func TestProfileRepositoryFindBy(t *testing.T) {
 	})
 
 	t.Run("failed find profiles because column does not exist", func(t *testing.T) {
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"random":     nil,
-		}, nil, nil, nil)
+		profiles, err := profileRepo.FindBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.IsNull("random"),
+		))
 		assert.Error(t, err)
 		assert.Empty(t, profiles)
 	})
 
 	t.Run("failed find profiles because wrong orderBy input 1", func(t *testing.T) {
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, &map[string]string{
-			"first_name": "ASCC",
-			"dob":        "DESC",
-		}, nil, nil)
+		profiles, err := profileRepo.FindBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		).OrderBy(
+			domain.Asc("first_name ASCC"),
+			domain.Desc("dob"),
+		))
 		assert.Error(t, err)
 		assert.Empty(t, profiles)
 	})
 
 	t.Run("failed find profiles because wrong orderBy input 2", func(t *testing.T) {
-		profiles, err := profileRepo.FindBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, &map[string]string{
-			"random": "DESC",
-		}, nil, nil)
+		profiles, err := profileRepo.FindBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		).OrderBy(
+			domain.Desc("random"),
+		))
 		assert.Error(t, err)
 		assert.Empty(t, profiles)
 	})
@@ -174,7 +175,7 @@ func TestProfileRepositoryFind(t *testing.T) {
 			t.Errorf("error truncating test database tables: %v", err)
 		}
 	}()
-	profileRepo := repository.NewProfileSqlxRepository(dbConn)
+	profileRepo := repository.NewProfileSqlxRepository(dbConn, testKeyring())
 
 	// prepare data
 	profileFixtures, err := dbfixture.SeedProfiles(dbConn, 5)
@@ -207,7 +208,7 @@ func TestProfileRepositoryFindOneBy(t *testing.T) {
 			t.Errorf("error truncating test database tables: %v", err)
 		}
 	}()
-	profileRepo := repository.NewProfileSqlxRepository(dbConn)
+	profileRepo := repository.NewProfileSqlxRepository(dbConn, testKeyring())
 
 	// prepare data
 	profileFixtures, err := dbfixture.SeedProfiles(dbConn, 3)
@@ -216,11 +217,11 @@ func TestProfileRepositoryFindOneBy(t *testing.T) {
 	}
 
 	t.Run("success find a profile by ...", func(t *testing.T) {
-		profile, err := profileRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, nil)
+		profile, err := profileRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, profile)
 		assert.Equal(t, profileFixtures[0].FirstName, profile.FirstName)
@@ -231,14 +232,14 @@ func TestProfileRepositoryFindOneBy(t *testing.T) {
 	})
 
 	t.Run("success find a profile by ... with ORDER BY", func(t *testing.T) {
-		profile, err := profileRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, &map[string]string{
-			"first_name": "ASC",
-			"dob":        "DESC",
-		})
+		profile, err := profileRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		).OrderBy(
+			domain.Asc("first_name"),
+			domain.Desc("dob"),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, profile)
 		assert.Equal(t, profileFixtures[0].FirstName, profile.FirstName)
@@ -248,36 +249,36 @@ func TestProfileRepositoryFindOneBy(t *testing.T) {
 	})
 
 	t.Run("failed find a profile because column does not exist", func(t *testing.T) {
-		profile, err := profileRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"random":     nil,
-		}, nil)
+		profile, err := profileRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.IsNull("random"),
+		))
 		assert.Error(t, err)
 		assert.Empty(t, profile)
 	})
 
 	t.Run("failed find a profile because wrong orderBy input 1", func(t *testing.T) {
-		profile, err := profileRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, &map[string]string{
-			"first_name": "ASCC",
-			"dob":        "DESC",
-		})
+		profile, err := profileRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		).OrderBy(
+			domain.Asc("first_name ASCC"),
+			domain.Desc("dob"),
+		))
 		assert.Error(t, err)
 		assert.Empty(t, profile)
 	})
 
 	t.Run("failed find a profile because wrong orderBy input 2", func(t *testing.T) {
-		profile, err := profileRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"first_name": profileFixtures[0].FirstName,
-			"dob":        nil,
-			"user_uuid":  &profileFixtures[0].UserUUID,
-		}, &map[string]string{
-			"random": "DESC",
-		})
+		profile, err := profileRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("first_name", profileFixtures[0].FirstName),
+			domain.IsNull("dob"),
+			domain.Eq("user_uuid", &profileFixtures[0].UserUUID),
+		).OrderBy(
+			domain.Desc("random"),
+		))
 		assert.Error(t, err)
 		assert.Empty(t, profile)
 	})
@@ -289,7 +290,7 @@ func TestProfileRepositoryStore(t *testing.T) {
 			t.Errorf("error truncating test database tables: %v", err)
 		}
 	}()
-	profileRepo := repository.NewProfileSqlxRepository(dbConn)
+	profileRepo := repository.NewProfileSqlxRepository(dbConn, testKeyring())
 
 	// prepare data
 	users, err := dbfixture.SeedUsers(dbConn, 3)
@@ -359,7 +360,7 @@ func TestProfileRepositoryUpdate(t *testing.T) {
 			t.Errorf("error truncating test database tables: %v", err)
 		}
 	}()
-	profileRepo := repository.NewProfileSqlxRepository(dbConn)
+	profileRepo := repository.NewProfileSqlxRepository(dbConn, testKeyring())
 
 	// prepare data
 	profileFixtures, err := dbfixture.SeedProfiles(dbConn, 2)
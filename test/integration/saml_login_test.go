@@ -0,0 +1,291 @@
+package integration_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+// testIdentityProvider is an in process identity provider, it knows service providers by their metadata
+type testIdentityProvider struct {
+	*saml.IdentityProvider
+	serviceProviders map[string]*saml.EntityDescriptor
+}
+
+func (p *testIdentityProvider) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	sp, ok := p.serviceProviders[serviceProviderID]
+	if !ok {
+		return nil, errors.New("unknown service provider")
+	}
+	return sp, nil
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	metadataURL, _ := url.Parse("https://idp.example.com/metadata")
+	ssoURL, _ := url.Parse("https://idp.example.com/sso")
+
+	idp := &testIdentityProvider{serviceProviders: make(map[string]*saml.EntityDescriptor)}
+	idp.IdentityProvider = &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             cert,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		ServiceProviderProvider: idp,
+	}
+
+	return idp
+}
+
+func (p *testIdentityProvider) metadata(t *testing.T) string {
+	metadata, err := xml.Marshal(p.Metadata())
+	require.NoError(t, err)
+	return string(metadata)
+}
+
+// trust fetch service provider metadata of the provider, as an administrator registers it on the identity provider
+func (p *testIdentityProvider) trust(t *testing.T, providerUUID string) {
+	req, _ := http.NewRequest(http.MethodGet, "/saml/"+providerUUID+"/metadata", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var sp saml.EntityDescriptor
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &sp))
+	p.serviceProviders[sp.EntityID] = &sp
+}
+
+// respond answer authentication request at redirect url with an assertion of the session
+func (p *testIdentityProvider) respond(t *testing.T, redirectURL string, session *saml.Session) saml.IdpAuthnRequestForm {
+	r, _ := http.NewRequest(http.MethodGet, redirectURL, nil)
+
+	req, err := saml.NewIdpAuthnRequest(p.IdentityProvider, r)
+	require.NoError(t, err)
+	require.NoError(t, req.Validate())
+	require.NoError(t, saml.DefaultAssertionMaker{}.MakeAssertion(req, session))
+	require.NoError(t, req.MakeAssertionEl())
+
+	form, err := req.PostBinding()
+	require.NoError(t, err)
+
+	return form
+}
+
+func samlStart(query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/saml/login?"+query, nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func samlAcs(form saml.IdpAuthnRequestForm) *httptest.ResponseRecorder {
+	acsURL, _ := url.Parse(form.URL)
+	body := url.Values{"SAMLResponse": {form.SAMLResponse}, "RelayState": {form.RelayState}}
+
+	req, _ := http.NewRequest(http.MethodPost, acsURL.Path, strings.NewReader(body.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func TestSamlLogin(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	idp := newTestIdentityProvider(t)
+	emailDomain := "sso.example.com"
+	provider, err := dbfixture.SeedSamlProvider(dbConn, domain.SamlProvider{
+		EmailDomain: &emailDomain,
+		IdpMetadata: idp.metadata(t),
+	})
+	require.NoError(t, err)
+	idp.trust(t, provider.UUID)
+
+	var consumed saml.IdpAuthnRequestForm
+
+	t.Run("success provision user on first login", func(t *testing.T) {
+		w := samlStart("email=jane@sso.example.com")
+		assert.Equal(t, http.StatusFound, w.Result().StatusCode)
+
+		form := idp.respond(t, w.Header().Get("Location"), &saml.Session{
+			ID:            "session-1",
+			NameID:        "jane@sso.example.com",
+			NameIDFormat:  string(saml.EmailAddressNameIDFormat),
+			UserGivenName: "Jane",
+			UserSurname:   "Doe",
+		})
+		w = samlAcs(form)
+		consumed = form
+
+		var resp domain.Response
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, "Login successfully", resp.Message)
+		assert.NotEmpty(t, resp.Data["token"])
+
+		var isActive bool
+		err = dbConn.Get(&isActive, "SELECT is_active FROM users WHERE email = $1", "jane@sso.example.com")
+		assert.NoError(t, err)
+		assert.True(t, isActive)
+
+		var firstName string
+		err = dbConn.Get(&firstName, "SELECT p.first_name FROM profiles p JOIN users u ON u.uuid = p.user_uuid WHERE u.email = $1", "jane@sso.example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", firstName)
+	})
+
+	t.Run("error replayed assertion", func(t *testing.T) {
+		w := samlAcs(consumed)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("error email outside provider domain", func(t *testing.T) {
+		w := samlStart("email=jane@sso.example.com")
+		form := idp.respond(t, w.Header().Get("Location"), &saml.Session{
+			ID:           "session-2",
+			NameID:       "mallory@other.example.com",
+			NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		})
+		w = samlAcs(form)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("error tampered relay state", func(t *testing.T) {
+		w := samlStart("email=jane@sso.example.com")
+		form := idp.respond(t, w.Header().Get("Location"), &saml.Session{
+			ID:           "session-3",
+			NameID:       "jane@sso.example.com",
+			NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		})
+		form.RelayState = "tampered"
+		w = samlAcs(form)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("error response signed by unknown identity provider", func(t *testing.T) {
+		w := samlStart("email=jane@sso.example.com")
+
+		rogue := newTestIdentityProvider(t)
+		rogue.trust(t, provider.UUID)
+		form := rogue.respond(t, w.Header().Get("Location"), &saml.Session{
+			ID:           "session-4",
+			NameID:       "jane@sso.example.com",
+			NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		})
+		w = samlAcs(form)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("error no provider for email domain", func(t *testing.T) {
+		w := samlStart("email=jane@unknown.example.com")
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+}
+
+func TestSamlLoginTenant(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	tenants, err := dbfixture.SeedTenants(dbConn, 2)
+	require.NoError(t, err)
+
+	idp := newTestIdentityProvider(t)
+	emailDomain := "tenant.example.com"
+	provider, err := dbfixture.SeedSamlProvider(dbConn, domain.SamlProvider{
+		TenantUUID:  &tenants[0].UUID,
+		EmailDomain: &emailDomain,
+		IdpMetadata: idp.metadata(t),
+	})
+	require.NoError(t, err)
+	idp.trust(t, provider.UUID)
+
+	_, err = dbConn.Exec("INSERT INTO users (email, password) VALUES ($1, 'password')", "nobody@tenant.example.com")
+	require.NoError(t, err)
+	_, err = dbConn.Exec("INSERT INTO users (email, password, tenant_uuid) VALUES ($1, 'password', $2)", "other@tenant.example.com", tenants[1].UUID)
+	require.NoError(t, err)
+
+	for _, email := range []string{"nobody@tenant.example.com", "other@tenant.example.com"} {
+		email := email
+
+		t.Run("error user outside tenant "+email, func(t *testing.T) {
+			w := samlStart("tenant=" + tenants[0].UUID)
+			form := idp.respond(t, w.Header().Get("Location"), &saml.Session{
+				ID:           "session-" + email,
+				NameID:       email,
+				NameIDFormat: string(saml.EmailAddressNameIDFormat),
+			})
+			w = samlAcs(form)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+
+			var tenantUUID *string
+			err := dbConn.Get(&tenantUUID, "SELECT tenant_uuid FROM users WHERE email = $1", email)
+			assert.NoError(t, err)
+			assert.NotEqual(t, &tenants[0].UUID, tenantUUID)
+		})
+	}
+
+	t.Run("error provider of tenant without email domain", func(t *testing.T) {
+		rogue := newTestIdentityProvider(t)
+		provider, err := dbfixture.SeedSamlProvider(dbConn, domain.SamlProvider{
+			TenantUUID:  &tenants[1].UUID,
+			IdpMetadata: rogue.metadata(t),
+		})
+		require.NoError(t, err)
+		rogue.trust(t, provider.UUID)
+
+		w := samlStart("tenant=" + tenants[1].UUID)
+		form := rogue.respond(t, w.Header().Get("Location"), &saml.Session{
+			ID:           "session-rogue",
+			NameID:       "mallory@other.example.com",
+			NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		})
+		w = samlAcs(form)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,109 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/pkg/scim"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func TestScimGroups(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	_, err := dbfixture.SeedTenants(dbConn, 2)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var users []scim.User
+	for i := 1; i <= 2; i++ {
+		var user scim.User
+		w := scimRequest(http.MethodPost, "/scim/v2/Users", "scim-token-1", fmt.Sprintf(`{"userName": "member%d@example.com"}`, i), nil)
+		err := json.Unmarshal(w.Body.Bytes(), &user)
+		assert.NoError(t, err)
+		users = append(users, user)
+	}
+
+	var created scim.Group
+
+	t.Run("success create group", func(t *testing.T) {
+		body := fmt.Sprintf(`{"displayName": "Engineering", "members": [{"value": "%s"}]}`, users[0].ID)
+		w := scimRequest(http.MethodPost, "/scim/v2/Groups", "scim-token-1", body, nil)
+		err := json.Unmarshal(w.Body.Bytes(), &created)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		assert.Equal(t, "Engineering", created.DisplayName)
+		assert.Len(t, created.Members, 1)
+	})
+
+	t.Run("failed create group, displayName already exist", func(t *testing.T) {
+		w := scimRequest(http.MethodPost, "/scim/v2/Groups", "scim-token-1", `{"displayName": "Engineering"}`, nil)
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+	})
+
+	t.Run("failed create group, member of another tenant", func(t *testing.T) {
+		body := fmt.Sprintf(`{"displayName": "Sales", "members": [{"value": "%s"}]}`, users[0].ID)
+		w := scimRequest(http.MethodPost, "/scim/v2/Groups", "scim-token-2", body, nil)
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("success filter groups", func(t *testing.T) {
+		w := scimRequest(http.MethodGet, "/scim/v2/Groups?filter="+url.QueryEscape(`displayName sw "eng"`), "scim-token-1", "", nil)
+
+		var resp scim.ListResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, 1, resp.TotalResults)
+	})
+
+	t.Run("success patch group members", func(t *testing.T) {
+		body := fmt.Sprintf(`{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [
+				{"op": "add", "path": "members", "value": [{"value": "%s"}]},
+				{"op": "remove", "path": "members[value eq \"%s\"]"}
+			]
+		}`, users[1].ID, users[0].ID)
+		w := scimRequest(http.MethodPatch, "/scim/v2/Groups/"+created.ID, "scim-token-1", body, map[string]string{
+			"If-Match": created.Meta.Version,
+		})
+
+		var resp scim.Group
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Len(t, resp.Members, 1)
+		assert.Equal(t, users[1].ID, resp.Members[0].Value)
+	})
+
+	t.Run("deleted user is removed from group", func(t *testing.T) {
+		w := scimRequest(http.MethodDelete, "/scim/v2/Users/"+users[1].ID, "scim-token-1", "", nil)
+		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+		w = scimRequest(http.MethodGet, "/scim/v2/Groups/"+created.ID, "scim-token-1", "", nil)
+		var resp scim.Group
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Empty(t, resp.Members)
+	})
+
+	t.Run("success delete group", func(t *testing.T) {
+		w := scimRequest(http.MethodDelete, "/scim/v2/Groups/"+created.ID, "scim-token-1", "", nil)
+		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+		w = scimRequest(http.MethodGet, "/scim/v2/Groups/"+created.ID, "scim-token-1", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,197 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/pkg/scim"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func scimRequest(method string, path string, token string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", scim.MediaType)
+	req.Header.Set("Authorization", "Bearer "+token)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func TestScimUnauthorized(t *testing.T) {
+	w := scimRequest(http.MethodGet, "/scim/v2/Users", "invalid-token", "", nil)
+
+	var resp scim.Error
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	assert.Equal(t, []string{scim.SchemaError}, resp.Schemas)
+}
+
+func TestScimUsers(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	_, err := dbfixture.SeedTenants(dbConn, 2)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var created scim.User
+
+	t.Run("success create user", func(t *testing.T) {
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "bjensen@example.com",
+			"externalId": "bjensen",
+			"name": {"givenName": "Barbara", "familyName": "Jensen"},
+			"phoneNumbers": [{"value": "555-555-8377", "type": "work"}],
+			"active": true
+		}`
+		w := scimRequest(http.MethodPost, "/scim/v2/Users", "scim-token-1", body, nil)
+		err := json.Unmarshal(w.Body.Bytes(), &created)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		assert.Equal(t, scim.MediaType, w.Header().Get("Content-Type"))
+		assert.NotEmpty(t, created.ID)
+		assert.Equal(t, "Barbara", created.Name.GivenName)
+		assert.Equal(t, "555-555-8377", created.PhoneNumbers[0].Value)
+		assert.True(t, *created.Active)
+		assert.Equal(t, created.Meta.Version, w.Header().Get("ETag"))
+	})
+
+	t.Run("failed create user, userName already exist", func(t *testing.T) {
+		body := `{"userName": "bjensen@example.com"}`
+		w := scimRequest(http.MethodPost, "/scim/v2/Users", "scim-token-1", body, nil)
+
+		var resp scim.Error
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+		assert.Equal(t, "uniqueness", resp.ScimType)
+	})
+
+	t.Run("success filter users", func(t *testing.T) {
+		for _, filter := range []string{
+			`userName eq "BJENSEN@example.com"`,
+			`userName sw "bjen"`,
+			`userName co "example" and active eq true`,
+			`externalId eq "bjensen"`,
+		} {
+			w := scimRequest(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(filter), "scim-token-1", "", nil)
+
+			var resp scim.ListResponse
+			err := json.Unmarshal(w.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode, filter)
+			assert.Equal(t, 1, resp.TotalResults, filter)
+			assert.Len(t, resp.Resources, 1, filter)
+		}
+	})
+
+	t.Run("failed filter users, unsupported operator", func(t *testing.T) {
+		w := scimRequest(http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName gt "a"`), "scim-token-1", "", nil)
+
+		var resp scim.Error
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		assert.Equal(t, "invalidFilter", resp.ScimType)
+	})
+
+	t.Run("user of another tenant is not visible", func(t *testing.T) {
+		w := scimRequest(http.MethodGet, "/scim/v2/Users/"+created.ID, "scim-token-2", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+
+		w = scimRequest(http.MethodGet, "/scim/v2/Users", "scim-token-2", "", nil)
+		var resp scim.ListResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, resp.TotalResults)
+	})
+
+	t.Run("success pagination", func(t *testing.T) {
+		for i := 1; i <= 2; i++ {
+			body := fmt.Sprintf(`{"userName": "user%d@example.com"}`, i)
+			w := scimRequest(http.MethodPost, "/scim/v2/Users", "scim-token-1", body, nil)
+			assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		}
+
+		w := scimRequest(http.MethodGet, "/scim/v2/Users?startIndex=2&count=1", "scim-token-1", "", nil)
+
+		var resp scim.ListResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, resp.TotalResults)
+		assert.Equal(t, 2, resp.StartIndex)
+		assert.Equal(t, 1, resp.ItemsPerPage)
+	})
+
+	t.Run("failed patch user, etag does not match", func(t *testing.T) {
+		body := `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"active","value":false}]}`
+		w := scimRequest(http.MethodPatch, "/scim/v2/Users/"+created.ID, "scim-token-1", body, map[string]string{
+			"If-Match": `W/"outdated"`,
+		})
+		assert.Equal(t, http.StatusPreconditionFailed, w.Result().StatusCode)
+	})
+
+	t.Run("success patch user", func(t *testing.T) {
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [
+				{"op": "Replace", "path": "active", "value": "False"},
+				{"op": "replace", "path": "name.familyName", "value": "Doe"},
+				{"op": "add", "value": {"externalId": "bdoe"}}
+			]
+		}`
+		w := scimRequest(http.MethodPatch, "/scim/v2/Users/"+created.ID, "scim-token-1", body, map[string]string{
+			"If-Match": created.Meta.Version,
+		})
+
+		var resp scim.User
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.False(t, *resp.Active)
+		assert.Equal(t, "Barbara", resp.Name.GivenName)
+		assert.Equal(t, "Doe", resp.Name.FamilyName)
+		assert.Equal(t, "bdoe", resp.ExternalID)
+		assert.NotEqual(t, created.Meta.Version, resp.Meta.Version)
+	})
+
+	t.Run("success replace user", func(t *testing.T) {
+		body := `{"userName": "barbara@example.com", "name": {"givenName": "Babs"}, "active": true}`
+		w := scimRequest(http.MethodPut, "/scim/v2/Users/"+created.ID, "scim-token-1", body, nil)
+
+		var resp scim.User
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, "barbara@example.com", resp.UserName)
+		assert.Equal(t, "Babs", resp.Name.GivenName)
+		assert.Empty(t, resp.Name.FamilyName)
+		assert.Empty(t, resp.PhoneNumbers)
+	})
+
+	t.Run("success delete user", func(t *testing.T) {
+		w := scimRequest(http.MethodDelete, "/scim/v2/Users/"+created.ID, "scim-token-1", "", nil)
+		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+		w = scimRequest(http.MethodGet, "/scim/v2/Users/"+created.ID, "scim-token-1", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,107 @@
+package integration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func TestTransactor(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	var (
+		transactor  = repository.NewSqlxTransactor(dbConn, sql.LevelSerializable)
+		userRepo    = repository.NewUserSqlxRepository(dbConn)
+		profileRepo = repository.NewProfileSqlxRepository(dbConn, testKeyring())
+		errAbort    = errors.New("abort")
+	)
+
+	countUsers := func(t *testing.T, email string) int {
+		count, err := userRepo.Count(context.TODO(), domain.Where(domain.Eq("email", email)))
+		require.NoError(t, err)
+		return count
+	}
+
+	t.Run("success commit user with profile", func(t *testing.T) {
+		err := transactor.WithinTransaction(context.TODO(), func(ctx context.Context) error {
+			user, err := userRepo.Store(ctx, &domain.User{Email: "tx1@mail.com", Password: "123"})
+			if err != nil {
+				return err
+			}
+
+			// uncommitted user is visible inside the transaction only
+			assert.Equal(t, 0, countUsers(t, "tx1@mail.com"))
+			checkUser, err := userRepo.FindOneBy(ctx, domain.Where(domain.Eq("email", "tx1@mail.com")))
+			require.NoError(t, err)
+			require.NotNil(t, checkUser)
+
+			_, err = profileRepo.Store(ctx, &domain.Profile{User: *user})
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, countUsers(t, "tx1@mail.com"))
+	})
+
+	t.Run("error rollback keeps nothing and returns error as is", func(t *testing.T) {
+		err := transactor.WithinTransaction(context.TODO(), func(ctx context.Context) error {
+			user, err := userRepo.Store(ctx, &domain.User{Email: "tx2@mail.com", Password: "123"})
+			if err != nil {
+				return err
+			}
+			if _, err = profileRepo.Store(ctx, &domain.Profile{User: *user}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.Equal(t, errAbort, err)
+		assert.Equal(t, 0, countUsers(t, "tx2@mail.com"))
+	})
+
+	t.Run("error nested call joins the outer transaction", func(t *testing.T) {
+		err := transactor.WithinTransaction(context.TODO(), func(ctx context.Context) error {
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := userRepo.Store(ctx, &domain.User{Email: "tx3@mail.com", Password: "123"})
+				return err
+			})
+			require.NoError(t, err)
+			return errAbort
+		})
+		assert.Equal(t, errAbort, err)
+		assert.Equal(t, 0, countUsers(t, "tx3@mail.com"))
+	})
+
+	t.Run("success concurrent registrations of the same email", func(t *testing.T) {
+		var (
+			userUcase = newUserUsecase(userRepo)
+			wg        sync.WaitGroup
+			errs      = make([]error, 3)
+		)
+
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = userUcase.Register(context.TODO(), &domain.User{Email: "tx4@mail.com", Password: "Password1"})
+			}(i)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 1, countUsers(t, "tx4@mail.com"))
+	})
+}
@@ -1,17 +1,21 @@
 package integration_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+	"github.com/wicaker/user/internal/repository"
 	"github.com/wicaker/user/test/dbfixture"
 )
 
@@ -38,6 +42,7 @@ func TestActivationHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 		api.ServeHTTP(w, req)
 
+		relayOutbox()
 		assert.Equal(t, "user.register", publishedMessage.RoutingKey)
 		msg = getMessageInMq()
 		assert.Equal(t, "testactivation@mail.com", msg.EmailDestination)
@@ -62,6 +67,25 @@ func TestActivationHandler(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
 	})
 
+	t.Run("failed, because the token is not an activation token", func(t *testing.T) {
+		var (
+			resp domain.Response
+		)
+
+		parsedToken, err := middleware.JwtVerifyType(msg.Token, domain.TokenActivation)
+		assert.NoError(t, err)
+		loginToken := createJWT(domain.User{UUID: parsedToken.UUID, Email: parsedToken.Email, SecurityStamp: parsedToken.SecurityStamp}, time.Minute*2)
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/user/activation/%s", loginToken), nil)
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		err = json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
 	t.Run("success,  because user inactive", func(t *testing.T) {
 		var (
 			resp domain.Response
@@ -81,6 +105,14 @@ func TestActivationHandler(t *testing.T) {
 		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
 		assert.Empty(t, resp.Data)
 		assert.Nil(t, resp.Errors)
+
+		parsedToken, err := middleware.JwtVerifyType(msg.Token, domain.TokenActivation)
+		assert.NoError(t, err)
+		usr, err := repository.NewUserSqlxRepository(dbConn).FindOneBy(context.TODO(), domain.Where(domain.Eq("uuid", parsedToken.UUID)))
+		assert.NoError(t, err)
+		if assert.NotNil(t, usr) {
+			assert.NotEqual(t, parsedToken.SecurityStamp, usr.SecurityStamp)
+		}
 	})
 
 	t.Run("failed, because user already active", func(t *testing.T) {
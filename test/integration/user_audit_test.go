@@ -0,0 +1,140 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+type auditEventsResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		AuditEvents  []domain.AuditEvent      `json:"audit_events"`
+		Verification domain.AuditVerification `json:"verification"`
+	} `json:"data"`
+}
+
+func fetchAuditEvents(t *testing.T, token string, query url.Values) (int, auditEventsResponse) {
+	var resp auditEventsResponse
+
+	w := consentRequest(http.MethodGet, "/audit-events?"+query.Encode(), token, "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Result().StatusCode, resp
+}
+
+func TestAuditEvents(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 2)
+	require.NoError(t, err)
+	_, err = dbConn.Exec(`UPDATE users SET role=$1 WHERE uuid=$2`, domain.RoleAdmin, users[0].UUID)
+	require.NoError(t, err)
+	adminToken := createJWT(users[0], time.Minute)
+
+	start := time.Now().Add(-time.Minute)
+	w := consentRequest(http.MethodPost, "/user/login", "", `{"email":"user2@example.com","password":"wrong"}`)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	w = consentRequest(http.MethodPost, "/user/login", "", `{"email":"user2@example.com","password":"Password2"}`)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	t.Run("success, login attempts are recorded", func(t *testing.T) {
+		status, resp := fetchAuditEvents(t, adminToken, url.Values{
+			"action": {domain.AuditLogin},
+			"from":   {start.Format(time.RFC3339)},
+		})
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, resp.Data.AuditEvents, 2)
+
+		// newest first
+		success, failure := resp.Data.AuditEvents[0], resp.Data.AuditEvents[1]
+		assert.Equal(t, domain.AuditSuccess, success.Outcome)
+		assert.Equal(t, users[1].UUID, *success.TargetUUID)
+		assert.Equal(t, "203.0.113.7", success.IPAddress)
+		assert.Equal(t, "consent-test", success.UserAgent)
+		assert.Equal(t, domain.AuditFailure, failure.Outcome)
+		assert.Nil(t, failure.TargetUUID)
+		assert.NotNil(t, failure.Reason)
+		assert.NotContains(t, string(failure.Metadata), "wrong")
+	})
+
+	t.Run("success, sensitive changes are redacted", func(t *testing.T) {
+		w := consentRequest(http.MethodPut, "/user/password/change", createJWT(users[1], time.Minute),
+			`{"email":"user2@example.com","password":"Password2","new_password":"Password22"}`)
+		require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+		getMessageInMq()
+
+		status, resp := fetchAuditEvents(t, adminToken, url.Values{
+			"action": {domain.AuditChangePassword},
+			"target": {users[1].UUID},
+		})
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, resp.Data.AuditEvents, 1)
+
+		var metadata map[string]map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Data.AuditEvents[0].Metadata, &metadata))
+		assert.Equal(t, domain.AuditRedacted, metadata["diff"]["new_password"]["to"])
+		assert.NotContains(t, string(resp.Data.AuditEvents[0].Metadata), "Password22")
+	})
+
+	t.Run("success, empty time range", func(t *testing.T) {
+		status, resp := fetchAuditEvents(t, adminToken, url.Values{
+			"to": {start.Format(time.RFC3339)},
+		})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, resp.Data.AuditEvents)
+	})
+
+	t.Run("error invalid time range", func(t *testing.T) {
+		status, _ := fetchAuditEvents(t, adminToken, url.Values{"from": {"yesterday"}})
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("error not admin", func(t *testing.T) {
+		status, _ := fetchAuditEvents(t, createJWT(users[1], time.Minute), url.Values{})
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("error events are append-only", func(t *testing.T) {
+		_, err := dbConn.Exec(`UPDATE audit_events SET outcome='success'`)
+		assert.Error(t, err)
+		_, err = dbConn.Exec(`DELETE FROM audit_events`)
+		assert.Error(t, err)
+	})
+
+	t.Run("success verify hash chain", func(t *testing.T) {
+		var resp auditEventsResponse
+		w := consentRequest(http.MethodGet, "/audit-events/verify", adminToken, "")
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Data.Verification.Valid)
+		assert.Equal(t, 3, resp.Data.Verification.Checked)
+	})
+
+	t.Run("success detect tampering", func(t *testing.T) {
+		_, err := dbConn.Exec(`ALTER TABLE audit_events DISABLE TRIGGER append_only`)
+		require.NoError(t, err)
+		_, err = dbConn.Exec(`UPDATE audit_events SET outcome='success' WHERE outcome='failure'`)
+		require.NoError(t, err)
+		_, err = dbConn.Exec(`ALTER TABLE audit_events ENABLE TRIGGER append_only`)
+		require.NoError(t, err)
+
+		var resp auditEventsResponse
+		w := consentRequest(http.MethodGet, "/audit-events/verify", adminToken, "")
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.False(t, resp.Data.Verification.Valid)
+		assert.NotNil(t, resp.Data.Verification.BrokenAt)
+	})
+}
@@ -55,9 +55,9 @@ func TestChangeEmailUserInactive(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
 	assert.Empty(t, resp.Data)
 
-	u, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-		"email": &userNew.Email,
-	}, nil)
+	u, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+		domain.Eq("email", &userNew.Email),
+	))
 	assert.NoError(t, err)
 	assert.Empty(t, u)
 
@@ -247,9 +247,9 @@ func TestChangeEmailSuccess(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
 	assert.Empty(t, resp.Data)
 
-	u, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-		"email": &userNew.Email,
-	}, nil)
+	u, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+		domain.Eq("email", &userNew.Email),
+	))
 	assert.NoError(t, err)
 	assert.NotEmpty(t, u)
 }
@@ -15,7 +15,6 @@ import (
 	"github.com/wicaker/user/internal/domain"
 	"github.com/wicaker/user/internal/middleware"
 	"github.com/wicaker/user/internal/repository"
-	"github.com/wicaker/user/internal/usecase"
 	"github.com/wicaker/user/test/dbfixture"
 )
 
@@ -33,7 +32,7 @@ func TestUserChangePasswordConfirmSuccess(t *testing.T) {
 
 	var (
 		userRepo     = repository.NewUserSqlxRepository(dbConn)
-		userUsecase  = usecase.NewUserUsecase(time.Duration(2)*time.Second, userRepo)
+		userUsecase  = newUserUsecase(userRepo)
 		newPassoword = "newpassword"
 		userOld      = users[0]
 		userNew      = domain.User{
@@ -83,20 +82,21 @@ func TestUserChangePasswordConfirmSuccess(t *testing.T) {
 		assert.Error(t, err)
 		assert.Empty(t, jwt)
 
-		usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email": userOld.Email,
-		}, nil)
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", userOld.Email),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, usr)
 		assert.Equal(t, usr.Email, userNew.Email)
 		assert.NotEmpty(t, usr.NewPassword)
 
+		relayOutbox()
 		assert.Equal(t, "user.change_password", publishedMessage.RoutingKey)
 		msg = getMessageInMq()
-		parsedToken, err := middleware.JwtVerify(msg.Token)
+		parsedToken, err := middleware.JwtVerifyType(msg.Token, domain.TokenPasswordConfirm)
 		assert.NoError(t, err)
 		assert.Equal(t, users[0].Email, msg.EmailDestination)
-		assert.Equal(t, usr.Salt, parsedToken.Salt)
+		assert.Equal(t, usr.SecurityStamp, parsedToken.SecurityStamp)
 	})
 
 	t.Run("success confirm change password", func(t *testing.T) {
@@ -127,9 +127,9 @@ func TestUserChangePasswordConfirmSuccess(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotEmpty(t, jwt)
 
-		usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email": userOld.Email,
-		}, nil)
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", userOld.Email),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, usr)
 		assert.Equal(t, usr.Email, userNew.Email)
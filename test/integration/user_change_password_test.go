@@ -14,7 +14,6 @@ import (
 	"github.com/wicaker/user/internal/domain"
 	"github.com/wicaker/user/internal/middleware"
 	"github.com/wicaker/user/internal/repository"
-	"github.com/wicaker/user/internal/usecase"
 	"github.com/wicaker/user/test/dbfixture"
 )
 
@@ -242,7 +241,7 @@ func TestUserChangePasswordSuccess(t *testing.T) {
 
 	var (
 		userRepo     = repository.NewUserSqlxRepository(dbConn)
-		userUsecase  = usecase.NewUserUsecase(time.Duration(2)*time.Second, userRepo)
+		userUsecase  = newUserUsecase(userRepo)
 		newPassoword = "newpassword"
 		userNew      = domain.User{
 			Email:       users[0].Email,
@@ -278,18 +277,19 @@ func TestUserChangePasswordSuccess(t *testing.T) {
 	assert.Error(t, err)
 	assert.Empty(t, jwt)
 
-	usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-		"email": users[0].Email,
-	}, nil)
+	usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+		domain.Eq("email", users[0].Email),
+	))
 	assert.NoError(t, err)
 	assert.NotEmpty(t, usr)
 	assert.Equal(t, usr.Email, userNew.Email)
 	assert.NotEmpty(t, usr.NewPassword)
 
+	relayOutbox()
 	assert.Equal(t, "user.change_password", publishedMessage.RoutingKey)
 	msg := getMessageInMq()
 	token, err := middleware.JwtVerify(msg.Token)
 	assert.NoError(t, err)
 	assert.Equal(t, users[0].Email, msg.EmailDestination)
-	assert.Equal(t, usr.Salt, token.Salt)
+	assert.Equal(t, usr.SecurityStamp, token.SecurityStamp)
 }
@@ -0,0 +1,163 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func consentRequest(method string, path string, token string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRealIP, "203.0.113.7")
+	req.Header.Set("User-Agent", "consent-test")
+	if token != "" {
+		req.Header.Set("x-access-token", token)
+	}
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterConsent(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	_, err := dbfixture.SeedPolicyDocuments(dbConn, "1.0")
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("error without consent", func(t *testing.T) {
+		var resp domain.Response
+
+		w := consentRequest(http.MethodPost, "/user/register", "", `{"email":"consent@mail.com","password":"123"}`)
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+		assert.Equal(t, domain.ErrPolicyNotAccepted.Error(), resp.Message)
+	})
+
+	t.Run("error consent of unknown version", func(t *testing.T) {
+		w := consentRequest(http.MethodPost, "/user/register", "", `{"email":"consent@mail.com","password":"123","consents":[
+			{"kind":"terms_of_service","version":"0.9"},{"kind":"privacy_policy","version":"1.0"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		w := consentRequest(http.MethodPost, "/user/register", "", `{"email":"consent@mail.com","password":"123","consents":[
+			{"kind":"terms_of_service","version":"1.0"},{"kind":"privacy_policy","version":"1.0"}]}`)
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		getMessageInMq()
+
+		var consents []struct {
+			IPAddress string `db:"ip_address"`
+			UserAgent string `db:"user_agent"`
+		}
+		err := dbConn.Select(&consents, `SELECT c.ip_address, c.user_agent FROM consents c JOIN users u ON u.uuid = c.user_uuid WHERE u.email=$1`, "consent@mail.com")
+		assert.NoError(t, err)
+		assert.Len(t, consents, 2)
+		assert.Equal(t, "203.0.113.7", consents[0].IPAddress)
+		assert.Equal(t, "consent-test", consents[0].UserAgent)
+	})
+}
+
+func TestReConsentGate(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = dbConn.Exec(`UPDATE users SET role=$1 WHERE uuid=$2`, domain.RoleAdmin, users[1].UUID)
+	assert.NoError(t, err)
+
+	_, err = dbfixture.SeedPolicyDocuments(dbConn, "1.0")
+	if err != nil {
+		t.Error(err)
+	}
+
+	token := createJWT(users[0], time.Minute)
+	adminToken := createJWT(users[1], time.Minute)
+
+	t.Run("error authenticated endpoint before consent", func(t *testing.T) {
+		var resp domain.Response
+
+		w := consentRequest(http.MethodPut, "/user/email", token, `{"email":"changed@example.com","password":"Password1"}`)
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		assert.Equal(t, domain.ErrConsentRequired.Error(), resp.Message)
+		assert.Len(t, resp.Data["documents"], 2)
+	})
+
+	t.Run("success accept", func(t *testing.T) {
+		w := consentRequest(http.MethodPost, "/user/consents", token, `{"consents":[
+			{"kind":"terms_of_service","version":"1.0"},{"kind":"privacy_policy","version":"1.0"}]}`)
+		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	})
+
+	t.Run("success publish new version", func(t *testing.T) {
+		w := consentRequest(http.MethodPost, "/user/consents", adminToken, `{"consents":[
+			{"kind":"terms_of_service","version":"1.0"},{"kind":"privacy_policy","version":"1.0"}]}`)
+		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+		w = consentRequest(http.MethodPost, "/policies", token, `{"kind":"terms_of_service","version":"2.0","url":"https://example.com/legal/tos/2.0"}`)
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+
+		w = consentRequest(http.MethodPost, "/policies", adminToken, `{"kind":"terms_of_service","version":"2.0","url":"https://example.com/legal/tos/2.0"}`)
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+		w = consentRequest(http.MethodPost, "/policies", adminToken, `{"kind":"terms_of_service","version":"2.0","url":"https://example.com/legal/tos/2.0"}`)
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+	})
+
+	t.Run("error authenticated endpoint after new version", func(t *testing.T) {
+		var resp domain.Response
+
+		w := consentRequest(http.MethodPut, "/user/password/change", token, `{"email":"user1@example.com","password":"Password1","new_password":"Password2"}`)
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		assert.Len(t, resp.Data["documents"], 1)
+
+		w = consentRequest(http.MethodPost, "/user/consents", token, `{"consents":[{"kind":"terms_of_service","version":"1.0"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+
+		w = consentRequest(http.MethodPost, "/user/consents", token, `{"consents":[{"kind":"terms_of_service","version":"2.0"}]}`)
+		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	})
+
+	t.Run("success consent history", func(t *testing.T) {
+		var resp struct {
+			Data struct {
+				Consents []domain.Consent `json:"consents"`
+			} `json:"data"`
+		}
+
+		w := consentRequest(http.MethodGet, "/user/consents", token, "")
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Len(t, resp.Data.Consents, 3)
+		assert.Equal(t, "2.0", resp.Data.Consents[0].Version)
+		assert.Equal(t, "203.0.113.7", *resp.Data.Consents[0].IPAddress)
+	})
+}
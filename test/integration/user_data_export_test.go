@@ -0,0 +1,218 @@
+package integration_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/internal/worker"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+type dataExportResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		Export domain.DataExport `json:"export"`
+	} `json:"data"`
+}
+
+func exportRequest(method string, path string, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("x-access-token", token)
+	}
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func signExportLink(id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", id, expires)))
+	return fmt.Sprintf("/user/exports/%s/download?expires=%d&signature=%s", id, expires, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestDataExport(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	profiles, err := dbfixture.SeedProfiles(dbConn, 1)
+	require.NoError(t, err)
+	user := profiles[0].User
+	require.NoError(t, makeUserActive(&user))
+	token := createJWT(user, time.Minute)
+
+	var export domain.DataExport
+
+	t.Run("success request", func(t *testing.T) {
+		var resp dataExportResponse
+
+		w := exportRequest(http.MethodPost, "/user/exports", token)
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, w.Result().StatusCode)
+		assert.NotEmpty(t, resp.Data.Export.UUID)
+		export = resp.Data.Export
+	})
+
+	t.Run("success ready", func(t *testing.T) {
+		var resp dataExportResponse
+
+		for i := 0; i < 50 && resp.Data.Export.Status != domain.ExportReady; i++ {
+			time.Sleep(100 * time.Millisecond)
+			w := exportRequest(http.MethodGet, "/user/exports/"+export.UUID, token)
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		require.Equal(t, domain.ExportReady, resp.Data.Export.Status)
+		assert.NotEmpty(t, resp.Data.Export.DownloadURL)
+
+		relayOutbox()
+		assert.Equal(t, "user.export_ready", exportMessage.RoutingKey)
+		var msg map[string]interface{}
+		assert.NoError(t, unmarshalEvent(exportMessage, &msg))
+		assert.Equal(t, user.Email, msg["email_destination"])
+		assert.Equal(t, export.UUID, msg["export_uuid"])
+	})
+
+	t.Run("error another user", func(t *testing.T) {
+		other, err := dbfixture.SeedActiveUsers(dbConn, 1)
+		require.NoError(t, err)
+
+		w := exportRequest(http.MethodGet, "/user/exports/"+export.UUID, createJWT(other[0], time.Minute))
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("success download", func(t *testing.T) {
+		w := exportRequest(http.MethodGet, signExportLink(export.UUID, time.Now().Add(time.Minute).Unix()), "")
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		body := w.Body.Bytes()
+		archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+
+		files := make(map[string][]byte)
+		for _, f := range archive.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			files[f.Name], err = ioutil.ReadAll(rc)
+			require.NoError(t, err)
+			rc.Close()
+		}
+
+		var exportedUser map[string]interface{}
+		assert.NoError(t, json.Unmarshal(files["user.json"], &exportedUser))
+		assert.Equal(t, user.Email, exportedUser["email"])
+		assert.NotContains(t, exportedUser, "password")
+		assert.NotContains(t, exportedUser, "salt")
+
+		var exportedProfile map[string]interface{}
+		assert.NoError(t, json.Unmarshal(files["profile.json"], &exportedProfile))
+		assert.Equal(t, *profiles[0].FirstName, exportedProfile["first_name"])
+
+		assert.Contains(t, files, "consents.json")
+		assert.Contains(t, files, "audit_events.json")
+		assert.Contains(t, files, "logins.json")
+	})
+
+	t.Run("error tampered link", func(t *testing.T) {
+		w := exportRequest(http.MethodGet, "/user/exports/"+export.UUID+"/download?expires=9999999999&signature=00", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("error expired link", func(t *testing.T) {
+		w := exportRequest(http.MethodGet, signExportLink(export.UUID, time.Now().Add(-time.Minute).Unix()), "")
+		assert.Equal(t, http.StatusGone, w.Result().StatusCode)
+	})
+}
+
+func TestDataExportStalePending(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+	require.NoError(t, err)
+	token := createJWT(users[0], time.Minute)
+
+	// export left pending by a build stopped before it completed
+	var stale string
+	err = dbConn.Get(&stale, "INSERT INTO data_exports (user_uuid, created_at) VALUES ($1, $2) RETURNING uuid", users[0].UUID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	t.Run("success stale pending export is replaced", func(t *testing.T) {
+		var resp dataExportResponse
+
+		w := exportRequest(http.MethodPost, "/user/exports", token)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusAccepted, w.Result().StatusCode)
+		assert.NotEqual(t, stale, resp.Data.Export.UUID)
+
+		var status string
+		assert.NoError(t, dbConn.Get(&status, "SELECT status FROM data_exports WHERE uuid = $1", stale))
+		assert.Equal(t, domain.ExportFailed, status)
+	})
+}
+
+func TestExportSweeper(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+	require.NoError(t, err)
+
+	// export left pending by a build stopped with a previous process, and one still being built
+	var stale, building string
+	err = dbConn.Get(&stale, "INSERT INTO data_exports (user_uuid, created_at) VALUES ($1, $2) RETURNING uuid", users[0].UUID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	err = dbConn.Get(&building, "INSERT INTO data_exports (user_uuid) VALUES ($1) RETURNING uuid", users[0].UUID)
+	require.NoError(t, err)
+
+	sweeper := worker.NewExportSweeper(repository.NewDataExportSqlxRepository(dbConn), time.Minute)
+
+	t.Run("success stale pending export is failed", func(t *testing.T) {
+		n, err := sweeper.Sweep(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		var export domain.DataExport
+		require.NoError(t, dbConn.Get(&export, "SELECT * FROM data_exports WHERE uuid = $1", stale))
+		assert.Equal(t, domain.ExportFailed, export.Status)
+		if assert.NotNil(t, export.Error) {
+			assert.Equal(t, domain.ExportTimedOut, *export.Error)
+		}
+
+		require.NoError(t, dbConn.Get(&export, "SELECT * FROM data_exports WHERE uuid = $1", building))
+		assert.Equal(t, domain.ExportPending, export.Status)
+	})
+
+	t.Run("success nothing left to sweep", func(t *testing.T) {
+		n, err := sweeper.Sweep(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+}
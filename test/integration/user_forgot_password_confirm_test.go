@@ -15,6 +15,7 @@ import (
 	"github.com/wicaker/user/internal/domain"
 	"github.com/wicaker/user/internal/repository"
 	"github.com/wicaker/user/test/dbfixture"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestForgotPasswordConfirmParsedTokenDataInvalid(t *testing.T) {
@@ -32,7 +33,7 @@ func TestForgotPasswordConfirmParsedTokenDataInvalid(t *testing.T) {
 	var (
 		userRepo    = repository.NewUserSqlxRepository(dbConn)
 		newPassword = "newpassword"
-		jwt         = createJWT(users[0], time.Minute*2)
+		jwt         = createTypedJWT(users[0], domain.TokenForgotPassword, time.Minute*2)
 		resp        domain.Response
 		reqBody     = domain.User{
 			NewPassword: &newPassword,
@@ -54,10 +55,10 @@ func TestForgotPasswordConfirmParsedTokenDataInvalid(t *testing.T) {
 	assert.NotEmpty(t, resp.Message)
 	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
 
-	usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-		"email":    users[0].Email,
-		"password": users[0].Password,
-	}, nil)
+	usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+		domain.Eq("email", users[0].Email),
+		domain.Eq("password", users[0].Password),
+	))
 	assert.NoError(t, err)
 	assert.NotEmpty(t, usr)
 }
@@ -77,7 +78,7 @@ func TestForgotPasswordConfirmNewPasswordInvalid(t *testing.T) {
 	var (
 		userRepo    = repository.NewUserSqlxRepository(dbConn)
 		newPassword = ""
-		jwt         = createJWT(users[0], time.Minute*2)
+		jwt         = createTypedJWT(users[0], domain.TokenForgotPassword, time.Minute*2)
 		resp        domain.Response
 		reqBody     = domain.User{
 			NewPassword: &newPassword,
@@ -100,10 +101,10 @@ func TestForgotPasswordConfirmNewPasswordInvalid(t *testing.T) {
 		assert.NotEmpty(t, resp.Message)
 		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
 
-		usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email":    users[0].Email,
-			"password": users[0].Password,
-		}, nil)
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", users[0].Email),
+			domain.Eq("password", users[0].Password),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, usr)
 	})
@@ -124,7 +125,7 @@ func TestForgotPasswordConfirmSuccess(t *testing.T) {
 	var (
 		userRepo    = repository.NewUserSqlxRepository(dbConn)
 		newPassword = "newpassword"
-		jwt         = createJWT(users[0], time.Minute*2)
+		jwt         = createTypedJWT(users[0], domain.TokenForgotPassword, time.Minute*2)
 		resp        domain.Response
 		reqBody     = domain.User{
 			NewPassword: &newPassword,
@@ -134,7 +135,20 @@ func TestForgotPasswordConfirmSuccess(t *testing.T) {
 	t.Run("make user active", func(t *testing.T) {
 		err := makeUserActive(&users[0])
 		assert.NoError(t, err)
-		jwt = createJWT(users[0], time.Minute*2)
+		jwt = createTypedJWT(users[0], domain.TokenForgotPassword, time.Minute*2)
+	})
+
+	t.Run("failed, because the token is a login token", func(t *testing.T) {
+		j, err := json.Marshal(reqBody)
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/user/password/forgot/%s", createJWT(users[0], time.Minute*2)), strings.NewReader(string(j)))
+		assert.NoError(t, err)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
 	})
 
 	t.Run("success", func(t *testing.T) {
@@ -153,12 +167,15 @@ func TestForgotPasswordConfirmSuccess(t *testing.T) {
 		assert.NotEmpty(t, resp.Message)
 		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
 
-		usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email":    users[0].Email,
-			"password": reqBody.NewPassword,
-		}, nil)
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", users[0].Email),
+		))
 		assert.NoError(t, err)
 		assert.NotEmpty(t, usr)
+
+		// new password is stored as bcrypt hash
+		assert.NotEqual(t, newPassword, usr.Password)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(usr.Password), []byte(newPassword)))
 	})
 
 }
@@ -113,10 +113,11 @@ func TestForgotPasswordSuccess(t *testing.T) {
 	assert.NotEmpty(t, resp.Message)
 	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
 
+	relayOutbox()
 	assert.Equal(t, "user.forgot_password", publishedMessage.RoutingKey)
 	msg := getMessageInMq()
 	parsedToken, err := middleware.JwtVerify(msg.Token)
 	assert.NoError(t, err)
 	assert.Equal(t, users[0].Email, msg.EmailDestination)
-	assert.NotEmpty(t, parsedToken.Salt)
+	assert.NotEmpty(t, parsedToken.SecurityStamp)
 }
@@ -0,0 +1,124 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/test/dbfixture"
+	"github.com/wicaker/user/test/mock"
+)
+
+const (
+	firefoxLinux  = "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:78.0) Gecko/20100101 Firefox/78.0"
+	firefoxLinux2 = "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:80.0) Gecko/20100101 Firefox/80.0"
+	safariIphone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 13_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.1.1 Mobile/15E148 Safari/604.1"
+)
+
+func loginWithUserAgent(userAgent string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/user/login", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRealIP, "198.51.100.20")
+	req.Header.Set("User-Agent", userAgent)
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginHistory(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+	defer func() {
+		newLoginMessage = mock.Message{}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 2)
+	require.NoError(t, err)
+
+	t.Run("success, first login is not notified", func(t *testing.T) {
+		w := loginWithUserAgent(firefoxLinux, `{"email":"user1@example.com","password":"Password1"}`)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		relayOutbox()
+		assert.Empty(t, newLoginMessage.Message)
+	})
+
+	t.Run("success, browser update is the same device", func(t *testing.T) {
+		w := loginWithUserAgent(firefoxLinux2, `{"email":"user1@example.com","password":"Password1"}`)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		relayOutbox()
+		assert.Empty(t, newLoginMessage.Message)
+	})
+
+	t.Run("failed login is recorded", func(t *testing.T) {
+		w := loginWithUserAgent(safariIphone, `{"email":"user1@example.com","password":"wrong"}`)
+		require.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		relayOutbox()
+		assert.Empty(t, newLoginMessage.Message)
+	})
+
+	t.Run("success, new device is notified", func(t *testing.T) {
+		w := loginWithUserAgent(safariIphone, `{"email":"user1@example.com","password":"Password1"}`)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		relayOutbox()
+		require.NotEmpty(t, newLoginMessage.Message)
+		assert.Equal(t, "user.new_login", newLoginMessage.RoutingKey)
+
+		var message map[string]interface{}
+		require.NoError(t, unmarshalEvent(newLoginMessage, &message))
+		assert.Equal(t, users[0].Email, message["email_destination"])
+		assert.Equal(t, []interface{}{"new_device"}, message["reasons"])
+		assert.Equal(t, domain.DeviceMobile, message["device"])
+	})
+
+	t.Run("success get login history", func(t *testing.T) {
+		var resp struct {
+			Data struct {
+				Logins []domain.LoginAttempt `json:"logins"`
+			} `json:"data"`
+		}
+
+		w := consentRequest(http.MethodGet, "/user/logins?limit=10", createJWT(users[0], time.Minute), "")
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data.Logins, 4)
+
+		// newest first
+		assert.True(t, resp.Data.Logins[0].Success)
+		assert.Equal(t, "Safari", resp.Data.Logins[0].Browser)
+		assert.Equal(t, "198.51.100.20", resp.Data.Logins[0].IPAddress)
+		assert.False(t, resp.Data.Logins[1].Success)
+		assert.NotNil(t, resp.Data.Logins[1].FailureReason)
+		assert.Equal(t, "Firefox", resp.Data.Logins[3].Browser)
+		assert.Equal(t, domain.DeviceDesktop, resp.Data.Logins[3].Device)
+	})
+
+	t.Run("success, login history of another user is empty", func(t *testing.T) {
+		var resp struct {
+			Data struct {
+				Logins []domain.LoginAttempt `json:"logins"`
+			} `json:"data"`
+		}
+
+		w := consentRequest(http.MethodGet, "/user/logins", createJWT(users[1], time.Minute), "")
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Empty(t, resp.Data.Logins)
+	})
+
+	t.Run("error without token", func(t *testing.T) {
+		w := consentRequest(http.MethodGet, "/user/logins", "", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,117 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func loginRequest(email string, password string) (*httptest.ResponseRecorder, domain.Response) {
+	var resp domain.Response
+
+	j, _ := json.Marshal(domain.User{
+		Email:    email,
+		Password: password,
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/user/login", strings.NewReader(string(j)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+	return w, resp
+}
+
+func TestLoginLdapProvisionUser(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	userRepo := repository.NewUserSqlxRepository(dbConn)
+
+	t.Run("success, admin group mapped to admin role", func(t *testing.T) {
+		w, resp := loginRequest("alice@corp.example.org", "AlicePassword1")
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.NotEmpty(t, resp.Data["token"])
+
+		parsedToken, err := middleware.JwtVerify(fmt.Sprintf("%v", resp.Data["token"]))
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleAdmin, parsedToken.Role)
+
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", "alice@corp.example.org"),
+		))
+		assert.NoError(t, err)
+		assert.NotNil(t, usr)
+		assert.True(t, usr.IsActive)
+		assert.Equal(t, domain.RoleAdmin, usr.Role)
+	})
+
+	t.Run("success, second login reuse provisioned user", func(t *testing.T) {
+		w, _ := loginRequest("alice@corp.example.org", "AlicePassword1")
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		users, err := userRepo.FindAll(context.TODO())
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+	})
+
+	t.Run("success, user without mapped group get default role", func(t *testing.T) {
+		w, _ := loginRequest("bob@corp.example.org", "BobPassword1")
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", "bob@corp.example.org"),
+		))
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleUser, usr.Role)
+	})
+}
+
+func TestLoginLdapFailed(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	t.Run("failed, wrong password", func(t *testing.T) {
+		w, resp := loginRequest("alice@corp.example.org", "wrong")
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		assert.Equal(t, domain.ErrWrongPassword.Error(), resp.Message)
+	})
+
+	t.Run("failed, user not in directory", func(t *testing.T) {
+		w, resp := loginRequest("carol@corp.example.org", "CarolPassword1")
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+		assert.Equal(t, domain.ErrUserNotFound.Error(), resp.Message)
+	})
+
+	t.Run("failed, local password ignored for directory domain", func(t *testing.T) {
+		users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+		assert.NoError(t, err)
+
+		users[0].Email = "carol@corp.example.org"
+		err = makeUserActive(&users[0])
+		assert.NoError(t, err)
+
+		w, _ := loginRequest("carol@corp.example.org", "Password1")
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,86 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	tenants, err := dbfixture.SeedTenants(dbConn, 1)
+	require.NoError(t, err)
+	users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+	require.NoError(t, err)
+
+	_, err = dbConn.Exec(`UPDATE tenants SET password_history_size=3, password_max_age_days=30 WHERE uuid=$1`, tenants[0].UUID)
+	require.NoError(t, err)
+	_, err = dbConn.Exec(`UPDATE users SET tenant_uuid=$1, password_changed_at=now() - interval '31 days' WHERE uuid=$2`, tenants[0].UUID, users[0].UUID)
+	require.NoError(t, err)
+
+	var restrictedToken string
+
+	t.Run("error login with expired password", func(t *testing.T) {
+		var resp domain.Response
+
+		w := consentRequest(http.MethodPost, "/user/login", "", fmt.Sprintf(`{"email":"%s","password":"Password1"}`, users[0].Email))
+		require.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, domain.ErrPasswordChangeRequired.Error(), resp.Message)
+		assert.Equal(t, "password_change_required", resp.Data["error"])
+
+		restrictedToken = resp.Data["token"].(string)
+		parsedToken, err := middleware.JwtVerifyScope(restrictedToken, domain.ScopePasswordChange)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ScopePasswordChange, parsedToken.Scope)
+	})
+
+	t.Run("error restricted token on other endpoint", func(t *testing.T) {
+		w := consentRequest(http.MethodGet, "/user/logins", restrictedToken, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("error reuse current password", func(t *testing.T) {
+		w := consentRequest(http.MethodPut, "/user/password/change", restrictedToken,
+			fmt.Sprintf(`{"email":"%s","password":"Password1","new_password":"Password1"}`, users[0].Email))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	})
+
+	t.Run("success change expired password", func(t *testing.T) {
+		w := consentRequest(http.MethodPut, "/user/password/change", restrictedToken,
+			fmt.Sprintf(`{"email":"%s","password":"Password1","new_password":"Password2"}`, users[0].Email))
+		require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+		msg := getMessageInMq()
+		w = consentRequest(http.MethodPut, "/user/password/change/"+msg.Token, "", "")
+		require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+		w = consentRequest(http.MethodPost, "/user/login", "", fmt.Sprintf(`{"email":"%s","password":"Password2"}`, users[0].Email))
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("error reuse replaced password", func(t *testing.T) {
+		token := createJWT(users[0], time.Minute*2)
+		w := consentRequest(http.MethodPut, "/user/password/change", token,
+			fmt.Sprintf(`{"email":"%s","password":"Password2","new_password":"Password1"}`, users[0].Email))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+
+		var count int
+		require.NoError(t, dbConn.Get(&count, `SELECT count(*) FROM password_history WHERE user_uuid=$1`, users[0].UUID))
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,134 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+type profileResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		Profile struct {
+			UUID      string  `json:"uuid"`
+			FirstName *string `json:"first_name"`
+			Version   int     `json:"version"`
+		} `json:"profile"`
+	} `json:"data"`
+}
+
+func profileRequest(method string, token string, ifMatch string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/user/profile", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("x-access-token", token)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func TestProfileVersion(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+	require.NoError(t, err)
+	token := createJWT(users[0], time.Minute*2)
+
+	t.Run("error profile not found", func(t *testing.T) {
+		w := profileRequest(http.MethodGet, token, "", "")
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("error conditional create of missing profile", func(t *testing.T) {
+		w := profileRequest(http.MethodPut, token, `"1"`, `{"first_name": "John"}`)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Result().StatusCode)
+	})
+
+	var etag string
+	t.Run("success create profile", func(t *testing.T) {
+		var resp profileResponse
+
+		w := profileRequest(http.MethodPut, token, "", `{"first_name": "John", "gender": "m"}`)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.Data.Profile.Version)
+		require.NotNil(t, resp.Data.Profile.FirstName)
+		assert.Equal(t, "John", *resp.Data.Profile.FirstName)
+
+		etag = w.Header().Get("ETag")
+		assert.Equal(t, `"1"`, etag)
+	})
+
+	t.Run("success conditional update increments version", func(t *testing.T) {
+		w := profileRequest(http.MethodPut, token, etag, `{"first_name": "Jane"}`)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		w = profileRequest(http.MethodGet, token, "", "")
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("error stale if-match", func(t *testing.T) {
+		w := profileRequest(http.MethodPut, token, etag, `{"first_name": "Lost"}`)
+		require.Equal(t, http.StatusPreconditionFailed, w.Result().StatusCode)
+
+		var resp domain.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, domain.ErrPreconditionFailed.Error(), resp.Message)
+	})
+
+	t.Run("error invalid if-match", func(t *testing.T) {
+		w := profileRequest(http.MethodPut, token, "2", `{"first_name": "Lost"}`)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Result().StatusCode)
+	})
+
+	t.Run("error concurrent repository update", func(t *testing.T) {
+		var (
+			userRepo    = repository.NewUserSqlxRepository(dbConn)
+			profileRepo = repository.NewProfileSqlxRepository(dbConn, testKeyring())
+		)
+
+		profile, err := profileRepo.FindOneBy(context.TODO(), domain.Where(domain.Eq("user_uuid", users[0].UUID)))
+		require.NoError(t, err)
+		require.NotNil(t, profile)
+		profile.User = users[0]
+
+		stale := *profile
+		require.NoError(t, profileRepo.Update(context.TODO(), profile))
+		assert.Equal(t, stale.Version+1, profile.Version)
+		assert.Equal(t, domain.ErrConflict, profileRepo.Update(context.TODO(), &stale))
+
+		missing := stale
+		missing.UUID = "00000000-0000-0000-0000-000000000000"
+		assert.Equal(t, domain.ErrProfileNotFound, profileRepo.Update(context.TODO(), &missing))
+
+		user, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		staleUser := *user
+		_, err = userRepo.Update(context.TODO(), user)
+		require.NoError(t, err)
+		_, err = userRepo.Update(context.TODO(), &staleUser)
+		assert.Equal(t, domain.ErrConflict, errors.Cause(err))
+	})
+}
@@ -0,0 +1,172 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/transport"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+// apiWithEnv build echo server of the given environment, the previous environment is restored afterward
+func apiWithEnv(env map[string]string) *echo.Echo {
+	for k, v := range env {
+		old, ok := os.LookupEnv(k)
+		os.Setenv(k, v)
+		if ok {
+			defer os.Setenv(k, old)
+		} else {
+			defer os.Unsetenv(k)
+		}
+	}
+
+	return transport.Echo(dbConn, nil, listrmq)
+}
+
+func registerRequest(e *echo.Echo, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/user/register", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func inviteRequest(e *echo.Echo, token string, email string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/user/invitations", strings.NewReader(`{"email":"`+email+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("x-access-token", token)
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterDisposableEmail(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	var resp domain.Response
+
+	w := registerRequest(api, `{"email":"someone@mailinator.com","password":"123"}`)
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	assert.Equal(t, domain.ErrDisposableEmail.Error(), resp.Message)
+
+	w = registerRequest(api, `{"email":"someone@eu.mailinator.com","password":"123"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+}
+
+func TestRegisterInviteOnly(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	e := apiWithEnv(map[string]string{"REGISTRATION_MODE": "invite"})
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = dbConn.Exec(`UPDATE users SET role=$1 WHERE uuid=$2`, domain.RoleAdmin, users[0].UUID)
+	assert.NoError(t, err)
+
+	var inviteToken string
+
+	t.Run("error without invitation", func(t *testing.T) {
+		var resp domain.Response
+
+		w := registerRequest(e, `{"email":"invited@mail.com","password":"123"}`)
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		assert.Equal(t, domain.ErrInvitationRequired.Error(), resp.Message)
+	})
+
+	t.Run("error invited by non admin", func(t *testing.T) {
+		w := inviteRequest(e, createJWT(users[1], time.Minute), "invited@mail.com")
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("success invite", func(t *testing.T) {
+		var resp domain.Response
+
+		w := inviteRequest(e, createJWT(users[0], time.Minute), "invited@mail.com")
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		assert.Equal(t, "invited@mail.com", resp.Data["email"])
+
+		relayOutbox()
+		assert.Equal(t, "user.invite", publishedMessage.RoutingKey)
+		msg := getMessageInMq()
+		assert.Equal(t, "invited@mail.com", msg.EmailDestination)
+		assert.NotEmpty(t, msg.Token)
+		inviteToken = msg.Token
+	})
+
+	t.Run("error invitation of another email", func(t *testing.T) {
+		var resp domain.Response
+
+		w := registerRequest(e, `{"email":"other@mail.com","password":"123","invite_token":"`+inviteToken+`"}`)
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusGone, w.Result().StatusCode)
+		assert.Equal(t, domain.ErrInvalidInvitation.Error(), resp.Message)
+	})
+
+	t.Run("success register with invitation", func(t *testing.T) {
+		w := registerRequest(e, `{"email":"invited@mail.com","password":"123","invite_token":"`+inviteToken+`"}`)
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		getMessageInMq()
+	})
+
+	t.Run("error invitation already accepted", func(t *testing.T) {
+		w := registerRequest(e, `{"email":"invited@mail.com","password":"123","invite_token":"`+inviteToken+`"}`)
+		assert.Equal(t, http.StatusGone, w.Result().StatusCode)
+	})
+}
+
+func TestRegisterAllowedDomains(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	e := apiWithEnv(map[string]string{
+		"REGISTRATION_MODE":            "domain",
+		"REGISTRATION_ALLOWED_DOMAINS": "example.com, example.org",
+	})
+
+	t.Run("success allowed domain", func(t *testing.T) {
+		w := registerRequest(e, `{"email":"someone@Example.org","password":"123"}`)
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		getMessageInMq()
+	})
+
+	t.Run("error domain not allowed", func(t *testing.T) {
+		var resp domain.Response
+
+		w := registerRequest(e, `{"email":"someone@mail.com","password":"123"}`)
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+		assert.Equal(t, domain.ErrEmailDomainNotAllowed.Error(), resp.Message)
+	})
+}
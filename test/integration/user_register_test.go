@@ -53,18 +53,19 @@ func TestRegisterHandlerSuccess(t *testing.T) {
 		assert.Nil(t, resp.Data)
 		assert.Nil(t, resp.Errors)
 
-		usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email": &mockUser.Email,
-		}, nil)
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", &mockUser.Email),
+		))
 		assert.False(t, usr.IsActive)
-		assert.NotEmpty(t, usr.Salt)
+		assert.NotEmpty(t, usr.SecurityStamp)
 
+		relayOutbox()
 		assert.Equal(t, "user.register", publishedMessage.RoutingKey)
 		msg := getMessageInMq()
 		parsedToken, err := middleware.JwtVerify(msg.Token)
 		assert.NoError(t, err)
 		assert.Equal(t, "register1@mail.com", msg.EmailDestination)
-		assert.Equal(t, usr.Salt, parsedToken.Salt)
+		assert.Equal(t, usr.SecurityStamp, parsedToken.SecurityStamp)
 		assert.NotEmpty(t, parsedToken.UUID)
 	})
 
@@ -90,19 +91,20 @@ func TestRegisterHandlerSuccess(t *testing.T) {
 		assert.Nil(t, resp.Data)
 		assert.Nil(t, resp.Errors)
 
-		usr, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email": &mockUser.Email,
-		}, nil)
+		usr, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", &mockUser.Email),
+		))
 		assert.False(t, usr.IsActive)
-		assert.NotEmpty(t, usr.Salt)
+		assert.NotEmpty(t, usr.SecurityStamp)
 		mockUser.UUID = usr.UUID
 
+		relayOutbox()
 		assert.Equal(t, "user.register", publishedMessage.RoutingKey)
 		msg := getMessageInMq()
 		parsedToken, err := middleware.JwtVerify(msg.Token)
 		assert.NoError(t, err)
 		assert.Equal(t, "register1@mail.com", msg.EmailDestination)
-		assert.Equal(t, usr.Salt, parsedToken.Salt)
+		assert.Equal(t, usr.SecurityStamp, parsedToken.SecurityStamp)
 		assert.NotEmpty(t, parsedToken.UUID)
 	})
 }
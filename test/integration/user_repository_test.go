@@ -91,9 +91,9 @@ func TestUserRepositoryFindOneBy(t *testing.T) {
 	}
 
 	t.Run("success find user", func(t *testing.T) {
-		usrs, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email": &users[0].Email,
-		}, nil)
+		usrs, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", &users[0].Email),
+		))
 
 		assert.NotNil(t, usrs)
 		assert.NoError(t, err)
@@ -103,14 +103,85 @@ func TestUserRepositoryFindOneBy(t *testing.T) {
 
 	t.Run("no rows in result set", func(t *testing.T) {
 		email := "emailnotfound@example.com"
-		usrs, err := userRepo.FindOneBy(context.TODO(), map[string]interface{}{
-			"email": &email,
-		}, nil)
+		usrs, err := userRepo.FindOneBy(context.TODO(), domain.Where(
+			domain.Eq("email", &email),
+		))
 		assert.Nil(t, usrs)
 		assert.NoError(t, err)
 	})
 }
 
+func TestUserRepositoryFindBy(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+	userRepo := repository.NewUserSqlxRepository(dbConn)
+
+	// prepare data
+	users, err := dbfixture.SeedUsers(dbConn, 5)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("success find users by IN and OR group", func(t *testing.T) {
+		usrs, err := userRepo.FindBy(context.TODO(), domain.Where(
+			domain.In("email", users[0].Email, users[1].Email, users[2].Email),
+			domain.Or(
+				domain.Eq("uuid", users[0].UUID),
+				domain.Like("email", domain.EscapeLike("USER3")+"%"),
+			),
+		).OrderBy(domain.Asc("email")))
+		assert.NoError(t, err)
+		assert.Len(t, usrs, 2)
+		assert.Equal(t, users[0].UUID, usrs[0].UUID)
+		assert.Equal(t, users[2].UUID, usrs[1].UUID)
+	})
+
+	t.Run("success find users by range and not equal", func(t *testing.T) {
+		usrs, err := userRepo.FindBy(context.TODO(), domain.Where(
+			domain.Between("created_at", users[0].CreatedAt, time.Now().Add(time.Minute)),
+			domain.NotEq("email", users[4].Email),
+			domain.IsNull("new_password"),
+		).OrderBy(domain.Desc("email")).Paginate(2, 1))
+		assert.NoError(t, err)
+		assert.Len(t, usrs, 2)
+		assert.Equal(t, users[2].UUID, usrs[0].UUID)
+		assert.Equal(t, users[1].UUID, usrs[1].UUID)
+	})
+
+	t.Run("success count users", func(t *testing.T) {
+		count, err := userRepo.Count(context.TODO(), domain.Where(
+			domain.Gte("created_at", users[0].CreatedAt),
+		).OrderBy(domain.Asc("email")).Paginate(1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, 5, count)
+	})
+
+	t.Run("empty IN and OR match nothing", func(t *testing.T) {
+		usrs, err := userRepo.FindBy(context.TODO(), domain.Where(domain.In("email")))
+		assert.NoError(t, err)
+		assert.Empty(t, usrs)
+
+		usrs, err = userRepo.FindBy(context.TODO(), domain.Where(domain.Or()))
+		assert.NoError(t, err)
+		assert.Empty(t, usrs)
+	})
+
+	t.Run("failed column which is not whitelisted", func(t *testing.T) {
+		usrs, err := userRepo.FindBy(context.TODO(), domain.Where(
+			domain.Eq("email=email OR 1", 1),
+		))
+		assert.Equal(t, domain.ErrInvalidQuery, err)
+		assert.Empty(t, usrs)
+
+		usrs, err = userRepo.FindBy(context.TODO(), domain.Where().OrderBy(domain.Asc("email; DROP TABLE users")))
+		assert.Equal(t, domain.ErrInvalidQuery, err)
+		assert.Empty(t, usrs)
+	})
+}
+
 func TestUserRepositoryStore(t *testing.T) {
 	defer func() {
 		if err := dbfixture.Truncate(dbConn); err != nil {
@@ -0,0 +1,75 @@
+package integration_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/repository"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func TestSecurityStamp(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+	require.NoError(t, err)
+
+	var (
+		userRepo = repository.NewUserSqlxRepository(dbConn)
+		token    = createJWT(users[0], time.Hour)
+	)
+
+	t.Run("success token survives unrelated update", func(t *testing.T) {
+		user, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+
+		newPassword := "unconfirmed"
+		user.NewPassword = &newPassword
+		_, err = userRepo.Update(context.TODO(), user)
+		require.NoError(t, err)
+
+		w := consentRequest(http.MethodGet, "/user/logins", token, "")
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("error token without security stamp", func(t *testing.T) {
+		user := users[0]
+		user.SecurityStamp = ""
+
+		w := consentRequest(http.MethodGet, "/user/logins", createJWT(user, time.Hour), "")
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("success logout everywhere", func(t *testing.T) {
+		w := consentRequest(http.MethodPost, "/user/logout/everywhere", token, "")
+		require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+
+		user, err := userRepo.Find(context.TODO(), users[0].UUID)
+		require.NoError(t, err)
+		assert.NotEqual(t, users[0].SecurityStamp, user.SecurityStamp)
+
+		events, err := repository.NewAuditEventSqlxRepository(dbConn).FindBy(context.TODO(), domain.AuditEventFilter{
+			Actions: []string{domain.AuditLogoutEverywhere},
+		})
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("error token revoked by logout everywhere", func(t *testing.T) {
+		w := consentRequest(http.MethodGet, "/user/logins", token, "")
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+
+		w = consentRequest(http.MethodPost, "/user/logout/everywhere", token, "")
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,90 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wicaker/user/internal/domain"
+	"github.com/wicaker/user/internal/middleware"
+	"github.com/wicaker/user/test/dbfixture"
+)
+
+func createJWTWithAuthTime(user domain.User, authTime time.Time) string {
+	tk := &domain.JWToken{
+		UUID:          user.UUID,
+		Email:         user.Email,
+		SecurityStamp: user.SecurityStamp,
+		AuthTime:      authTime.Unix(),
+		Type:          domain.TokenLogin,
+		StandardClaims: &jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}
+	if authTime.IsZero() {
+		tk.AuthTime = 0
+	}
+
+	token, err := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tk).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func TestStepUpAuthentication(t *testing.T) {
+	defer func() {
+		if err := dbfixture.Truncate(dbConn); err != nil {
+			t.Errorf("error truncating test database tables: %v", err)
+		}
+	}()
+
+	users, err := dbfixture.SeedActiveUsers(dbConn, 1)
+	require.NoError(t, err)
+	staleToken := createJWTWithAuthTime(users[0], time.Now().Add(-time.Hour))
+
+	t.Run("error stale token on change email", func(t *testing.T) {
+		var resp domain.Response
+
+		w := consentRequest(http.MethodPut, "/user/email", staleToken, `{"email":"new1@example.com","password":"Password1"}`)
+		require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, domain.ErrReauthenticationRequired.Error(), resp.Message)
+		assert.Equal(t, "reauthentication_required", resp.Data["error"])
+		assert.Equal(t, middleware.ReauthenticatePath, resp.Data["reauthenticate"])
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "insufficient_user_authentication")
+	})
+
+	t.Run("error token without auth_time on change password", func(t *testing.T) {
+		w := consentRequest(http.MethodPut, "/user/password/change", createJWTWithAuthTime(users[0], time.Time{}),
+			`{"email":"user1@example.com","password":"Password1","new_password":"Password11"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("error reauthenticate with wrong password", func(t *testing.T) {
+		w := consentRequest(http.MethodPost, "/user/reauthenticate", staleToken, `{"password":"wrong"}`)
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("success reauthenticate then change email", func(t *testing.T) {
+		var resp domain.Response
+
+		w := consentRequest(http.MethodPost, "/user/reauthenticate", staleToken, `{"password":"Password1"}`)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+		freshToken := resp.Data["token"].(string)
+		parsedToken, err := middleware.JwtVerify(freshToken)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), time.Unix(parsedToken.AuthTime, 0), time.Minute)
+
+		w = consentRequest(http.MethodPut, "/user/email", freshToken, `{"email":"new1@example.com","password":"Password1"}`)
+		assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	})
+}
@@ -13,13 +13,20 @@ type Message struct {
 
 type queue struct {
 	name    string
+	format  rmq.Format
 	Message *Message
 }
 
 // NewMockQueueRMQ /
 func NewMockQueueRMQ(name string, message *Message) rmq.Queue {
+	return NewMockFormatQueueRMQ(name, rmq.FormatPlain, message)
+}
+
+// NewMockFormatQueueRMQ creates a mock queue of format, messages are recorded as they are given to Publish
+func NewMockFormatQueueRMQ(name string, format rmq.Format, message *Message) rmq.Queue {
 	return &queue{
 		name:    name,
+		format:  format,
 		Message: message,
 	}
 }
@@ -41,3 +48,13 @@ func (q *queue) Publish(message string, routingKey string, headers map[string]in
 func (q *queue) GetQueueName() string {
 	return q.name
 }
+
+// Format /
+func (q *queue) Format() rmq.Format {
+	return q.format
+}
+
+// State /
+func (q *queue) State() rmq.State {
+	return rmq.StateConnected
+}